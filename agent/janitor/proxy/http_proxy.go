@@ -1,12 +1,16 @@
 package proxy
 
 import (
+	"bytes"
+	"context"
 	"crypto/tls"
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net"
 	"net/http"
+	"net/http/httputil"
 	"strings"
 	"time"
 
@@ -27,7 +31,8 @@ func NewHTTPProxyHandler(domain string) http.Handler {
 	}
 }
 
-// lookup a proper backend according by request
+// lookup a proper backend according by request, the routing rules go first,
+// then the upstream alias & the `<upstream>.<domain>` naming.
 func (p *HTTPProxy) lookup(r *http.Request) (*upstream.BackendCombined, error) {
 	remoteIP, _, err := net.SplitHostPort(r.RemoteAddr)
 	if err != nil {
@@ -38,19 +43,30 @@ func (p *HTTPProxy) lookup(r *http.Request) (*upstream.BackendCombined, error) {
 		return nil, errors.New("request Host empty")
 	}
 
+	host, port, err := net.SplitHostPort(r.Host)
+	if err != nil {
+		host, port = r.Host, "80"
+	}
+
 	var (
-		split    = strings.Split(r.Host, ":")
-		host     = split[0]
-		port     = split[1]
 		byAlias  bool // flag on looking up by upstream alias or not
 		selected *upstream.BackendCombined
+		client   = &upstream.Client{IP: remoteIP, Header: r.Header}
 	)
 	if !strings.HasSuffix(host, p.suffix) {
 		byAlias = true
 	}
 
-	if byAlias {
-		selected = upstream.LookupAlias(remoteIP, host)
+	if u, rule := upstream.MatchRule(r, host); u != nil {
+		selected = upstream.Lookup(client, u, "")
+		if selected != nil {
+			log.Debugf("[HTTP] proxy request [%s %s%s] matched rule [%s] of upstream [%s]",
+				r.Method, r.Host, r.URL.Path, rule, u.Name)
+			upstream.Rewrite(rule, r)
+		}
+
+	} else if byAlias {
+		selected = upstream.LookupAlias(client, host)
 
 	} else {
 		trimed := strings.TrimSuffix(host, p.suffix)
@@ -59,11 +75,11 @@ func (p *HTTPProxy) lookup(r *http.Request) (*upstream.BackendCombined, error) {
 		switch len(ss) {
 		case 3: // upstream
 			ups := trimed
-			selected = upstream.LookupUpstream(remoteIP, ups, port, "")
+			selected = upstream.LookupUpstream(client, ups, port, "")
 		case 4: // specified backend
 			ups := fmt.Sprintf("%s.%s.%s.%s", ss[1], ss[2], ss[3], ss[4])
 			backend := trimed
-			selected = upstream.LookupUpstream(remoteIP, ups, port, backend)
+			selected = upstream.LookupUpstream(client, ups, port, backend)
 		default:
 			return nil, fmt.Errorf("request Host [%s] invalid", host)
 		}
@@ -107,31 +123,76 @@ func (p *HTTPProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// detect & update backend scheme
-	if selected.Backend.Scheme == "" {
-		https, err := detectHTTPs(selected.Addr())
-		if err != nil {
-			err = fmt.Errorf("detect selected scheme error: %v", err)
-			http.Error(w, err.Error(), 500)
-			return
-		}
+	var (
+		remoteIP, _, _ = net.SplitHostPort(r.RemoteAddr) // verified by lookup
+		policy         = upstream.RetryPolicy(selected.Upstream)
+	)
 
-		if https {
-			selected.Backend.Scheme = "https"
-		} else {
-			selected.Backend.Scheme = "http"
-		}
+	// buffer the request body to replay it on the other backends
+	body, replayable, err := bufferBody(r, policy.Retries)
+	if err != nil {
+		http.Error(w, err.Error(), 400)
+		return
+	}
+	if !replayable {
+		policy.Retries = 0
+	}
+
+	ft := &failover{
+		client:   &upstream.Client{IP: remoteIP, Header: r.Header},
+		selected: selected,
+		policy:   policy,
+		body:     body,
+	}
 
-		upstream.UpsertBackend(selected)
+	// the reverse proxy doesn't support the protocol upgrades
+	if isUpgrade(r) {
+		in, out, err = p.doRawProxy(w, r, ft)
+		return
 	}
 
+	in, out, err = p.doProxy(w, r, ft)
+}
+
+// doProxy forwards the request to the backend at the request level, so the
+// request could be routed & rewritten.
+func (p *HTTPProxy) doProxy(w http.ResponseWriter, req *http.Request, ft *failover) (int64, int64, error) {
 	var (
-		addr    = selected.Backend.Addr()
-		sche    = selected.Backend.Scheme
-		ups     = selected.Upstream.Name
-		backend = selected.Backend.ID
+		in = httpRequestLen(req)
+		cw = &countWriter{ResponseWriter: w}
 	)
 
+	rp := &httputil.ReverseProxy{
+		Director: func(r *http.Request) {
+			// the backend address is set by the failover transport
+			if _, ok := r.Header["User-Agent"]; !ok {
+				r.Header.Set("User-Agent", "") // not to add the default one
+			}
+		},
+		Transport: ft,
+		ModifyResponse: func(resp *http.Response) error {
+			// stick the client to the responded backend by the cookie
+			if cookie := upstream.StickyCookie(ft.selected); cookie != nil {
+				resp.Header.Add("Set-Cookie", cookie.String())
+			}
+			return nil
+		},
+	}
+
+	// the reverse proxy responds 502 on the transport errors, which are
+	// recorded by the failover transport.
+	rp.ServeHTTP(cw, req)
+	ft.end(in, cw.n, ft.err != nil)
+
+	if ft.err != nil {
+		return in, cw.n, fmt.Errorf("proxy to upstream %s error: %v", ft.selected.Addr(), ft.err)
+	}
+	return in, cw.n, nil
+}
+
+// doRawProxy proxies the upgraded connections, eg: websocket, by hijacking the
+// client connection & copying the raw bytes in both directions.
+func (p *HTTPProxy) doRawProxy(w http.ResponseWriter, req *http.Request, ft *failover) (in, out int64, err error) {
 	// obtian the underlying net.Conn
 	hj, ok := w.(http.Hijacker)
 	if !ok {
@@ -140,47 +201,31 @@ func (p *HTTPProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	conn, _, err := hj.Hijack()
+	dst, err := ft.connect()
 	if err != nil {
-		err = fmt.Errorf("hijack tcp conn error: %v", err)
-		http.Error(w, err.Error(), 500)
+		err = fmt.Errorf("cannot connect to upstream %s: %v", ft.selected.Addr(), err)
+		http.Error(w, err.Error(), http.StatusBadGateway)
 		return
 	}
-	defer conn.Close()
-
-	// do proxy
-	stats.Incr(&stats.DeltaBackend{ups, backend, 1, 0, 0, 1}, nil) // conn, active
-	in, out, err = p.doRawProxy(conn, r, sche, addr)
-	stats.Incr(&stats.DeltaBackend{ups, backend, -1, uint64(in), uint64(out), 0}, nil) // disconnect
-}
+	defer dst.Close()
 
-func (p *HTTPProxy) doRawProxy(src net.Conn, req *http.Request, sche, addr string) (int64, int64, error) {
-	var in, out int64
+	defer func() {
+		ft.end(in, out, err != nil)
+	}()
 
-	// dial backend
-	dst, err := net.DialTimeout("tcp", addr, time.Second*60)
+	src, _, err := hj.Hijack()
 	if err != nil {
-		err = fmt.Errorf("cannot connect to upstream %s: %v", addr, err)
-		src.Write([]byte("HTTP/1.0 500 Internal Server Error\r\n\r\n" + err.Error() + "\r\n"))
-		return in, out, err
-	}
-	defer dst.Close()
-
-	// tls wrap and try handshake
-	if sche == "https" {
-		dst, err = wrapWithTLS(dst)
-		if err != nil {
-			err = fmt.Errorf("tls handshake with upstream %s error: %v", addr, err)
-			src.Write([]byte("HTTP/1.0 500 Internal Server Error\r\n\r\n" + err.Error() + "\r\n"))
-			return in, out, err
-		}
+		err = fmt.Errorf("hijack tcp conn error: %v", err)
+		http.Error(w, err.Error(), 500)
+		return
 	}
+	defer src.Close()
 
 	err = req.WriteProxy(dst) // send original request
 	if err != nil {
-		err = fmt.Errorf("copying request to %s error: %v", addr, err)
+		err = fmt.Errorf("copying request to %s error: %v", ft.selected.Addr(), err)
 		src.Write([]byte("HTTP/1.0 500 Internal Server Error\r\n\r\n" + err.Error() + "\r\n"))
-		return in, out, err
+		return
 	}
 	in += httpRequestLen(req)
 
@@ -202,12 +247,290 @@ func (p *HTTPProxy) doRawProxy(src net.Conn, req *http.Request, sche, addr strin
 	err = <-errc
 	if err != nil && err != io.EOF {
 		err = fmt.Errorf("io copy error: %v", err)
-		src.Write([]byte("HTTP/1.0 500 Internal Server Error\r\n\r\n" + err.Error() + "\r\n"))
-		return in, out, err
+		return
 	}
 	return in, out, nil
 }
 
+func isUpgrade(r *http.Request) bool {
+	if r.Header.Get("Upgrade") == "" {
+		return false
+	}
+
+	for _, v := range r.Header["Connection"] {
+		for _, token := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// failover is the transport of one proxied request, which sends the request to
+// the selected backend, and retries on the other backends of the upstream on the
+// connection failures, or on the 502 & 503 of the idempotent requests if configured.
+type failover struct {
+	client   *upstream.Client
+	selected *upstream.BackendCombined // the backend responded finally
+	policy   upstream.Retry
+	body     []byte // buffered request body to replay
+	err      error  // the final transport error
+}
+
+func (f *failover) RoundTrip(req *http.Request) (*http.Response, error) {
+	var (
+		tried []string
+		ctx   = context.WithValue(req.Context(), connectTimeoutKey{}, f.policy.ConnectTimeoutDuration())
+	)
+
+	for {
+		tried = append(tried, f.selected.Backend.ID)
+		f.begin()
+
+		resp, err := f.roundTrip(req.WithContext(ctx))
+
+		// passive health checking, the 5xx responses are taken as failures as well
+		upstream.ReportResult(f.selected, err == nil && resp.StatusCode < 500)
+
+		if !f.retriable(req, resp, err) || !f.next(tried) {
+			if err == nil && resp.StatusCode >= 500 {
+				stats.Incr(&stats.DeltaBackend{Uid: f.selected.Upstream.Name, Bid: f.selected.Backend.ID, S5xx: 1}, nil)
+			}
+			f.err = err
+			return resp, err
+		}
+
+		if resp != nil {
+			resp.Body.Close()
+		}
+	}
+}
+
+func (f *failover) roundTrip(req *http.Request) (*http.Response, error) {
+	b := f.selected.Backend
+
+	if err := f.detectScheme(); err != nil {
+		return nil, err
+	}
+
+	u := *req.URL
+	u.Scheme = b.Scheme
+	u.Host = b.Addr()
+	req.URL = &u
+
+	if f.body != nil {
+		req.Body = ioutil.NopCloser(bytes.NewReader(f.body))
+	}
+
+	return transport.RoundTrip(req)
+}
+
+// connect connects the selected backend for the raw proxy, the others of the
+// upstream are tried in turn on the connection failures.
+func (f *failover) connect() (net.Conn, error) {
+	var tried []string
+
+	for {
+		tried = append(tried, f.selected.Backend.ID)
+		f.begin()
+
+		conn, err := f.dialRaw()
+		upstream.ReportResult(f.selected, err == nil)
+		if err == nil {
+			return conn, nil
+		}
+
+		if !f.next(tried) {
+			f.end(0, 0, true)
+			return nil, err
+		}
+	}
+}
+
+func (f *failover) dialRaw() (net.Conn, error) {
+	if err := f.detectScheme(); err != nil {
+		return nil, err
+	}
+
+	b := f.selected.Backend
+
+	conn, err := net.DialTimeout("tcp", b.Addr(), f.policy.ConnectTimeoutDuration())
+	if err != nil {
+		return nil, err
+	}
+
+	// tls wrap and try handshake
+	if b.Scheme == "https" {
+		tlsConn, err := wrapWithTLS(conn)
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("tls handshake error: %v", err)
+		}
+		conn = tlsConn
+	}
+
+	return conn, nil
+}
+
+// detectScheme detects & updates the scheme of the selected backend.
+func (f *failover) detectScheme() error {
+	b := f.selected.Backend
+	if b.Scheme != "" {
+		return nil
+	}
+
+	https, err := detectHTTPs(b.Addr(), f.policy.ConnectTimeoutDuration())
+	if err != nil {
+		return &dialError{fmt.Errorf("detect selected scheme error: %v", err)}
+	}
+
+	if https {
+		b.Scheme = "https"
+	} else {
+		b.Scheme = "http"
+	}
+
+	upstream.UpsertBackend(f.selected)
+	return nil
+}
+
+// retriable tells whether the failure could be retried on the other backends.
+func (f *failover) retriable(req *http.Request, resp *http.Response, err error) bool {
+	if err != nil {
+		// the request is never sent on the connection failures, so it's safe to
+		// retry whatever the method is.
+		_, ok := err.(*dialError)
+		return ok
+	}
+
+	if !f.policy.OnStatus || !idempotent(req.Method) {
+		return false
+	}
+	return resp.StatusCode == http.StatusBadGateway || resp.StatusCode == http.StatusServiceUnavailable
+}
+
+// next moves to another backend for the retry, false if no more retries.
+func (f *failover) next(tried []string) bool {
+	if len(tried) > f.policy.Retries {
+		return false
+	}
+
+	next := upstream.LookupNext(f.client, f.selected.Upstream, tried)
+	if next == nil {
+		return false
+	}
+
+	log.Warnf("[HTTP] proxy request failed on backend [%s], retrying on backend [%s]", f.selected.Backend.ID, next.Backend.ID)
+
+	f.end(0, 0, true)
+	f.selected = next
+	return true
+}
+
+// begin & end count the active connection of the selected backend.
+func (f *failover) begin() {
+	stats.Incr(&stats.DeltaBackend{Uid: f.selected.Upstream.Name, Bid: f.selected.Backend.ID, Ac: 1, Req: 1}, nil) // conn, active
+	upstream.IncrActive(f.selected, 1)
+}
+
+func (f *failover) end(in, out int64, failed bool) {
+	upstream.IncrActive(f.selected, -1)
+
+	var fail uint64
+	if failed {
+		fail = 1
+	}
+	stats.Incr(&stats.DeltaBackend{Uid: f.selected.Upstream.Name, Bid: f.selected.Backend.ID, Ac: -1, Rx: uint64(in), Tx: uint64(out), Fail: fail}, nil) // disconnect
+}
+
+// dialError is the failure of connecting the backend.
+type dialError struct {
+	err error
+}
+
+func (e *dialError) Error() string {
+	return e.err.Error()
+}
+
+type connectTimeoutKey struct{}
+
+// dial connects the backend with the connect timeout of the upstream.
+func dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	d := &net.Dialer{
+		Timeout:   time.Second * 60,
+		KeepAlive: time.Second * 30,
+	}
+	if timeout, ok := ctx.Value(connectTimeoutKey{}).(time.Duration); ok {
+		d.Timeout = timeout
+	}
+
+	conn, err := d.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, &dialError{err}
+	}
+	return conn, nil
+}
+
+func idempotent(method string) bool {
+	switch method {
+	case "GET", "HEAD", "OPTIONS", "TRACE", "PUT", "DELETE":
+		return true
+	}
+	return false
+}
+
+// the max size of the request body buffered for the retries
+const maxReplayBody = 1 << 20
+
+// bufferBody reads the request body into memory to replay it on the retries,
+// the body of unknown or too large size is not replayable.
+func bufferBody(r *http.Request, retries int) ([]byte, bool, error) {
+	if r.Body == nil || r.Body == http.NoBody || r.ContentLength == 0 {
+		return nil, true, nil
+	}
+
+	if retries == 0 || r.ContentLength < 0 || r.ContentLength > maxReplayBody {
+		return nil, false, nil
+	}
+
+	body := make([]byte, r.ContentLength)
+	if _, err := io.ReadFull(r.Body, body); err != nil {
+		return nil, false, fmt.Errorf("read request body error: %v", err)
+	}
+	r.Body.Close()
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	return body, true, nil
+}
+
+// the shared transport to the backends, the https backends are not verified.
+var transport = &http.Transport{
+	DialContext:         dial,
+	TLSClientConfig:     &tls.Config{InsecureSkipVerify: true},
+	TLSHandshakeTimeout: time.Second * 10,
+	MaxIdleConnsPerHost: 32,
+	IdleConnTimeout:     time.Second * 90,
+}
+
+// countWriter counts the transmitted bytes of the response body.
+type countWriter struct {
+	http.ResponseWriter
+	n int64
+}
+
+func (w *countWriter) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	w.n += int64(n)
+	return n, err
+}
+
+func (w *countWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
 // try hard to obtain the size of initial raw HTTP request according by RFC7231.
 // Note: we can't obtain the actually exact size through *http.Request because some details
 // of the initial request are lost while parsing it into *http.Request within golang http.Server
@@ -229,8 +552,8 @@ func httpRequestLen(r *http.Request) int64 {
 	return n
 }
 
-func detectHTTPs(addr string) (https bool, err error) {
-	conn, err := net.DialTimeout("tcp", addr, time.Second*60)
+func detectHTTPs(addr string, timeout time.Duration) (https bool, err error) {
+	conn, err := net.DialTimeout("tcp", addr, timeout)
 	if err != nil {
 		return
 	}
@@ -105,7 +105,7 @@ func (p *TCPProxyServer) lookup(conn net.Conn) (*upstream.BackendCombined, error
 
 	listen := ":" + localPort
 
-	selected := upstream.LookupListen(remoteHost, listen)
+	selected := upstream.LookupListen(&upstream.Client{IP: remoteHost}, listen)
 	if selected == nil {
 		return nil, fmt.Errorf("no matched backends for request [%s]", listen)
 	}
@@ -161,28 +161,69 @@ func (p *TCPProxyServer) serveTCP(conn net.Conn) {
 		return
 	}
 
+	// connect the backend, failover to the others if failed
+	dst, selected, err := p.connect(conn, selected)
+	if err != nil {
+		return
+	}
+	defer dst.Close()
+
 	var (
-		addr    = selected.Addr()
 		ups     = selected.Upstream.Name
 		backend = selected.Backend.ID
 	)
 
 	// do proxy
-	stats.Incr(&stats.DeltaBackend{ups, backend, 1, 0, 0, 1}, nil) // conn, active
-	in, out, err = p.doRawProxy(conn, addr)
-	stats.Incr(&stats.DeltaBackend{ups, backend, -1, uint64(in), uint64(out), 0}, nil) // disconnect
+	stats.Incr(&stats.DeltaBackend{Uid: ups, Bid: backend, Ac: 1, Req: 1}, nil) // conn, active
+	upstream.IncrActive(selected, 1)
+	in, out, err = p.doRawProxy(conn, dst)
+	upstream.IncrActive(selected, -1)
+
+	var fail uint64
+	if err != nil {
+		fail = 1
+	}
+	stats.Incr(&stats.DeltaBackend{Uid: ups, Bid: backend, Ac: -1, Rx: uint64(in), Tx: uint64(out), Fail: fail}, nil) // disconnect
 }
 
-func (p *TCPProxyServer) doRawProxy(src net.Conn, addr string) (int64, int64, error) {
-	var in, out int64
+// connect dials the selected backend, the other backends of the upstream are
+// tried in turn on the connection failures, within the retries configured.
+func (p *TCPProxyServer) connect(src net.Conn, selected *upstream.BackendCombined) (net.Conn, *upstream.BackendCombined, error) {
+	var (
+		remoteHost, _, _ = net.SplitHostPort(src.RemoteAddr().String())
+		policy           = upstream.RetryPolicy(selected.Upstream)
+		tried            []string
+	)
 
-	// dial backend
-	dst, err := net.DialTimeout("tcp", addr, time.Second*60)
-	if err != nil {
-		err = fmt.Errorf("cannot connect to upstream %s: %v", addr, err)
-		return in, out, err
+	for {
+		tried = append(tried, selected.Backend.ID)
+
+		// the dial result is reported for passive health checking
+		dst, err := net.DialTimeout("tcp", selected.Addr(), policy.ConnectTimeoutDuration())
+		upstream.ReportResult(selected, err == nil)
+		if err == nil {
+			return dst, selected, nil
+		}
+
+		err = fmt.Errorf("cannot connect to upstream %s: %v", selected.Addr(), err)
+		stats.Incr(&stats.DeltaBackend{Uid: selected.Upstream.Name, Bid: selected.Backend.ID, Req: 1, Fail: 1}, nil)
+
+		if len(tried) > policy.Retries {
+			return nil, selected, err
+		}
+
+		next := upstream.LookupNext(&upstream.Client{IP: remoteHost}, selected.Upstream, tried)
+		if next == nil {
+			return nil, selected, err
+		}
+
+		log.Warnf("[TCP] proxy %v, retrying on backend [%s]", err, next.Backend.ID)
+		selected = next
 	}
-	defer dst.Close()
+}
+
+func (p *TCPProxyServer) doRawProxy(src, dst net.Conn) (int64, int64, error) {
+	var in, out int64
 
 	// io copy between src & dst
 	errc := make(chan error, 2)
@@ -199,7 +240,7 @@ func (p *TCPProxyServer) doRawProxy(src net.Conn, addr string) (int64, int64, er
 	go cp(dst, src, &in)
 	cp(src, dst, &out) // note: hanging wait while copying the response
 
-	err = <-errc
+	err := <-errc
 	if err != nil && err != io.EOF {
 		err = fmt.Errorf("io copy error: %v", err)
 		return in, out, err
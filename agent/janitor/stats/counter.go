@@ -77,14 +77,20 @@ type BackendCounter struct {
 	RxBytes       uint64 `json:"rx_bytes"`       // nb of received bytes
 	TxBytes       uint64 `json:"tx_bytes"`       // nb of transmitted bytes
 	Requests      uint64 `json:"requests"`       // nb of requests
+	Fails         uint64 `json:"fails"`          // nb of failed requests
+	Status5xx     uint64 `json:"status_5xx"`     // nb of requests responded with 5xx
 	RxRate        uint   `json:"rx_rate"`        // received bytes / second
 	TxRate        uint   `json:"tx_rate"`        // transmitted bytes / second
 	ReqRate       uint   `json:"requests_rate"`  // requests / second
+	FailRate      uint   `json:"fails_rate"`     // failed requests / second
+	Ejected       bool   `json:"ejected"`        // ejected by the health checks
+	Ejections     uint64 `json:"ejections"`      // nb of ejections
 
-	lastRx  uint64 // used for calculate rate per second
-	lastTx  uint64
-	lastReq uint64
-	freshed bool
+	lastRx   uint64 // used for calculate rate per second
+	lastTx   uint64
+	lastReq  uint64
+	lastFail uint64
+	freshed  bool
 
 	startedAt time.Time
 
@@ -105,12 +111,15 @@ func (c *BackendCounter) MarshalJSON() ([]byte, error) {
 }
 
 type DeltaBackend struct {
-	Uid string
-	Bid string
-	Ac  int
-	Rx  uint64
-	Tx  uint64
-	Req uint64
+	Uid   string
+	Bid   string
+	Ac    int
+	Rx    uint64
+	Tx    uint64
+	Req   uint64
+	Fail  uint64
+	S5xx  uint64
+	Eject int // 1: ejected, -1: back
 }
 
 type DeltaGlb struct {
@@ -217,23 +226,27 @@ func (c *BackendCounter) freshRate() {
 		c.RxRate = 0
 		c.TxRate = 0
 		c.ReqRate = 0
+		c.FailRate = 0
 		return
 	}
 
 	var (
-		nRx  = c.RxBytes - c.lastRx
-		nTx  = c.TxBytes - c.lastTx
-		nReq = c.Requests - c.lastReq
-		intv = uint64(rateFreshIntv.Seconds())
+		nRx   = c.RxBytes - c.lastRx
+		nTx   = c.TxBytes - c.lastTx
+		nReq  = c.Requests - c.lastReq
+		nFail = c.Fails - c.lastFail
+		intv  = uint64(rateFreshIntv.Seconds())
 	)
 
 	c.RxRate = uint(nRx / intv)
 	c.TxRate = uint(nTx / intv)
 	c.ReqRate = uint(nReq / intv)
+	c.FailRate = uint(nFail / intv)
 
 	c.lastRx = c.RxBytes
 	c.lastTx = c.TxBytes
 	c.lastReq = c.Requests
+	c.lastFail = c.Fails
 
 	c.freshed = false // mark as consumed
 }
@@ -302,6 +315,20 @@ func (c *Stats) updateBackend(d *DeltaBackend) {
 	if n := d.Req; n > 0 {
 		backend.Requests += n
 	}
+	if n := d.Fail; n > 0 {
+		backend.Fails += n
+	}
+	if n := d.S5xx; n > 0 {
+		backend.Status5xx += n
+	}
+
+	switch {
+	case d.Eject > 0:
+		backend.Ejected = true
+		backend.Ejections++
+	case d.Eject < 0:
+		backend.Ejected = false
+	}
 
 	backend.freshed = true
 }
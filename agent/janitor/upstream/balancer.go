@@ -1,7 +1,11 @@
 package upstream
 
 import (
+	"errors"
 	"math/rand"
+	"net/http"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -9,19 +13,132 @@ func init() {
 	rand.Seed(time.Now().UnixNano())
 }
 
+const (
+	BalanceRR        = "rr"        // round robin
+	BalanceWRR       = "wrr"       // weighted round robin, the default
+	BalanceSWRR      = "swrr"      // smooth weighted round robin
+	BalanceLeastConn = "leastconn" // least active connections
+	BalanceP2C       = "p2c"       // random two choices by active connections
+	BalanceHash      = "hash"      // consistent hashing
+
+	HashOnIP     = "ip"
+	HashOnHeader = "header"
+	HashOnCookie = "cookie"
+)
+
+// Balance configures the load balancing algorithm of the upstream.
+type Balance struct {
+	Algorithm string `json:"algorithm" yaml:"algorithm"`                 // rr, wrr, swrr, leastconn, p2c, hash. default wrr
+	HashOn    string `json:"hashOn,omitempty" yaml:"hashOn,omitempty"`   // ip, header, cookie. default ip
+	HashKey   string `json:"hashKey,omitempty" yaml:"hashKey,omitempty"` // name of the header or cookie
+}
+
+func (bl *Balance) Valid() error {
+	switch bl.Algorithm {
+	case "", BalanceRR, BalanceWRR, BalanceSWRR, BalanceLeastConn, BalanceP2C:
+	case BalanceHash:
+		switch bl.HashOn {
+		case "", HashOnIP:
+		case HashOnHeader, HashOnCookie:
+			if bl.HashKey == "" {
+				return errors.New("balance hash key required by hashing on header or cookie")
+			}
+		default:
+			return errors.New("unsupported balance hash on, should be [ip,header,cookie]")
+		}
+	default:
+		return errors.New("unsupported balance algorithm, should be [rr,wrr,swrr,leastconn,p2c,hash]")
+	}
+	return nil
+}
+
+// normalizeBalance returns the balance config with the defaults filled.
+func normalizeBalance(bl *Balance) *Balance {
+	ret := &Balance{Algorithm: BalanceWRR}
+	if bl == nil {
+		return ret
+	}
+
+	*ret = *bl
+	if ret.Algorithm == "" {
+		ret.Algorithm = BalanceWRR
+	}
+	if ret.Algorithm != BalanceHash {
+		ret.HashOn, ret.HashKey = "", ""
+	} else if ret.HashOn == "" {
+		ret.HashOn = HashOnIP
+	}
+	return ret
+}
+
+// Client is the source of the proxied connection or request.
+type Client struct {
+	IP     string
+	Header http.Header // nil for the tcp connections
+}
+
+func (c *Client) header(name string) string {
+	if c == nil || c.Header == nil {
+		return ""
+	}
+	return c.Header.Get(name)
+}
+
+func (c *Client) cookie(name string) string {
+	if c == nil || c.Header == nil {
+		return ""
+	}
+	ck, err := (&http.Request{Header: c.Header}).Cookie(name)
+	if err != nil {
+		return ""
+	}
+	return ck.Value
+}
+
+func (c *Client) ip() string {
+	if c == nil {
+		return ""
+	}
+	return c.IP
+}
+
 type Balancer interface {
-	Next([]*Backend) *Backend
+	Next([]*Backend, *Client) *Backend
+}
+
+func newBalancer(bl *Balance) Balancer {
+	switch bl.Algorithm {
+	case BalanceRR:
+		return &rrBalancer{}
+	case BalanceSWRR:
+		return &swrrBalancer{current: make(map[string]float64)}
+	case BalanceLeastConn:
+		return &leastConnBalancer{}
+	case BalanceP2C:
+		return &p2cBalancer{}
+	case BalanceHash:
+		return &hashBalancer{on: bl.HashOn, key: bl.HashKey}
+	}
+
+	return &wrrBalancer{
+		index: -1,
+		cw:    0,
+	}
 }
 
 type rrBalancer struct {
+	sync.Mutex
 	current int
 }
 
-func (b *rrBalancer) Next(bs []*Backend) *Backend {
+func (b *rrBalancer) Next(bs []*Backend, _ *Client) *Backend {
 	if len(bs) == 0 {
 		return nil
 	}
 
+	b.Lock()
+	defer b.Unlock()
+
 	if b.current >= len(bs) {
 		b.current = 0
 	}
@@ -33,7 +150,7 @@ func (b *rrBalancer) Next(bs []*Backend) *Backend {
 
 type weightBalancer struct{}
 
-func (b *weightBalancer) Next(bs []*Backend) *Backend {
+func (b *weightBalancer) Next(bs []*Backend, _ *Client) *Backend {
 	if len(bs) == 0 {
 		return nil
 	}
@@ -54,3 +171,112 @@ func (b *weightBalancer) Next(bs []*Backend) *Backend {
 
 	return nil
 }
+
+// swrrBalancer is the smooth weighted round robin as nginx, the backends are
+// picked evenly interleaved by their weights, eg: {a, a, b, a, c} for 3:1:1.
+type swrrBalancer struct {
+	sync.Mutex
+	current map[string]float64 // backend id -> current weight
+}
+
+func (b *swrrBalancer) Next(bs []*Backend, _ *Client) *Backend {
+	b.Lock()
+	defer b.Unlock()
+
+	var (
+		best  *Backend
+		total float64
+		seen  = make(map[string]bool, len(bs))
+	)
+
+	for _, t := range bs {
+		seen[t.ID] = true
+		if t.Weight <= 0 {
+			continue
+		}
+
+		b.current[t.ID] += t.Weight
+		total += t.Weight
+
+		if best == nil || b.current[t.ID] > b.current[best.ID] {
+			best = t
+		}
+	}
+
+	// forget the removed backends
+	for id := range b.current {
+		if !seen[id] {
+			delete(b.current, id)
+		}
+	}
+
+	if best == nil {
+		return nil
+	}
+
+	b.current[best.ID] -= total
+	return best
+}
+
+// leastConnBalancer picks the backend with the least active connections, the
+// ties are picked in turn.
+type leastConnBalancer struct {
+	sync.Mutex
+	offset int
+}
+
+func (b *leastConnBalancer) Next(bs []*Backend, _ *Client) *Backend {
+	if len(bs) == 0 {
+		return nil
+	}
+
+	b.Lock()
+	b.offset++
+	offset := b.offset
+	b.Unlock()
+
+	var best *Backend
+	for i := range bs {
+		t := bs[(offset+i)%len(bs)]
+		if best == nil || t.Active() < best.Active() {
+			best = t
+		}
+	}
+
+	return best
+}
+
+// p2cBalancer picks two of the backends randomly, and then the one with the
+// less active connections, which avoids the herd on the least loaded backend.
+type p2cBalancer struct{}
+
+func (b *p2cBalancer) Next(bs []*Backend, _ *Client) *Backend {
+	switch len(bs) {
+	case 0:
+		return nil
+	case 1:
+		return bs[0]
+	}
+
+	i := rand.Intn(len(bs))
+	j := rand.Intn(len(bs) - 1)
+	if j >= i {
+		j++
+	}
+
+	if bs[j].Active() < bs[i].Active() {
+		return bs[j]
+	}
+	return bs[i]
+}
+
+// Active returns the active connections of the backend.
+func (b *Backend) Active() int64 {
+	return atomic.LoadInt64(&b.active)
+}
+
+// IncrActive counts the active connections of the backend by the proxies,
+// which the least connections balancers take.
+func IncrActive(cmb *BackendCombined, delta int64) {
+	atomic.AddInt64(&cmb.Backend.active, delta)
+}
@@ -0,0 +1,228 @@
+package upstream
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func newBackends(weights ...float64) []*Backend {
+	bs := make([]*Backend, len(weights))
+	for i, w := range weights {
+		bs[i] = &Backend{ID: "b" + strconv.Itoa(i), Weight: w}
+	}
+	return bs
+}
+
+func pick(b Balancer, bs []*Backend, c *Client, n int) map[string]int {
+	counts := make(map[string]int)
+	for i := 0; i < n; i++ {
+		if t := b.Next(bs, c); t != nil {
+			counts[t.ID]++
+		}
+	}
+	return counts
+}
+
+func TestBalanceValid(t *testing.T) {
+	tests := []struct {
+		name    string
+		bl      *Balance
+		wantErr bool
+	}{
+		{name: "default", bl: &Balance{}},
+		{name: "leastconn", bl: &Balance{Algorithm: BalanceLeastConn}},
+		{name: "hash on ip", bl: &Balance{Algorithm: BalanceHash}},
+		{name: "hash on header", bl: &Balance{Algorithm: BalanceHash, HashOn: HashOnHeader, HashKey: "X-User"}},
+		{name: "hash on cookie without key", bl: &Balance{Algorithm: BalanceHash, HashOn: HashOnCookie}, wantErr: true},
+		{name: "hash on unknown", bl: &Balance{Algorithm: BalanceHash, HashOn: "path"}, wantErr: true},
+		{name: "unknown algorithm", bl: &Balance{Algorithm: "random"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.bl.Valid(); (err != nil) != tt.wantErr {
+				t.Errorf("Valid() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNormalizeBalance(t *testing.T) {
+	tests := []struct {
+		name string
+		bl   *Balance
+		want *Balance
+	}{
+		{name: "nil", bl: nil, want: &Balance{Algorithm: BalanceWRR}},
+		{name: "empty", bl: &Balance{}, want: &Balance{Algorithm: BalanceWRR}},
+		{name: "hash on ip", bl: &Balance{Algorithm: BalanceHash}, want: &Balance{Algorithm: BalanceHash, HashOn: HashOnIP}},
+		{name: "hash key dropped", bl: &Balance{Algorithm: BalanceRR, HashOn: HashOnHeader, HashKey: "X-User"}, want: &Balance{Algorithm: BalanceRR}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeBalance(tt.bl); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("normalizeBalance() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBalancerDistribution(t *testing.T) {
+	tests := []struct {
+		name    string
+		bl      *Balance
+		weights []float64
+		n       int
+		want    map[string]int
+	}{
+		{
+			name:    "rr",
+			bl:      &Balance{Algorithm: BalanceRR},
+			weights: []float64{1, 5, 1},
+			n:       9,
+			want:    map[string]int{"b0": 3, "b1": 3, "b2": 3},
+		},
+		{
+			name:    "wrr",
+			bl:      &Balance{Algorithm: BalanceWRR},
+			weights: []float64{3, 1, 1},
+			n:       10,
+			want:    map[string]int{"b0": 6, "b1": 2, "b2": 2},
+		},
+		{
+			name:    "swrr",
+			bl:      &Balance{Algorithm: BalanceSWRR},
+			weights: []float64{3, 1, 1},
+			n:       10,
+			want:    map[string]int{"b0": 6, "b1": 2, "b2": 2},
+		},
+		{
+			name:    "swrr skips the drained",
+			bl:      &Balance{Algorithm: BalanceSWRR},
+			weights: []float64{1, 0, 1},
+			n:       4,
+			want:    map[string]int{"b0": 2, "b2": 2},
+		},
+		{
+			name:    "leastconn ties in turn",
+			bl:      &Balance{Algorithm: BalanceLeastConn},
+			weights: []float64{1, 1, 1},
+			n:       9,
+			want:    map[string]int{"b0": 3, "b1": 3, "b2": 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newBalancer(normalizeBalance(tt.bl))
+			if got := pick(b, newBackends(tt.weights...), nil, tt.n); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("picked %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSWRRSmooth(t *testing.T) {
+	var (
+		b  = newBalancer(&Balance{Algorithm: BalanceSWRR})
+		bs = newBackends(3, 1, 1)
+	)
+
+	// the heaviest backend is interleaved, never picked 3 times in a row
+	var run int
+	for i := 0; i < 20; i++ {
+		if b.Next(bs, nil).ID != "b0" {
+			run = 0
+			continue
+		}
+		if run++; run >= 3 {
+			t.Fatalf("b0 picked %d times in a row", run)
+		}
+	}
+}
+
+func TestLeastActive(t *testing.T) {
+	bs := newBackends(1, 1, 1)
+	bs[0].active, bs[1].active, bs[2].active = 5, 1, 3
+
+	for _, algo := range []string{BalanceLeastConn, BalanceP2C} {
+		t.Run(algo, func(t *testing.T) {
+			b := newBalancer(&Balance{Algorithm: algo})
+			counts := pick(b, bs, nil, 100)
+
+			if counts["b0"] != 0 {
+				t.Errorf("the most active backend picked %d times", counts["b0"])
+			}
+			if algo == BalanceLeastConn && counts["b1"] != 100 {
+				t.Errorf("the least active backend picked %d times, want 100", counts["b1"])
+			}
+		})
+	}
+}
+
+func TestBalancerEmpty(t *testing.T) {
+	for _, algo := range []string{BalanceRR, BalanceWRR, BalanceSWRR, BalanceLeastConn, BalanceP2C, BalanceHash} {
+		t.Run(algo, func(t *testing.T) {
+			b := newBalancer(normalizeBalance(&Balance{Algorithm: algo}))
+			if got := b.Next(nil, &Client{IP: "1.1.1.1"}); got != nil {
+				t.Errorf("Next() = %v, want nil", got)
+			}
+		})
+	}
+}
+
+func TestClient(t *testing.T) {
+	c := &Client{
+		IP: "1.1.1.1",
+		Header: http.Header{
+			"X-User": {"u1"},
+			"Cookie": {"sid=s1; lang=en"},
+		},
+	}
+
+	if got := c.header("x-user"); got != "u1" {
+		t.Errorf("header() = %q, want u1", got)
+	}
+	if got := c.cookie("sid"); got != "s1" {
+		t.Errorf("cookie() = %q, want s1", got)
+	}
+	if got := c.cookie("none"); got != "" {
+		t.Errorf("cookie() = %q, want empty", got)
+	}
+
+	var tcp *Client
+	if tcp.header("X-User") != "" || tcp.cookie("sid") != "" || tcp.ip() != "" {
+		t.Errorf("nil client should return the empty values")
+	}
+}
+
+func TestNextBackendSkipsDrained(t *testing.T) {
+	for _, algo := range []string{BalanceRR, BalanceWRR, BalanceSWRR, BalanceLeastConn, BalanceP2C, BalanceHash} {
+		t.Run(algo, func(t *testing.T) {
+			bs := newBackends(1, 0, 1)
+			// the drained one has the least active connections
+			bs[0].active, bs[2].active = 2, 2
+
+			u := &Upstream{
+				Name:     "u1",
+				Backends: bs,
+				balancer: newBalancer(normalizeBalance(&Balance{Algorithm: algo})),
+			}
+
+			for i := 0; i < 100; i++ {
+				c := &Client{IP: "10.0.0." + strconv.Itoa(i)}
+				if got := nextBackend(u, c, nil); got == nil || got.ID == "b1" {
+					t.Fatalf("nextBackend() = %v, want the backends with weight", got)
+				}
+			}
+
+			bs[0].Weight, bs[2].Weight = 0, 0
+			if got := nextBackend(u, &Client{IP: "10.0.0.1"}, nil); got != nil {
+				t.Errorf("nextBackend() = %v, want nil as all drained", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package upstream
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// virtual nodes of each backend on the hash ring
+const hashReplicas = 160
+
+type ringNode struct {
+	hash    uint32
+	backend *Backend
+}
+
+// hashBalancer is the consistent hashing by the client ip, header or cookie,
+// the same client goes to the same backend, and only the clients of the
+// removed backend are moved on the backends changes.
+type hashBalancer struct {
+	sync.Mutex
+	on   string // ip, header, cookie
+	key  string // name of the header or cookie
+	ring []ringNode
+	sig  string // the backends the ring built by
+}
+
+func (b *hashBalancer) Next(bs []*Backend, c *Client) *Backend {
+	if len(bs) == 0 {
+		return nil
+	}
+
+	b.Lock()
+	defer b.Unlock()
+
+	b.build(bs)
+
+	h := hashOf(b.hashKey(c))
+	i := sort.Search(len(b.ring), func(i int) bool {
+		return b.ring[i].hash >= h
+	})
+	if i == len(b.ring) {
+		i = 0
+	}
+
+	return b.ring[i].backend
+}
+
+// hashKey takes the client ip if the header or cookie absent.
+func (b *hashBalancer) hashKey(c *Client) string {
+	var key string
+
+	switch b.on {
+	case HashOnHeader:
+		key = c.header(b.key)
+	case HashOnCookie:
+		key = c.cookie(b.key)
+	}
+
+	if key == "" {
+		key = c.ip()
+	}
+	return key
+}
+
+// build rebuilds the ring if the backends changed.
+func (b *hashBalancer) build(bs []*Backend) {
+	ids := make([]string, len(bs))
+	for i, t := range bs {
+		ids[i] = t.ID
+	}
+	sig := strings.Join(ids, ",")
+
+	if sig == b.sig {
+		return
+	}
+
+	ring := make([]ringNode, 0, len(bs)*hashReplicas)
+	for _, t := range bs {
+		for i := 0; i < hashReplicas; i++ {
+			ring = append(ring, ringNode{hashOf(t.ID + "#" + strconv.Itoa(i)), t})
+		}
+	}
+	sort.Slice(ring, func(i, j int) bool {
+		return ring[i].hash < ring[j].hash
+	})
+
+	b.ring, b.sig = ring, sig
+}
+
+func hashOf(s string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(s))
+	return h.Sum32()
+}
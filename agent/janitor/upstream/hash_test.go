@@ -0,0 +1,82 @@
+package upstream
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+func TestHashStable(t *testing.T) {
+	var (
+		b  = newBalancer(&Balance{Algorithm: BalanceHash, HashOn: HashOnIP})
+		bs = newBackends(1, 1, 1, 1)
+	)
+
+	for i := 0; i < 100; i++ {
+		c := &Client{IP: "10.0.0." + strconv.Itoa(i)}
+		first := b.Next(bs, c)
+		for j := 0; j < 3; j++ {
+			if got := b.Next(bs, c); got != first {
+				t.Fatalf("client %s moved from %s to %s", c.IP, first.ID, got.ID)
+			}
+		}
+	}
+}
+
+func TestHashRemoveBackend(t *testing.T) {
+	var (
+		b      = newBalancer(&Balance{Algorithm: BalanceHash, HashOn: HashOnIP})
+		bs     = newBackends(1, 1, 1, 1)
+		before = make(map[string]*Backend)
+	)
+
+	for i := 0; i < 1000; i++ {
+		ip := "10.0." + strconv.Itoa(i/256) + "." + strconv.Itoa(i%256)
+		before[ip] = b.Next(bs, &Client{IP: ip})
+	}
+
+	removed := bs[1]
+	left := []*Backend{bs[0], bs[2], bs[3]}
+
+	for ip, prev := range before {
+		got := b.Next(left, &Client{IP: ip})
+		if got == removed {
+			t.Fatalf("client %s still goes to the removed backend", ip)
+		}
+		if prev != removed && got != prev {
+			t.Errorf("client %s moved from %s to %s, only the clients of %s should move", ip, prev.ID, got.ID, removed.ID)
+		}
+	}
+}
+
+func TestHashKey(t *testing.T) {
+	c := &Client{
+		IP: "1.1.1.1",
+		Header: http.Header{
+			"X-User": {"u1"},
+			"Cookie": {"sid=s1"},
+		},
+	}
+
+	tests := []struct {
+		name string
+		bl   *Balance
+		c    *Client
+		want string
+	}{
+		{name: "ip", bl: &Balance{HashOn: HashOnIP}, c: c, want: "1.1.1.1"},
+		{name: "header", bl: &Balance{HashOn: HashOnHeader, HashKey: "X-User"}, c: c, want: "u1"},
+		{name: "cookie", bl: &Balance{HashOn: HashOnCookie, HashKey: "sid"}, c: c, want: "s1"},
+		{name: "header absent", bl: &Balance{HashOn: HashOnHeader, HashKey: "X-Other"}, c: c, want: "1.1.1.1"},
+		{name: "tcp", bl: &Balance{HashOn: HashOnCookie, HashKey: "sid"}, c: &Client{IP: "2.2.2.2"}, want: "2.2.2.2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &hashBalancer{on: tt.bl.HashOn, key: tt.bl.HashKey}
+			if got := b.hashKey(tt.c); got != tt.want {
+				t.Errorf("hashKey() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
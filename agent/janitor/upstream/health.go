@@ -0,0 +1,264 @@
+package upstream
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/Dataman-Cloud/swan/agent/janitor/stats"
+)
+
+const (
+	defaultCheckInterval = time.Second * 5
+	defaultCheckTimeout  = time.Second * 2
+	defaultMaxFailures   = 3
+	defaultCoolDown      = time.Second * 30
+)
+
+// HealthCheck configures the backend health checking of the upstream in the
+// proxy, independent of the task health reported by the manager.
+type HealthCheck struct {
+	Protocol    string  `json:"protocol,omitempty" yaml:"protocol,omitempty"`       // tcp or http, the active checks are disabled if empty
+	Path        string  `json:"path,omitempty" yaml:"path,omitempty"`               // http check path
+	Interval    float64 `json:"interval,omitempty" yaml:"interval,omitempty"`       // by seconds, default 5
+	Timeout     float64 `json:"timeout,omitempty" yaml:"timeout,omitempty"`         // by seconds, default 2
+	MaxFailures int     `json:"maxFailures,omitempty" yaml:"maxFailures,omitempty"` // consecutive failures to eject, default 3
+	CoolDown    float64 `json:"coolDown,omitempty" yaml:"coolDown,omitempty"`       // by seconds the backend stays ejected, default 30
+	Passive     bool    `json:"passive,omitempty" yaml:"passive,omitempty"`         // outlier detection by the connection failures & 5xx responses
+}
+
+func (hc *HealthCheck) Valid() error {
+	switch hc.Protocol {
+	case "", "tcp", "http":
+	default:
+		return errors.New("unsupported health check protocol, should be [tcp,http]")
+	}
+
+	if hc.Interval < 0 || hc.Timeout < 0 || hc.CoolDown < 0 || hc.MaxFailures < 0 {
+		return errors.New("health check interval, timeout, cool down & max failures can't be negative")
+	}
+
+	return nil
+}
+
+func (hc *HealthCheck) interval() time.Duration {
+	return seconds(hc.Interval, defaultCheckInterval)
+}
+
+func (hc *HealthCheck) timeout() time.Duration {
+	return seconds(hc.Timeout, defaultCheckTimeout)
+}
+
+func (hc *HealthCheck) coolDown() time.Duration {
+	return seconds(hc.CoolDown, defaultCoolDown)
+}
+
+func (hc *HealthCheck) maxFailures() int {
+	if hc.MaxFailures > 0 {
+		return hc.MaxFailures
+	}
+	return defaultMaxFailures
+}
+
+func seconds(v float64, def time.Duration) time.Duration {
+	if v > 0 {
+		return time.Duration(v * float64(time.Second))
+	}
+	return def
+}
+
+// BackendHealth holds the ejection state of the backend.
+type BackendHealth struct {
+	sync.Mutex
+	failures     int       // consecutive failures
+	ejections    uint64    // nb of ejections
+	ejectedUntil time.Time // zero if not ejected
+}
+
+func (h *BackendHealth) MarshalJSON() ([]byte, error) {
+	h.Lock()
+	defer h.Unlock()
+
+	m := map[string]interface{}{
+		"failures":  h.failures,
+		"ejections": h.ejections,
+		"ejected":   h.ejected(),
+	}
+	if h.ejected() {
+		m["ejected_until"] = h.ejectedUntil
+	}
+
+	return json.Marshal(m)
+}
+
+func sameHealthCheck(a, b *HealthCheck) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func (h *BackendHealth) ejected() bool {
+	return time.Now().Before(h.ejectedUntil)
+}
+
+// available tells whether the backend could be selected, the ejected backend
+// turns available again after the cool-down.
+func (b *Backend) available() bool {
+	if b.Health == nil {
+		return true
+	}
+
+	b.Health.Lock()
+	defer b.Health.Unlock()
+
+	return !b.Health.ejected()
+}
+
+// report records the check result or the proxied traffic result of the backend,
+// the backend is ejected after the max consecutive failures.
+func (u *Upstream) report(hc *HealthCheck, b *Backend, ok bool) {
+	if hc == nil || b.Health == nil {
+		return
+	}
+
+	h := b.Health
+	h.Lock()
+	defer h.Unlock()
+
+	if ok {
+		h.failures = 0
+		if !h.ejectedUntil.IsZero() && !h.ejected() {
+			h.ejectedUntil = time.Time{}
+			log.Printf("upstream %s backend %s turns available", u.Name, b.ID)
+			stats.Incr(&stats.DeltaBackend{Uid: u.Name, Bid: b.ID, Eject: -1}, nil)
+		}
+		return
+	}
+
+	h.failures++
+	if h.failures < hc.maxFailures() || h.ejected() {
+		return
+	}
+
+	h.ejectedUntil = time.Now().Add(hc.coolDown())
+	h.ejections++
+	log.Warnf("upstream %s backend %s ejected for %s after %d consecutive failures", u.Name, b.ID, hc.coolDown(), h.failures)
+	stats.Incr(&stats.DeltaBackend{Uid: u.Name, Bid: b.ID, Eject: 1}, nil)
+}
+
+// ReportResult reports the result of the proxied connection or request to
+// the backend for the passive outlier detection.
+func ReportResult(cmb *BackendCombined, ok bool) {
+	mgr.RLock()
+	var (
+		u  = cmb.Upstream
+		hc = u.HealthCheck
+	)
+	mgr.RUnlock()
+
+	if hc != nil && hc.Passive {
+		u.report(hc, cmb.Backend, ok)
+	}
+}
+
+// checker runs the active health checks of the upstream's backends.
+type checker struct {
+	stopCh chan struct{}
+}
+
+// note: must be called under protection of mutext lock
+func (u *Upstream) startChecker() {
+	if u.HealthCheck == nil || u.HealthCheck.Protocol == "" {
+		return
+	}
+
+	c := &checker{stopCh: make(chan struct{})}
+	u.checker = c
+
+	go func(hc *HealthCheck) {
+		ticker := time.NewTicker(hc.interval())
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				u.checkBackends(hc)
+			case <-c.stopCh:
+				return
+			}
+		}
+	}(u.HealthCheck)
+}
+
+// note: must be called under protection of mutext lock
+func (u *Upstream) stopChecker() {
+	if u.checker != nil {
+		close(u.checker.stopCh)
+		u.checker = nil
+	}
+}
+
+func (u *Upstream) checkBackends(hc *HealthCheck) {
+	mgr.RLock()
+	backends := make([]*Backend, len(u.Backends))
+	copy(backends, u.Backends)
+	mgr.RUnlock()
+
+	var wg sync.WaitGroup
+	for _, b := range backends {
+		wg.Add(1)
+		go func(b *Backend) {
+			defer wg.Done()
+
+			err := probe(hc, b)
+			if err != nil {
+				log.Debugf("upstream %s backend %s health check failed: %v", u.Name, b.ID, err)
+			}
+			u.report(hc, b, err == nil)
+		}(b)
+	}
+	wg.Wait()
+}
+
+func probe(hc *HealthCheck, b *Backend) error {
+	if hc.Protocol == "tcp" {
+		conn, err := net.DialTimeout("tcp", b.Addr(), hc.timeout())
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+
+	scheme := b.Scheme
+	if scheme == "" {
+		scheme = "http"
+	}
+
+	client := &http.Client{
+		Timeout: hc.timeout(),
+		Transport: &http.Transport{
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+			DisableKeepAlives: true,
+		},
+	}
+
+	resp, err := client.Get(fmt.Sprintf("%s://%s%s", scheme, b.Addr(), hc.Path))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if code := resp.StatusCode; code < 200 || code >= 400 {
+		return fmt.Errorf("unexpected response code %d", code)
+	}
+
+	return nil
+}
@@ -0,0 +1,173 @@
+package upstream
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestHealthCheckValid(t *testing.T) {
+	tests := []struct {
+		name    string
+		hc      *HealthCheck
+		wantErr bool
+	}{
+		{name: "passive only", hc: &HealthCheck{Passive: true}},
+		{name: "http", hc: &HealthCheck{Protocol: "http", Path: "/health", Interval: 1}},
+		{name: "unknown protocol", hc: &HealthCheck{Protocol: "udp"}, wantErr: true},
+		{name: "negative interval", hc: &HealthCheck{Protocol: "tcp", Interval: -1}, wantErr: true},
+		{name: "negative max failures", hc: &HealthCheck{Protocol: "tcp", MaxFailures: -1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.hc.Valid(); (err != nil) != tt.wantErr {
+				t.Errorf("Valid() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEjection(t *testing.T) {
+	var (
+		u  = &Upstream{Name: "u1"}
+		hc = &HealthCheck{MaxFailures: 3, CoolDown: 60}
+	)
+
+	tests := []struct {
+		name      string
+		results   []bool
+		available bool
+		ejections uint64
+	}{
+		{name: "healthy", results: []bool{true, true}, available: true},
+		{name: "failures below the max", results: []bool{false, false}, available: true},
+		{name: "failures reset by success", results: []bool{false, false, true, false, false}, available: true},
+		{name: "ejected on the max failures", results: []bool{false, false, false}, available: false, ejections: 1},
+		{name: "ejected once while cooling down", results: []bool{false, false, false, false, false, false}, available: false, ejections: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &Backend{ID: "b1", Health: &BackendHealth{}}
+			for _, ok := range tt.results {
+				u.report(hc, b, ok)
+			}
+
+			if got := b.available(); got != tt.available {
+				t.Errorf("available() = %v, want %v", got, tt.available)
+			}
+			if got := b.Health.ejections; got != tt.ejections {
+				t.Errorf("ejections = %d, want %d", got, tt.ejections)
+			}
+		})
+	}
+}
+
+func TestEjectionCoolDown(t *testing.T) {
+	var (
+		u  = &Upstream{Name: "u1"}
+		hc = &HealthCheck{MaxFailures: 1, CoolDown: 0.05}
+		b  = &Backend{ID: "b1", Health: &BackendHealth{}}
+	)
+
+	u.report(hc, b, false)
+	if b.available() {
+		t.Fatal("backend should be ejected")
+	}
+
+	time.Sleep(time.Millisecond * 100)
+	if !b.available() {
+		t.Fatal("backend should be available after the cool down")
+	}
+
+	// the success after the cool down clears the ejection
+	u.report(hc, b, true)
+	if !b.Health.ejectedUntil.IsZero() || b.Health.failures != 0 {
+		t.Errorf("ejection not cleared, failures = %d, until = %v", b.Health.failures, b.Health.ejectedUntil)
+	}
+
+	// ejected again by the next failure
+	u.report(hc, b, false)
+	if b.available() || b.Health.ejections != 2 {
+		t.Errorf("available() = %v, ejections = %d, want ejected twice", b.available(), b.Health.ejections)
+	}
+}
+
+func TestReportResultPassive(t *testing.T) {
+	tests := []struct {
+		name      string
+		hc        *HealthCheck
+		available bool
+	}{
+		{name: "no health check", hc: nil, available: true},
+		{name: "active only", hc: &HealthCheck{Protocol: "tcp", MaxFailures: 1}, available: true},
+		{name: "passive", hc: &HealthCheck{Passive: true, MaxFailures: 1}, available: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				b = &Backend{ID: "b1", Health: &BackendHealth{}}
+				u = &Upstream{Name: "u1", HealthCheck: tt.hc, Backends: []*Backend{b}}
+			)
+
+			ReportResult(&BackendCombined{u, b}, false)
+			if got := b.available(); got != tt.available {
+				t.Errorf("available() = %v, want %v", got, tt.available)
+			}
+		})
+	}
+}
+
+func backendOf(t *testing.T, addr string) *Backend {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		t.Fatalf("SplitHostPort(%s) error = %v", addr, err)
+	}
+	p, _ := strconv.ParseUint(port, 10, 64)
+	return &Backend{ID: "b1", IP: host, Port: p}
+}
+
+func TestProbe(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	closed := ln.Addr().String()
+	ln.Close()
+
+	up := backendOf(t, srv.Listener.Addr().String())
+	down := backendOf(t, closed)
+
+	tests := []struct {
+		name    string
+		hc      *HealthCheck
+		b       *Backend
+		wantErr bool
+	}{
+		{name: "tcp", hc: &HealthCheck{Protocol: "tcp", Timeout: 1}, b: up},
+		{name: "tcp refused", hc: &HealthCheck{Protocol: "tcp", Timeout: 1}, b: down, wantErr: true},
+		{name: "http", hc: &HealthCheck{Protocol: "http", Path: "/health", Timeout: 1}, b: up},
+		{name: "http 5xx", hc: &HealthCheck{Protocol: "http", Path: "/", Timeout: 1}, b: up, wantErr: true},
+		{name: "http refused", hc: &HealthCheck{Protocol: "http", Path: "/health", Timeout: 1}, b: down, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := probe(tt.hc, tt.b); (err != nil) != tt.wantErr {
+				t.Errorf("probe() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
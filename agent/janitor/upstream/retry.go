@@ -0,0 +1,61 @@
+package upstream
+
+import (
+	"errors"
+	"time"
+)
+
+const defaultConnectTimeout = time.Second * 60
+
+// Retry configures the failover of the proxy, the connection or the request
+// failed on one backend is retried on the other backends of the upstream.
+type Retry struct {
+	Retries        int     `json:"retries,omitempty" yaml:"retries,omitempty"`               // max retries on the other backends, 0 disables the failover
+	ConnectTimeout float64 `json:"connectTimeout,omitempty" yaml:"connectTimeout,omitempty"` // by seconds, default 60
+	OnStatus       bool    `json:"onStatus,omitempty" yaml:"onStatus,omitempty"`             // retry the idempotent http requests on 502 & 503 as well
+}
+
+func (r *Retry) Valid() error {
+	if r.Retries < 0 {
+		return errors.New("proxy retries can't be negative")
+	}
+	if r.ConnectTimeout < 0 {
+		return errors.New("proxy connect timeout can't be negative")
+	}
+	return nil
+}
+
+func (r Retry) ConnectTimeoutDuration() time.Duration {
+	return seconds(r.ConnectTimeout, defaultConnectTimeout)
+}
+
+// RetryPolicy returns the failover config of the upstream, the zero value
+// is returned if not configured, which means no retries.
+func RetryPolicy(u *Upstream) Retry {
+	mgr.RLock()
+	defer mgr.RUnlock()
+
+	if u == nil || u.Retry == nil {
+		return Retry{}
+	}
+	return *u.Retry
+}
+
+// LookupNext selects another backend of the upstream by the balancer for the
+// failover, the tried backends are skipped. nil if no more backends.
+func LookupNext(c *Client, u *Upstream, tried []string) *BackendCombined {
+	skip := make(map[string]bool, len(tried))
+	for _, id := range tried {
+		skip[id] = true
+	}
+
+	b := nextBackend(u, c, skip)
+	if b == nil {
+		return nil
+	}
+
+	// stick to the working one
+	u.stick(c, b)
+
+	return &BackendCombined{u, b}
+}
@@ -0,0 +1,167 @@
+package upstream
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Rule routes the http requests to the upstream by host, path, headers &
+// methods, so one public hostname could front several apps by path.
+type Rule struct {
+	Host       string            `json:"host,omitempty" yaml:"host,omitempty"` // default the upstream alias
+	PathPrefix string            `json:"pathPrefix,omitempty" yaml:"pathPrefix,omitempty"`
+	PathRegex  string            `json:"pathRegex,omitempty" yaml:"pathRegex,omitempty"`
+	Headers    map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"` // empty value matches any
+	Methods    []string          `json:"methods,omitempty" yaml:"methods,omitempty"`
+	Rewrite    string            `json:"rewrite,omitempty" yaml:"rewrite,omitempty"` // replaces the matched path prefix or regex
+	Priority   int               `json:"priority,omitempty" yaml:"priority,omitempty"`
+
+	re *regexp.Regexp // runtime
+}
+
+func (r *Rule) String() string {
+	return fmt.Sprintf("host=%s, prefix=%s, regex=%s, rewrite=%s", r.Host, r.PathPrefix, r.PathRegex, r.Rewrite)
+}
+
+// Valid verifies the rule and compiles its path regex.
+func (r *Rule) Valid() error {
+	if r == nil {
+		return errors.New("nil rule")
+	}
+
+	if r.PathPrefix != "" && r.PathRegex != "" {
+		return errors.New("rule path prefix & regex can't be both set")
+	}
+
+	if r.PathPrefix != "" && !strings.HasPrefix(r.PathPrefix, "/") {
+		return errors.New("rule path prefix should start with /")
+	}
+
+	if r.PathRegex != "" {
+		re, err := regexp.Compile(r.PathRegex)
+		if err != nil {
+			return fmt.Errorf("rule path regex invalid: %v", err)
+		}
+		r.re = re
+	}
+
+	return nil
+}
+
+func (r *Rule) match(alias, host, path, method string, header http.Header) bool {
+	h := r.Host
+	if h == "" {
+		h = alias
+	}
+
+	if h == "" || !strings.EqualFold(h, host) {
+		return false
+	}
+
+	if r.PathPrefix != "" && !strings.HasPrefix(path, r.PathPrefix) {
+		return false
+	}
+
+	if r.re != nil && !r.re.MatchString(path) {
+		return false
+	}
+
+	if len(r.Methods) > 0 {
+		var ok bool
+		for _, m := range r.Methods {
+			if strings.EqualFold(m, method) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+
+	for k, v := range r.Headers {
+		got, ok := header[http.CanonicalHeaderKey(k)]
+		if !ok {
+			return false
+		}
+		if v != "" && (len(got) == 0 || got[0] != v) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// rewrite returns the path rewritten by the rule.
+func (r *Rule) rewrite(p string) string {
+	if r.Rewrite == "" {
+		return p
+	}
+
+	if r.re != nil {
+		return r.re.ReplaceAllString(p, r.Rewrite)
+	}
+
+	if r.PathPrefix != "" {
+		rest := strings.TrimPrefix(p, r.PathPrefix)
+		ret := path.Join(r.Rewrite, rest)
+		if strings.HasSuffix(rest, "/") && !strings.HasSuffix(ret, "/") {
+			ret += "/"
+		}
+		return ret
+	}
+
+	return p
+}
+
+// more specific, more preferred
+func (r *Rule) specificity() int {
+	n := len(r.PathPrefix) + len(r.PathRegex) + len(r.Headers) + len(r.Methods)
+	if r.Host != "" {
+		n++
+	}
+	return n
+}
+
+// MatchRule selects the upstream by the routing rules, the matched rule with
+// the highest priority wins, then the more specific one. nil if no rules matched.
+func MatchRule(r *http.Request, host string) (*Upstream, *Rule) {
+	mgr.RLock()
+	defer mgr.RUnlock()
+
+	var (
+		selected *Upstream
+		best     *Rule
+	)
+
+	for _, u := range mgr.Upstreams {
+		for _, rule := range u.Rules {
+			if !rule.match(u.Alias, host, r.URL.Path, r.Method, r.Header) {
+				continue
+			}
+
+			if best == nil || rule.Priority > best.Priority ||
+				(rule.Priority == best.Priority && rule.specificity() > best.specificity()) {
+				selected, best = u, rule
+			}
+		}
+	}
+
+	return selected, best
+}
+
+// Rewrite rewrites the request path by the matched rule.
+func Rewrite(rule *Rule, r *http.Request) {
+	if rule == nil {
+		return
+	}
+
+	if p := rule.rewrite(r.URL.Path); p != r.URL.Path {
+		r.URL.Path = p
+		r.URL.RawPath = ""
+	}
+}
@@ -0,0 +1,193 @@
+package upstream
+
+import (
+	"net/http"
+	"testing"
+)
+
+func newRule(t *testing.T, r *Rule) *Rule {
+	if err := r.Valid(); err != nil {
+		t.Fatalf("Valid() of %s error = %v", r, err)
+	}
+	return r
+}
+
+func TestRuleValid(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    *Rule
+		wantErr bool
+	}{
+		{name: "prefix", rule: &Rule{PathPrefix: "/api"}},
+		{name: "regex", rule: &Rule{PathRegex: "^/v[0-9]+/"}},
+		{name: "nil", rule: nil, wantErr: true},
+		{name: "prefix and regex", rule: &Rule{PathPrefix: "/api", PathRegex: "^/api"}, wantErr: true},
+		{name: "relative prefix", rule: &Rule{PathPrefix: "api"}, wantErr: true},
+		{name: "bad regex", rule: &Rule{PathRegex: "(["}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.rule.Valid(); (err != nil) != tt.wantErr {
+				t.Errorf("Valid() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRuleRewrite(t *testing.T) {
+	tests := []struct {
+		name string
+		rule *Rule
+		path string
+		want string
+	}{
+		{
+			name: "no rewrite",
+			rule: &Rule{PathPrefix: "/api"},
+			path: "/api/users",
+			want: "/api/users",
+		},
+		{
+			name: "prefix",
+			rule: &Rule{PathPrefix: "/api", Rewrite: "/v1"},
+			path: "/api/users",
+			want: "/v1/users",
+		},
+		{
+			name: "prefix to root",
+			rule: &Rule{PathPrefix: "/api", Rewrite: "/"},
+			path: "/api/users",
+			want: "/users",
+		},
+		{
+			name: "prefix keeps the trailing slash",
+			rule: &Rule{PathPrefix: "/api", Rewrite: "/v1"},
+			path: "/api/users/",
+			want: "/v1/users/",
+		},
+		{
+			name: "regex",
+			rule: &Rule{PathRegex: "^/v([0-9]+)/", Rewrite: "/api/$1/"},
+			path: "/v2/users",
+			want: "/api/2/users",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := newRule(t, tt.rule)
+			if got := rule.rewrite(tt.path); got != tt.want {
+				t.Errorf("rewrite(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRuleSpecificity(t *testing.T) {
+	var (
+		host   = &Rule{Host: "a.com"}
+		prefix = &Rule{Host: "a.com", PathPrefix: "/api"}
+		longer = &Rule{Host: "a.com", PathPrefix: "/api/v1"}
+		header = &Rule{Host: "a.com", PathPrefix: "/api/v1", Headers: map[string]string{"X-Canary": ""}}
+	)
+
+	rules := []*Rule{host, prefix, longer, header}
+	for i := 1; i < len(rules); i++ {
+		if rules[i].specificity() <= rules[i-1].specificity() {
+			t.Errorf("specificity of {%s} = %d, want more than {%s} = %d",
+				rules[i], rules[i].specificity(), rules[i-1], rules[i-1].specificity())
+		}
+	}
+}
+
+func TestMatchRule(t *testing.T) {
+	var (
+		web = &Upstream{
+			Name:  "web",
+			Alias: "a.com",
+			Rules: []*Rule{newRule(t, &Rule{})},
+		}
+		api = &Upstream{
+			Name:  "api",
+			Alias: "api.local",
+			Rules: []*Rule{newRule(t, &Rule{Host: "a.com", PathPrefix: "/api"})},
+		}
+		canary = &Upstream{
+			Name:  "canary",
+			Alias: "canary.local",
+			Rules: []*Rule{newRule(t, &Rule{Host: "a.com", PathPrefix: "/api", Headers: map[string]string{"X-Canary": "1"}})},
+		}
+		admin = &Upstream{
+			Name:  "admin",
+			Alias: "admin.local",
+			Rules: []*Rule{newRule(t, &Rule{Host: "a.com", PathRegex: "^/admin", Methods: []string{"GET"}, Priority: 10})},
+		}
+	)
+
+	saved := mgr.Upstreams
+	mgr.Upstreams = []*Upstream{web, api, canary, admin}
+	defer func() { mgr.Upstreams = saved }()
+
+	tests := []struct {
+		name   string
+		host   string
+		method string
+		path   string
+		header http.Header
+		want   string
+	}{
+		{name: "alias as the host", host: "a.com", method: "GET", path: "/", want: "web"},
+		{name: "host case insensitive", host: "A.com", method: "GET", path: "/", want: "web"},
+		{name: "more specific prefix", host: "a.com", method: "GET", path: "/api/users", want: "api"},
+		{name: "header", host: "a.com", method: "GET", path: "/api/users", header: http.Header{"X-Canary": {"1"}}, want: "canary"},
+		{name: "header value mismatch", host: "a.com", method: "GET", path: "/api/users", header: http.Header{"X-Canary": {"0"}}, want: "api"},
+		{name: "priority", host: "a.com", method: "GET", path: "/admin/users", want: "admin"},
+		{name: "method mismatch", host: "a.com", method: "POST", path: "/admin/users", want: "web"},
+		{name: "unknown host", host: "b.com", method: "GET", path: "/api/users", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, "http://"+tt.host+tt.path, nil)
+			if err != nil {
+				t.Fatalf("NewRequest() error = %v", err)
+			}
+			if tt.header != nil {
+				req.Header = tt.header
+			}
+
+			u, rule := MatchRule(req, tt.host)
+
+			var got string
+			if u != nil {
+				got = u.Name
+			}
+			if got != tt.want {
+				t.Errorf("MatchRule() = %q, want %q", got, tt.want)
+			}
+			if (u == nil) != (rule == nil) {
+				t.Errorf("MatchRule() rule = %v, upstream = %v", rule, u)
+			}
+		})
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	rule := newRule(t, &Rule{PathPrefix: "/api", Rewrite: "/"})
+
+	req, err := http.NewRequest("GET", "http://a.com/api/a%2Fb", nil)
+	if err != nil {
+		t.Fatalf("NewRequest() error = %v", err)
+	}
+
+	Rewrite(rule, req)
+	if req.URL.Path != "/a/b" || req.URL.RawPath != "" {
+		t.Errorf("Rewrite() path = %q, raw = %q, want %q", req.URL.Path, req.URL.RawPath, "/a/b")
+	}
+
+	Rewrite(nil, req)
+	if req.URL.Path != "/a/b" {
+		t.Errorf("Rewrite() by nil rule = %q, want unchanged", req.URL.Path)
+	}
+}
@@ -2,15 +2,180 @@ package upstream
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net/http"
 	"sync"
 	"time"
 
 	log "github.com/Sirupsen/logrus"
 )
 
+const (
+	StickyByIP     = "ip"
+	StickyByCookie = "cookie"
+	StickyByHeader = "header"
+
+	defaultStickyCookie   = "SWAN_STICKY"
+	defaultSessionTimeout = time.Hour
+
+	// max sessions of each upstream, as the header values are given by the
+	// clients, the oldest of the sampled sessions is evicted beyond.
+	maxSessions       = 65536
+	sessionEvictProbe = 8
+)
+
+// Stickiness configures how the clients stick to the backends, by the client
+// ip, by the cookie injected by the proxy, or by the named request header.
+type Stickiness struct {
+	Mode    string  `json:"mode" yaml:"mode"`                           // ip, cookie, header. default ip
+	Name    string  `json:"name,omitempty" yaml:"name,omitempty"`       // name of the cookie or header, default SWAN_STICKY for the cookie
+	Timeout float64 `json:"timeout,omitempty" yaml:"timeout,omitempty"` // idle timeout of the sessions by seconds, default 3600
+}
+
+func (s *Stickiness) Valid() error {
+	switch s.Mode {
+	case "", StickyByIP, StickyByCookie:
+	case StickyByHeader:
+		if s.Name == "" {
+			return errors.New("sticky header name required")
+		}
+	default:
+		return errors.New("unsupported sticky mode, should be [ip,cookie,header]")
+	}
+
+	if s.Timeout < 0 {
+		return errors.New("sticky session timeout can't be negative")
+	}
+
+	return nil
+}
+
+func (s *Stickiness) timeout() time.Duration {
+	return seconds(s.Timeout, defaultSessionTimeout)
+}
+
+// key returns the key the session of the client stored by, empty if not stored,
+// eg: by the cookie. the tcp connections are always keyed by the client ip.
+func (s *Stickiness) key(c *Client) string {
+	if c.Header == nil || s.Mode == StickyByIP {
+		return c.ip()
+	}
+	if s.Mode == StickyByHeader {
+		return c.header(s.Name)
+	}
+	return ""
+}
+
+// normalizeStickiness returns the stickiness with the defaults filled.
+func normalizeStickiness(s *Stickiness) *Stickiness {
+	ret := &Stickiness{Mode: StickyByIP}
+	if s != nil {
+		*ret = *s
+	}
+
+	switch ret.Mode {
+	case "":
+		ret.Mode = StickyByIP
+	case StickyByCookie:
+		if ret.Name == "" {
+			ret.Name = defaultStickyCookie
+		}
+	}
+	if ret.Mode == StickyByIP {
+		ret.Name = ""
+	}
+
+	return ret
+}
+
+// stickyToken is the value of the sticky cookie of the backend, which tells
+// nothing about the backend to the clients.
+func (b *Backend) stickyToken() string {
+	h := fnv.New64a()
+	h.Write([]byte(b.ID))
+	return fmt.Sprintf("%x", h.Sum64())
+}
+
+// stickiness returns nil if the upstream not sticky.
+func (u *Upstream) stickiness() *Stickiness {
+	mgr.RLock()
+	defer mgr.RUnlock()
+
+	if !u.Sticky {
+		return nil
+	}
+	return u.Stickiness
+}
+
+// stickyBackend returns the backend the client sticks to, nil if not stuck or
+// the backend has been ejected or drained.
+func (u *Upstream) stickyBackend(s *Stickiness, c *Client) *Backend {
+	var b *Backend
+
+	if s.Mode == StickyByCookie && c.Header != nil {
+		if token := c.cookie(s.Name); token != "" {
+			mgr.RLock()
+			for _, t := range u.Backends {
+				if t.stickyToken() == token {
+					b = t
+					break
+				}
+			}
+			mgr.RUnlock()
+		}
+	} else if key := s.key(c); key != "" {
+		b = u.sessions.get(key)
+	}
+
+	if b == nil || !b.available() {
+		return nil
+	}
+
+	mgr.RLock()
+	drained := b.Weight <= 0
+	mgr.RUnlock()
+
+	if drained {
+		return nil
+	}
+	return b
+}
+
+// stick stores the session of the client on the backend.
+func (u *Upstream) stick(c *Client, b *Backend) {
+	s := u.stickiness()
+	if s == nil {
+		return
+	}
+
+	if key := s.key(c); key != "" {
+		u.sessions.update(key, b)
+	}
+}
+
+// StickyCookie returns the cookie which sticks the client to the selected
+// backend, nil if the upstream is not sticky by cookie. the cookie is renewed
+// by each response, so it expires as the idle sessions.
+func StickyCookie(cmb *BackendCombined) *http.Cookie {
+	s := cmb.Upstream.stickiness()
+	if s == nil || s.Mode != StickyByCookie {
+		return nil
+	}
+
+	return &http.Cookie{
+		Name:     s.Name,
+		Value:    cmb.Backend.stickyToken(),
+		Path:     "/",
+		MaxAge:   int(s.timeout().Seconds()),
+		HttpOnly: true,
+	}
+}
+
 // Sessions
 type Sessions struct {
-	m            map[string]*session // ip -> session
+	m            map[string]*session // ip or header value -> session
 	sync.RWMutex                     // protect m
 	stopCh       chan struct{}       // quit
 	gcInterval   time.Duration       // gc interval
@@ -22,12 +187,12 @@ type session struct {
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
-func newSessions() *Sessions {
+func newSessions(timeout time.Duration) *Sessions {
 	b := &Sessions{
 		m:          make(map[string]*session),
 		stopCh:     make(chan struct{}),
 		gcInterval: time.Second * 10,
-		timeout:    time.Hour * 1,
+		timeout:    timeout,
 	}
 
 	go b.gc()
@@ -38,19 +203,52 @@ func (s *Sessions) MarshalJSON() ([]byte, error) {
 	return json.Marshal(s.m)
 }
 
-func (s *Sessions) get(ip string) *Backend {
+func (s *Sessions) get(key string) *Backend {
 	s.RLock()
 	defer s.RUnlock()
-	sess, ok := s.m[ip]
+	sess, ok := s.m[key]
 	if !ok {
 		return nil
 	}
 	return sess.Backend
 }
 
-func (s *Sessions) update(ip string, b *Backend) {
+func (s *Sessions) update(key string, b *Backend) {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.m[key]; !ok && len(s.m) >= maxSessions {
+		s.evict()
+	}
+	s.m[key] = &session{b, time.Now()}
+}
+
+// evict removes the oldest of the sessions sampled by the random map
+// iteration, the caller must hold the lock.
+func (s *Sessions) evict() {
+	var (
+		oldest string
+		at     time.Time
+		n      int
+	)
+
+	for key, sess := range s.m {
+		if oldest == "" || sess.UpdatedAt.Before(at) {
+			oldest, at = key, sess.UpdatedAt
+		}
+		if n++; n >= sessionEvictProbe {
+			break
+		}
+	}
+
+	delete(s.m, oldest)
+}
+
+// reset drops all of the sessions & applies the new timeout.
+func (s *Sessions) reset(timeout time.Duration) {
 	s.Lock()
-	s.m[ip] = &session{b, time.Now()}
+	s.m = make(map[string]*session)
+	s.timeout = timeout
 	s.Unlock()
 }
 
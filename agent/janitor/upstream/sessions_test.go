@@ -0,0 +1,73 @@
+package upstream
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSessionsBounded(t *testing.T) {
+	s := newSessions(time.Hour)
+	defer s.stop()
+
+	b := &Backend{ID: "b1"}
+	for i := 0; i < maxSessions+100; i++ {
+		s.update(strconv.Itoa(i), b)
+	}
+
+	if n := len(s.m); n != maxSessions {
+		t.Errorf("sessions = %d, want %d", n, maxSessions)
+	}
+
+	// the existing session is updated without eviction
+	s.update("latest", b)
+	s.update("latest", &Backend{ID: "b2"})
+	if n := len(s.m); n != maxSessions {
+		t.Errorf("sessions = %d, want %d", n, maxSessions)
+	}
+	if got := s.get("latest"); got == nil || got.ID != "b2" {
+		t.Errorf("get() = %v, want b2", got)
+	}
+}
+
+func TestStickinessKey(t *testing.T) {
+	tests := []struct {
+		name string
+		s    *Stickiness
+		c    *Client
+		want string
+	}{
+		{
+			name: "ip",
+			s:    normalizeStickiness(nil),
+			c:    &Client{IP: "1.1.1.1", Header: map[string][]string{"X-User": {"u1"}}},
+			want: "1.1.1.1",
+		},
+		{
+			name: "header",
+			s:    normalizeStickiness(&Stickiness{Mode: StickyByHeader, Name: "X-User"}),
+			c:    &Client{IP: "1.1.1.1", Header: map[string][]string{"X-User": {"u1"}}},
+			want: "u1",
+		},
+		{
+			name: "cookie not stored",
+			s:    normalizeStickiness(&Stickiness{Mode: StickyByCookie}),
+			c:    &Client{IP: "1.1.1.1", Header: map[string][]string{}},
+			want: "",
+		},
+		{
+			name: "tcp by ip",
+			s:    normalizeStickiness(&Stickiness{Mode: StickyByHeader, Name: "X-User"}),
+			c:    &Client{IP: "1.1.1.1"},
+			want: "1.1.1.1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.s.key(tt.c); got != tt.want {
+				t.Errorf("Stickiness.key() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -28,30 +28,49 @@ type Upstream struct {
 	Listen   string     `json:"listen"`   // listen addr
 	Target   string     `json:"target"`   // target addr
 	Sticky   bool       `json:"sticky"`   // session sticky enabled (default no)
+	Rules    []*Rule    `json:"rules"`    // http routing rules (optional)
 	Backends []*Backend `json:"backends"` // backend servers
 
+	HealthCheck *HealthCheck `json:"health_check,omitempty"` // backend health checking (optional)
+	Retry       *Retry       `json:"retry,omitempty"`        // failover on the backend failures (optional)
+	Balance     *Balance     `json:"balance"`                // load balancing algorithm
+	Stickiness  *Stickiness  `json:"stickiness"`             // session sticky mode & timeout
+
 	sessions *Sessions // runtime
 	balancer Balancer  // runtime
+	checker  *checker  // runtime
 }
 
 func (u *Upstream) String() string {
-	return fmt.Sprintf("name=%s, alias=%s, listen=%s, sticky=%v", u.Name, u.Alias, u.Listen, u.Sticky)
+	return fmt.Sprintf("name=%s, alias=%s, listen=%s, sticky=%v, balance=%s", u.Name, u.Alias, u.Listen, u.Sticky, u.Balance.Algorithm)
 }
 
 func newUpstream(first *BackendCombined) *Upstream {
-	return &Upstream{
-		Name:     first.Upstream.Name,
-		Alias:    first.Upstream.Alias,
-		Listen:   first.Upstream.Listen,
-		Target:   first.Upstream.Target,
-		Sticky:   first.Upstream.Sticky,
-		Backends: []*Backend{first.Backend},
-		sessions: newSessions(), // sessions store
-		balancer: &wrrBalancer{
-			index: -1,
-			cw:    0,
-		}, // default balancer
-	}
+	first.Backend.Health = &BackendHealth{}
+
+	var (
+		balance    = normalizeBalance(first.Upstream.Balance)
+		stickiness = normalizeStickiness(first.Upstream.Stickiness)
+	)
+
+	u := &Upstream{
+		Name:        first.Upstream.Name,
+		Alias:       first.Upstream.Alias,
+		Listen:      first.Upstream.Listen,
+		Target:      first.Upstream.Target,
+		Sticky:      first.Upstream.Sticky,
+		Stickiness:  stickiness,
+		Rules:       first.Upstream.Rules,
+		HealthCheck: first.Upstream.HealthCheck,
+		Retry:       first.Upstream.Retry,
+		Balance:     balance,
+		Backends:    []*Backend{first.Backend},
+		sessions:    newSessions(stickiness.timeout()), // sessions store
+		balancer:    newBalancer(balance),              // wrr by default
+	}
+
+	u.startChecker()
+	return u
 }
 
 func (u *Upstream) valid() error {
@@ -61,6 +80,31 @@ func (u *Upstream) valid() error {
 	if u.Name == "" {
 		return errors.New("upstream name required")
 	}
+	for _, r := range u.Rules {
+		if err := r.Valid(); err != nil {
+			return err
+		}
+	}
+	if hc := u.HealthCheck; hc != nil {
+		if err := hc.Valid(); err != nil {
+			return err
+		}
+	}
+	if r := u.Retry; r != nil {
+		if err := r.Valid(); err != nil {
+			return err
+		}
+	}
+	if bl := u.Balance; bl != nil {
+		if err := bl.Valid(); err != nil {
+			return err
+		}
+	}
+	if s := u.Stickiness; s != nil {
+		if err := s.Valid(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -96,6 +140,10 @@ type Backend struct {
 	Version    string  `json:"version"`
 	Weight     float64 `json:"weihgt"`
 	CleanName  string  `json:"clean_name"` // backend server clean id(name)
+
+	Health *BackendHealth `json:"health,omitempty"` // runtime ejection state
+
+	active int64 // runtime active connections
 }
 
 func (b *Backend) String() string {
@@ -220,6 +268,7 @@ func UpsertBackend(cmb *BackendCombined) (onFirst bool, err error) {
 
 	// add new backend
 	if b == nil {
+		cmb.Backend.Health = &BackendHealth{}
 		u.Backends = append(u.Backends, cmb.Backend)
 		return
 	}
@@ -227,6 +276,23 @@ func UpsertBackend(cmb *BackendCombined) (onFirst bool, err error) {
 	// update upstream
 	u.Alias = cmb.Upstream.Alias
 	u.Sticky = cmb.Upstream.Sticky
+	if s := normalizeStickiness(cmb.Upstream.Stickiness); *s != *u.Stickiness {
+		u.Stickiness = s
+		u.sessions.reset(s.timeout()) // the sessions keyed by the previous mode
+	}
+	u.Rules = cmb.Upstream.Rules
+	u.Retry = cmb.Upstream.Retry
+
+	if balance := normalizeBalance(cmb.Upstream.Balance); *balance != *u.Balance {
+		u.Balance = balance
+		u.balancer = newBalancer(balance)
+	}
+
+	if !sameHealthCheck(u.HealthCheck, cmb.Upstream.HealthCheck) {
+		u.stopChecker()
+		u.HealthCheck = cmb.Upstream.HealthCheck
+		u.startChecker()
+	}
 
 	// update backend
 	b.IP = cmb.Backend.IP
@@ -235,6 +301,11 @@ func UpsertBackend(cmb *BackendCombined) (onFirst bool, err error) {
 	b.Version = cmb.Backend.Version
 	b.Weight = cmb.Backend.Weight
 
+	// the drained backend loses its sessions
+	if b.Weight <= 0 {
+		u.sessions.remove(b.ID)
+	}
+
 	return
 }
 
@@ -265,14 +336,15 @@ func RemoveBackend(cmb *BackendCombined) (onLast bool) {
 		return
 	}
 
-	// remove backend & session
+	// remove backend & session, the backend might be specified by the clean name
 	u.Backends = append(u.Backends[:idxb], u.Backends[idxb+1:]...)
-	u.sessions.remove(backend)
+	u.sessions.remove(b.ID)
 
-	// remove empty upstream & stop sessions gc
+	// remove empty upstream & stop sessions gc & health checks
 	if len(u.Backends) == 0 {
 		onLast = true
 		u.sessions.stop()
+		u.stopChecker()
 		mgr.Upstreams = append(mgr.Upstreams[:idxu], mgr.Upstreams[idxu+1:]...)
 	}
 
@@ -280,7 +352,7 @@ func RemoveBackend(cmb *BackendCombined) (onLast bool) {
 }
 
 // similar as lookup, but by upstream alias
-func LookupAlias(remoteIP, alias string) *BackendCombined {
+func LookupAlias(c *Client, alias string) *BackendCombined {
 	mgr.RLock()
 	_, u := getUpstreamByAlias(alias)
 	mgr.RUnlock()
@@ -289,11 +361,11 @@ func LookupAlias(remoteIP, alias string) *BackendCombined {
 		return nil
 	}
 
-	return Lookup(remoteIP, u, "")
+	return Lookup(c, u, "")
 }
 
 // similar as lookup, but by upstream listen
-func LookupListen(remoteIP, listen string) *BackendCombined {
+func LookupListen(c *Client, listen string) *BackendCombined {
 	mgr.RLock()
 	_, u := getUpstreamByListen(listen)
 	mgr.RUnlock()
@@ -302,10 +374,10 @@ func LookupListen(remoteIP, listen string) *BackendCombined {
 		return nil
 	}
 
-	return Lookup(remoteIP, u, "")
+	return Lookup(c, u, "")
 }
 
-func LookupUpstream(remoteIP, name, port, backend string) *BackendCombined {
+func LookupUpstream(c *Client, name, port, backend string) *BackendCombined {
 	var up *Upstream
 	mgr.RLock()
 	for _, u := range mgr.Upstreams {
@@ -319,16 +391,16 @@ func LookupUpstream(remoteIP, name, port, backend string) *BackendCombined {
 		return nil
 	}
 
-	return Lookup(remoteIP, up, backend)
+	return Lookup(c, up, backend)
 }
 
 // lookup select a suitable backend according by sessions & balancer
-func Lookup(remoteIP string, u *Upstream, backend string) *BackendCombined {
+func Lookup(c *Client, u *Upstream, backend string) *BackendCombined {
 	var b *Backend
 
 	defer func() {
-		if u.Sticky && b != nil {
-			u.sessions.update(remoteIP, b)
+		if b != nil {
+			u.stick(c, b)
 		}
 	}()
 
@@ -341,22 +413,22 @@ func Lookup(remoteIP string, u *Upstream, backend string) *BackendCombined {
 		return &BackendCombined{u, b}
 	}
 
-	// obtain session by remoteIP
-	if u.Sticky {
-		if b = u.sessions.get(remoteIP); b != nil {
+	// obtain session by the client, the ejected or drained backend is not sticked
+	if s := u.stickiness(); s != nil {
+		if b = u.stickyBackend(s, c); b != nil {
 			return &BackendCombined{u, b}
 		}
 	}
 
 	// use balancer to obtain a new backend
-	if b = nextBackend(u); b != nil {
+	if b = nextBackend(u, c, nil); b != nil {
 		return &BackendCombined{u, b}
 	}
 
 	return nil
 }
 
-func nextBackend(u *Upstream) *Backend {
+func nextBackend(u *Upstream, c *Client, skip map[string]bool) *Backend {
 	mgr.RLock()
 	defer mgr.RUnlock()
 
@@ -364,7 +436,31 @@ func nextBackend(u *Upstream) *Backend {
 		return nil
 	}
 
-	return u.balancer.Next(u.Backends)
+	// the drained backends, weight 0, never take the new traffic, whatever
+	// the balance algorithm is.
+	candidates := make([]*Backend, 0, len(u.Backends))
+	for _, b := range u.Backends {
+		if !skip[b.ID] && b.Weight > 0 {
+			candidates = append(candidates, b)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	// skip the ejected backends, unless all of them are ejected, as serving by
+	// the ejected ones is better than serving nothing.
+	backends := make([]*Backend, 0, len(candidates))
+	for _, b := range candidates {
+		if b.available() {
+			backends = append(backends, b)
+		}
+	}
+	if len(backends) == 0 {
+		backends = candidates
+	}
+
+	return u.balancer.Next(backends, c)
 }
 
 // note: must be called under protection of mutext lock
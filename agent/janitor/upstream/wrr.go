@@ -5,7 +5,7 @@ type wrrBalancer struct {
 	cw    int
 }
 
-func (b *wrrBalancer) Next(bs []*Backend) *Backend {
+func (b *wrrBalancer) Next(bs []*Backend, _ *Client) *Backend {
 	if len(bs) == 0 {
 		return nil
 	}
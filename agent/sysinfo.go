@@ -212,6 +212,39 @@ func (g *gatherer) osListenings() error {
 	return nil
 }
 
+// gpu collects the nvidia gpus, the agent without nvidia driver has no gpus.
+func (g *gatherer) gpu() error {
+	g.info.GPU = types.GPUInfo{
+		Models: make([]string, 0),
+	}
+
+	dirs, err := ioutil.ReadDir("/proc/driver/nvidia/gpus")
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	reg := regexp.MustCompile(`^Model:\s+(.+)$`)
+	for _, dir := range dirs {
+		bs, err := ioutil.ReadFile(path.Join("/proc/driver/nvidia/gpus", dir.Name(), "information"))
+		if err != nil {
+			continue
+		}
+
+		g.info.GPU.Total++
+		for _, line := range strings.Split(string(bs), "\n") {
+			if subMatch := reg.FindStringSubmatch(line); len(subMatch) >= 2 {
+				g.info.GPU.Models = append(g.info.GPU.Models, strings.TrimSpace(subMatch[1]))
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
 func (g *gatherer) containers() error {
 	return nil // TODO
 }
@@ -226,6 +259,7 @@ func Gather() (*types.SysInfo, error) {
 		g.loadAvg,
 		g.cpu,
 		g.memory,
+		g.gpu,
 		g.ips,
 		g.containers,
 		g.osListenings,
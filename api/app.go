@@ -2,6 +2,7 @@ package api
 
 import (
 	"fmt"
+	"math"
 	"net/http"
 	"strconv"
 	"strings"
@@ -19,6 +20,14 @@ import (
 	"github.com/gorilla/mux"
 )
 
+const (
+	canarySoakInterval = time.Second * 3
+
+	// the tasks are scaled by batches, the paused or canceled scaling stops
+	// between the batches.
+	scaleBatch = 10
+)
+
 func (r *Server) createApp(w http.ResponseWriter, req *http.Request) {
 	if err := checkForJSON(req); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
@@ -30,6 +39,12 @@ func (r *Server) createApp(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	deadline, err := launchDeadline(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	var version types.Version
 	if err := decode(req.Body, &version); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
@@ -94,11 +109,15 @@ func (r *Server) createApp(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	d := r.beginDeployment(id, types.DeploymentCreate, r.triggeredBy(req), nil, version.ID)
+
 	go func(appId string) {
 		var err error
 
 		// defer to mark op status
 		defer func() {
+			r.endDeployment(d, err)
+
 			if err != nil {
 				log.Errorf("launch app %s error: %v", appId, err)
 				r.memoAppStatus(appId, types.OpStatusNoop, fmt.Sprintf("launch app error: %v", err))
@@ -121,10 +140,12 @@ func (r *Server) createApp(w http.ResponseWriter, req *http.Request) {
 			// runtime tasks
 			cfg := types.NewTaskConfig(&version, i)
 			t := mesos.NewTask(cfg, id, name)
+			t.SetDeadline(deadline)
 			tasks = append(tasks, t)
 		}
 
 		err = r.driver.LaunchTasks(tasks)
+		r.memoLaunched(d, version.ID, tasks)
 		if err != nil {
 			err = fmt.Errorf("launch tasks got error: %v", err)
 			return
@@ -296,6 +317,12 @@ func (r *Server) scaleApp(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	deadline, err := launchDeadline(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	var scale types.Scale
 	if err := decode(req.Body, &scale); err != nil {
 		http.Error(w, fmt.Sprintf("decode scale param error: %v", err), http.StatusBadRequest)
@@ -324,32 +351,69 @@ func (r *Server) scaleApp(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	ver, err := r.db.GetVersion(app.ID, app.Version[0])
-	if err != nil {
-		http.Error(w, fmt.Sprintf("get version got error for scale app. %v", err), http.StatusInternalServerError)
+	if goal > current {
+		ver, err := r.db.GetVersion(appId, app.Version[0])
+		if err != nil {
+			http.Error(w, fmt.Sprintf("get version got error for scale app. %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		net := ver.Container.Network()
+		if net != "host" && net != "bridge" {
+			if len(ips) < int(goal-current) {
+				http.Error(w, fmt.Sprintf("IP number cannot be less than the instance number"), http.StatusBadRequest)
+				return
+			}
+		}
+	}
+
+	if err := r.scale(app, tasks, goal, ips, r.triggeredBy(req), deadline); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	writeJSON(w, http.StatusAccepted, "accepted")
+}
+
+// scale launches or kills the app tasks in background until there are goal instances,
+// the new tasks wait offers until the deadline, or the scheduler's default if zero.
+// the caller must make sure the app op status is noop.
+func (r *Server) scale(app *types.Application, tasks []*types.Task, goal int, ips []string, triggered string, deadline time.Time) error {
+	var (
+		appId   = app.ID
+		current = len(tasks)
+	)
+
+	ver, err := r.db.GetVersion(appId, app.Version[0])
+	if err != nil {
+		return fmt.Errorf("get version got error for scale app. %v", err)
+	}
+
 	newVer := ver
 	newVer.ID = fmt.Sprintf("%d", time.Now().UTC().UnixNano())
 	newVer.Instances = int32(goal)
 	newVer.IPs = ips
 
 	if err := r.db.CreateVersion(appId, newVer); err != nil {
-		http.Error(w, fmt.Sprintf("create app version failed: %v", err), http.StatusInternalServerError)
-		return
+		return fmt.Errorf("create app version failed: %v", err)
 	}
 
 	if goal < current { // scale dwon
 		if err := r.memoAppStatus(appId, types.OpStatusScalingDown, ""); err != nil {
-			http.Error(w, fmt.Sprintf("update app opstatus to scaling down error: %v", err), http.StatusInternalServerError)
-			return
+			return fmt.Errorf("update app opstatus to scaling down error: %v", err)
 		}
 
+		var (
+			d = r.beginDeployment(appId, types.DeploymentScale, triggered, app.Version, newVer.ID)
+			o = r.startOperation(appId, types.OpStatusScalingDown)
+		)
 		go func() {
 			var err error
 
 			defer func() {
+				r.finishOperation(o)
+				r.endDeployment(d, err)
+
 				if err != nil {
 					log.Errorf("scale down app %s error: %v", appId, err)
 					r.memoAppStatus(appId, types.OpStatusNoop, fmt.Sprintf("scale down app error: %v", err))
@@ -364,58 +428,69 @@ func (r *Server) scaleApp(w http.ResponseWriter, req *http.Request) {
 			types.TaskList(tasks).Reverse() // TODO
 			var (
 				killing = tasks[:current-goal]
-				wg      sync.WaitGroup
-				succeed int64
+				failed  int64
 			)
-			for _, task := range killing {
-				wg.Add(1)
 
-				go func(task *types.Task) {
-					defer wg.Done()
+			// kill by batches in parallel, so the operation could be paused or canceled between batches
+			for start := 0; start < len(killing); start += scaleBatch {
+				if err = o.checkpoint(); err != nil {
+					return
+				}
+
+				end := start + scaleBatch
+				if end > len(killing) {
+					end = len(killing)
+				}
+
+				var wg sync.WaitGroup
+				for _, task := range killing[start:end] {
+					wg.Add(1)
 
-					if err := r.delTask(appId, task); err != nil {
-						return
-					}
+					go func(task *types.Task) {
+						defer wg.Done()
 
-					atomic.AddInt64(&succeed, 1)
-				}(task)
+						kerr := r.delTask(appId, task)
+						d.AddTask(task.ID, task.Name, task.Version, types.DeploymentTaskKill, kerr)
+						if kerr != nil {
+							atomic.AddInt64(&failed, 1)
+						}
+					}(task)
+				}
+				wg.Wait()
 			}
-			wg.Wait()
 
-			if int(succeed) != len(killing) {
-				err = fmt.Errorf("%d tasks failed", len(killing)-int(succeed))
+			if failed > 0 {
+				err = fmt.Errorf("%d tasks failed", failed)
 			}
 		}()
 
-		writeJSON(w, http.StatusAccepted, "accepted")
-		return
+		return nil
 	}
 
 	// scale up
 
 	version, err := r.db.GetVersion(appId, app.Version[0])
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	net := version.Container.Docker.Network
-	if net != "host" && net != "bridge" {
-		if len(ips) < int(goal-current) {
-			http.Error(w, fmt.Sprintf("IP number cannot be less than the instance number"), http.StatusBadRequest)
-			return
-		}
+		return err
 	}
 
 	if err := r.memoAppStatus(appId, types.OpStatusScalingUp, ""); err != nil {
-		http.Error(w, fmt.Sprintf("update app opstatus to scaling up error: %v", err), http.StatusInternalServerError)
-		return
+		return fmt.Errorf("update app opstatus to scaling up error: %v", err)
 	}
+
+	var (
+		d = r.beginDeployment(appId, types.DeploymentScale, triggered, app.Version, newVer.ID)
+		o = r.startOperation(appId, types.OpStatusScalingUp)
+	)
+
 	go func() {
 		var err error
 
 		// defer to mark op status
 		defer func() {
+			r.finishOperation(o)
+			r.endDeployment(d, err)
+
 			if err != nil {
 				log.Errorf("scale up app %s error: %v", appId, err)
 				r.memoAppStatus(appId, types.OpStatusNoop, fmt.Sprintf("scale up app error: %v", err))
@@ -428,57 +503,77 @@ func (r *Server) scaleApp(w http.ResponseWriter, req *http.Request) {
 		log.Printf("Preparing to scale up App %s", appId)
 
 		var (
-			tasks = []*mesos.Task{}
+			failed int
+			last   error
 		)
 
-		// prepare for all of runtime tasks & db tasks
-		for i := current; i < goal; i++ {
-			var (
-				name    = fmt.Sprintf("%d.%s", i, appId)
-				id      = fmt.Sprintf("%s.%s", utils.RandomString(12), name)
-				restart = version.RestartPolicy
-				retries = 3
-			)
+		// launch by batches, so the operation could be paused or canceled between batches
+		for start := current; start < goal; start += scaleBatch {
+			if err = o.checkpoint(); err != nil {
+				return
+			}
 
-			if restart != nil && restart.Retries >= 0 {
-				retries = restart.Retries
+			end := start + scaleBatch
+			if end > goal {
+				end = goal
 			}
 
-			// runtime tasks
-			cfg := types.NewTaskConfig(version, i)
-			t := mesos.NewTask(cfg, id, name)
-			tasks = append(tasks, t)
+			tasks := make([]*mesos.Task, 0, end-start)
+			for i := start; i < end; i++ {
+				var (
+					name    = fmt.Sprintf("%d.%s", i, appId)
+					id      = fmt.Sprintf("%s.%s", utils.RandomString(12), name)
+					restart = version.RestartPolicy
+					retries = 3
+				)
+
+				if restart != nil && restart.Retries >= 0 {
+					retries = restart.Retries
+				}
 
-			// db tasks
-			task := &types.Task{
-				ID:         id,
-				Name:       name,
-				Weight:     100,
-				Status:     "pending",
-				Healthy:    types.TaskHealthyUnset,
-				Version:    version.ID,
-				MaxRetries: retries,
-				Created:    time.Now(),
-				Updated:    time.Now(),
-			}
-			if version.IsHealthSet() {
-				task.Healthy = types.TaskUnHealthy
+				// runtime tasks
+				cfg := types.NewTaskConfig(version, i)
+				t := mesos.NewTask(cfg, id, name)
+				t.SetDeadline(deadline)
+				tasks = append(tasks, t)
+
+				// db tasks
+				task := &types.Task{
+					ID:         id,
+					Name:       name,
+					Weight:     100,
+					Status:     "pending",
+					Healthy:    types.TaskHealthyUnset,
+					Version:    version.ID,
+					MaxRetries: retries,
+					Created:    time.Now(),
+					Updated:    time.Now(),
+				}
+				if version.IsHealthSet() {
+					task.Healthy = types.TaskUnHealthy
+				}
+
+				if err = r.db.CreateTask(appId, task); err != nil {
+					err = fmt.Errorf("create db task failed: %v", err)
+					return
+				}
 			}
 
-			if err = r.db.CreateTask(appId, task); err != nil {
-				err = fmt.Errorf("create db task failed: %v", err)
-				return
+			lerr := r.driver.LaunchTasks(tasks)
+			r.memoLaunched(d, version.ID, tasks)
+			if lerr != nil {
+				log.Errorf("scale up app %s launch %d task(s) error: %v", appId, len(tasks), lerr)
+				failed++
+				last = lerr
 			}
 		}
 
-		err = r.driver.LaunchTasks(tasks)
-		if err != nil {
-			err = fmt.Errorf("launch tasks got error: %v", err)
-			return
+		if failed > 0 {
+			err = fmt.Errorf("launch tasks got error: %d batch(es) launch failed, last error: %v", failed, last)
 		}
 	}()
 
-	writeJSON(w, http.StatusAccepted, "accepted")
+	return nil
 }
 
 func (r *Server) updateApp(w http.ResponseWriter, req *http.Request) {
@@ -495,6 +590,12 @@ func (r *Server) updateApp(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	deadline, err := launchDeadline(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	newVer := new(types.Version)
 	if err := decode(req.Body, newVer); err != nil {
 		http.Error(w, fmt.Sprintf("decode update version got error: %v", err), http.StatusBadRequest)
@@ -537,10 +638,36 @@ func (r *Server) updateApp(w http.ResponseWriter, req *http.Request) {
 	types.TaskList(tasks).Sort()
 	pending := tasks
 
+	var (
+		d = r.beginDeployment(appId, types.DeploymentUpdate, r.triggeredBy(req), app.Version, newVer.ID)
+		o = r.startOperation(appId, types.OpStatusUpdating)
+	)
+
 	go func() {
-		var err error
+		var (
+			err      error
+			updated  = make([]string, 0)          // new task ids which have been launched
+			previous = make(map[string]string, 0) // new task id -> version id of the replaced task
+			failed   = make([]string, 0)          // launch errors of the new tasks skipped by onfailure=continue
+		)
 
 		defer func() {
+			r.finishOperation(o)
+
+			// the canceled update stays where it is, could be updated again later.
+			if err != nil && err != errOperationCanceled && onfailure == types.UpdateRollback && len(updated) > 0 {
+				log.Errorf("update app %s error: %v, rolling back %d task(s)", appId, err, len(updated))
+				r.memoAppStatus(appId, types.OpStatusRollback, "")
+
+				if rerr := r.rollbackTasks(d, appId, updated, previous); rerr != nil {
+					err = fmt.Errorf("%v, rollback error: %v", err, rerr)
+				} else {
+					err = fmt.Errorf("%v, rolled back to previous version", err)
+				}
+			}
+
+			r.endDeployment(d, err)
+
 			if err != nil {
 				log.Errorf("update app %s error: %v", appId, err)
 				r.memoAppStatus(appId, types.OpStatusNoop, fmt.Sprintf("update app error: %v", err))
@@ -553,9 +680,14 @@ func (r *Server) updateApp(w http.ResponseWriter, req *http.Request) {
 		log.Printf("Preparing to update App %s", appId)
 
 		for i, t := range pending {
+			if err = o.checkpoint(); err != nil {
+				return
+			}
 
 			// kill & remove old
-			if err = r.delTask(appId, t); err != nil {
+			err = r.delTask(appId, t)
+			d.AddTask(t.ID, t.Name, t.Version, types.DeploymentTaskKill, err)
+			if err != nil {
 				err = fmt.Errorf("remove old task error: %v", err)
 				return
 			}
@@ -592,28 +724,54 @@ func (r *Server) updateApp(w http.ResponseWriter, req *http.Request) {
 				return
 			}
 
+			// the old task has gone, so the new one should be rolled back whether it's launched or not
+			updated = append(updated, task.ID)
+			previous[task.ID] = t.Version
+
 			// launch runtime new task
 			cfg := types.NewTaskConfig(newVer, i)
 			m := mesos.NewTask(cfg, task.ID, task.Name)
+			m.SetDeadline(deadline)
 			tasks := []*mesos.Task{m}
 
 			if err = r.driver.LaunchTasks(tasks); err != nil {
 				err = fmt.Errorf("launch new runtime task error: %v", err)
+				d.AddTask(task.ID, task.Name, newVer.ID, types.DeploymentTaskLaunch, err)
 
 				task.Status = "Failed"
 				task.ErrMsg = err.Error()
-				if err = r.db.UpdateTask(appId, task); err != nil {
+				if err := r.db.UpdateTask(appId, task); err != nil {
 					log.Errorf("update task %s got error: %v", id, err)
 				}
 
-				if onfailure == types.UpdateStop {
+				if onfailure != types.UpdateContinue {
+					return
+				}
+
+				// carry on, the failure is reported after all tasks updated
+				failed = append(failed, fmt.Sprintf("%s: %v", task.ID, err))
+			}
+
+			// make sure the new task turns healthy before moving on, otherwise rollback.
+			if err == nil && onfailure == types.UpdateRollback && newVer.IsHealthSet() {
+				if err = r.waitTaskHealthy(appId, task.ID, newVer.HealthCheck); err != nil {
+					err = fmt.Errorf("new task %s never become healthy: %v", task.ID, err)
+					d.AddTask(task.ID, task.Name, newVer.ID, types.DeploymentTaskLaunch, err)
 					return
 				}
 			}
 
+			if err == nil {
+				d.AddTask(task.ID, task.Name, newVer.ID, types.DeploymentTaskLaunch, nil)
+			}
+
 			// notify proxy
 			time.Sleep(time.Duration(delay) * time.Second)
 		}
+
+		if len(failed) > 0 {
+			err = fmt.Errorf("%d of %d new task(s) launch failed: %s", len(failed), len(pending), strings.Join(failed, "; "))
+		}
 	}()
 
 	writeJSON(w, http.StatusAccepted, "accepted")
@@ -633,17 +791,35 @@ func (s *Server) startApp(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	ver, err := s.db.GetVersion(appId, app.Version[0])
+	deadline, err := launchDeadline(req)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("get app version error: %v", err), http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	if err := s.memoAppStatus(appId, types.OpStatusStarting, ""); err != nil {
-		http.Error(w, fmt.Sprintf("update app opstatus to stopping got error: %v", err), http.StatusInternalServerError)
+	if err := s.start(app, s.triggeredBy(req), deadline); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	writeJSON(w, http.StatusAccepted, "accepted")
+}
+
+// start launches all of the app tasks of the current version in background,
+// the tasks wait offers until the deadline, or the scheduler's default if zero.
+// the caller must make sure the app op status is noop.
+func (s *Server) start(app *types.Application, triggered string, deadline time.Time) error {
+	appId := app.ID
+
+	ver, err := s.db.GetVersion(appId, app.Version[0])
+	if err != nil {
+		return fmt.Errorf("get app version error: %v", err)
+	}
+
+	if err := s.memoAppStatus(appId, types.OpStatusStarting, ""); err != nil {
+		return fmt.Errorf("update app opstatus to starting got error: %v", err)
+	}
+
 	var (
 		count   = int(ver.Instances)
 		restart = ver.RestartPolicy
@@ -654,11 +830,15 @@ func (s *Server) startApp(w http.ResponseWriter, req *http.Request) {
 		retries = restart.Retries
 	}
 
+	d := s.beginDeployment(appId, types.DeploymentStart, triggered, app.Version, ver.ID)
+
 	go func(appId string) {
 		var err error
 
 		// defer to mark op status
 		defer func() {
+			s.endDeployment(d, err)
+
 			if err != nil {
 				log.Errorf("start app %s error: %v", appId, err)
 				s.memoAppStatus(appId, types.OpStatusNoop, fmt.Sprintf("start app error: %v", err))
@@ -680,6 +860,7 @@ func (s *Server) startApp(w http.ResponseWriter, req *http.Request) {
 			// runtime tasks
 			cfg := types.NewTaskConfig(ver, i)
 			t := mesos.NewTask(cfg, id, name)
+			t.SetDeadline(deadline)
 			tasks = append(tasks, t)
 
 			// save db tasks
@@ -707,13 +888,14 @@ func (s *Server) startApp(w http.ResponseWriter, req *http.Request) {
 		}
 
 		err = s.driver.LaunchTasks(tasks)
+		s.memoLaunched(d, ver.ID, tasks)
 		if err != nil {
 			err = fmt.Errorf("launch tasks got error: %v", err)
 			return
 		}
 	}(appId)
 
-	writeJSON(w, http.StatusAccepted, "accepted")
+	return nil
 }
 
 func (s *Server) stopApp(w http.ResponseWriter, req *http.Request) {
@@ -736,15 +918,31 @@ func (s *Server) stopApp(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	if err := s.memoAppStatus(appId, types.OpStatusStopping, ""); err != nil {
-		http.Error(w, fmt.Sprintf("update app opstatus to stopping got error: %v", err), http.StatusInternalServerError)
+	if err := s.stop(app, tasks, s.triggeredBy(req)); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	writeJSON(w, http.StatusAccepted, "accepted")
+}
+
+// stop kills all of the app tasks in background, the caller must make sure
+// the app op status is noop.
+func (s *Server) stop(app *types.Application, tasks []*types.Task, triggered string) error {
+	appId := app.ID
+
+	if err := s.memoAppStatus(appId, types.OpStatusStopping, ""); err != nil {
+		return fmt.Errorf("update app opstatus to stopping got error: %v", err)
+	}
+
+	d := s.beginDeployment(appId, types.DeploymentStop, triggered, app.Version, "")
+
 	go func() {
 		var err error
 
 		defer func() {
+			s.endDeployment(d, err)
+
 			if err != nil {
 				log.Errorf("stop app %s error: %v", appId, err)
 				s.memoAppStatus(appId, types.OpStatusNoop, fmt.Sprintf("stop app error: %v", err))
@@ -762,8 +960,10 @@ func (s *Server) stopApp(w http.ResponseWriter, req *http.Request) {
 			go func(task *types.Task) {
 				defer wg.Done()
 
-				if err := s.delTask(appId, task); err != nil {
-					log.Errorf("app %s stop task %s error: %v", appId, task.ID)
+				err := s.delTask(appId, task)
+				d.AddTask(task.ID, task.Name, task.Version, types.DeploymentTaskKill, err)
+				if err != nil {
+					log.Errorf("app %s stop task %s error: %v", appId, task.ID, err)
 				}
 			}(task)
 		}
@@ -771,7 +971,7 @@ func (s *Server) stopApp(w http.ResponseWriter, req *http.Request) {
 		wg.Wait()
 	}()
 
-	writeJSON(w, http.StatusAccepted, "accepted")
+	return nil
 }
 
 func (r *Server) canaryUpdate(w http.ResponseWriter, req *http.Request) {
@@ -795,6 +995,13 @@ func (r *Server) canaryUpdate(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	if plan := canary.Plan; plan != nil {
+		if err := plan.Valid(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
 	var (
 		newVer    = canary.Version
 		value     = canary.Value
@@ -834,6 +1041,15 @@ func (r *Server) canaryUpdate(w http.ResponseWriter, req *http.Request) {
 		newVer = versions[0]
 	}
 
+	if delay == 0 {
+		delay = types.DefaultCanaryUpdateDelay
+	}
+
+	if plan := canary.Plan; plan != nil {
+		r.canaryPlan(w, req, app, newVer, plan, onfailure, delay)
+		return
+	}
+
 	if value <= 0 || value > 1 {
 		http.Error(w, "canary value must between (0, 1]", http.StatusBadRequest)
 		return
@@ -843,10 +1059,6 @@ func (r *Server) canaryUpdate(w http.ResponseWriter, req *http.Request) {
 		count = 1
 	}
 
-	if delay == 0 {
-		delay = types.DefaultCanaryUpdateDelay
-	}
-
 	// obtain all db tasks
 	tasks, err := r.db.ListTasks(app.ID)
 	if err != nil {
@@ -854,8 +1066,6 @@ func (r *Server) canaryUpdate(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	types.TaskList(tasks).Sort()
-
 	// current progress
 	new := 0
 	for _, task := range tasks {
@@ -864,21 +1074,7 @@ func (r *Server) canaryUpdate(w http.ResponseWriter, req *http.Request) {
 		}
 	}
 
-	var (
-		total = len(tasks)
-		goal  = new + count
-	)
-
-	if goal >= total {
-		goal = total
-		if value != 1 {
-			value = 1
-		}
-	}
-
-	newWeight := utils.ComputeWeight(float64(goal), float64(total), value)
-	pending := tasks[:goal]
-	oldTasks := tasks[goal:]
+	goal := new + count
 
 	// mark app db status
 	if err := r.memoAppStatus(appId, types.OpStatusCanaryUpdating, ""); err != nil {
@@ -886,14 +1082,22 @@ func (r *Server) canaryUpdate(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	var (
+		d = r.beginDeployment(appId, types.DeploymentCanary, r.triggeredBy(req), app.Version, newVer.ID)
+		o = r.startOperation(appId, types.OpStatusCanaryUpdating)
+	)
+
 	go func() {
 		var (
-			err      error
-			progress int
+			err  error
+			done bool
 		)
 
 		// defer to mark app db status
 		defer func() {
+			r.finishOperation(o)
+			r.endDeployment(d, err)
+
 			var (
 				errmsg   string
 				opStatus = types.OpStatusCanaryUnfinished
@@ -906,7 +1110,7 @@ func (r *Server) canaryUpdate(w http.ResponseWriter, req *http.Request) {
 				log.Printf("canary update app %s succeed", appId)
 			}
 
-			if progress >= total {
+			if done {
 				opStatus = types.OpStatusNoop
 			}
 
@@ -915,106 +1119,333 @@ func (r *Server) canaryUpdate(w http.ResponseWriter, req *http.Request) {
 
 		log.Printf("Preparing to canary update App %s", appId)
 
-		for i, t := range pending {
-			progress = i + 1
+		done, err = r.canaryStep(o, d, appId, newVer, goal, value, onfailure, delay)
+	}()
 
-			if t.Version == newVer.ID {
-				t.Weight = newWeight
+	writeJSON(w, http.StatusAccepted, "accepted")
+}
 
-				if uerr := r.db.UpdateTask(appId, t); uerr != nil {
-					err = fmt.Errorf("update task %s weight got error: %v", t.ID, uerr)
-					return
-				}
-				// TODO notify proxy
+// canaryPlan runs each step of the canary plan one by one in background, the plan
+// advances only if the new version tasks pass the soak checking after each step.
+func (r *Server) canaryPlan(w http.ResponseWriter, req *http.Request, app *types.Application, newVer *types.Version, plan *types.CanaryPlan, onfailure string, delay float64) {
+	appId := app.ID
 
-				log.Debugf("Sending task event to proxy for weight changed. taskId: %s weight: %.f", t.ID, newWeight)
-				if err := r.driver.SendEvent(appId, t); err != nil {
-					log.Errorf("Sending event got error: %v", err)
-				}
+	tasks, err := r.db.ListTasks(appId)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("list tasks got error for canary update. %v", err), http.StatusInternalServerError)
+		return
+	}
 
-				continue
-			}
+	var (
+		total = len(tasks)
+		soak  = plan.Soak
+	)
 
-			// remove old task
-			if err = r.delTask(appId, t); err != nil {
-				err = fmt.Errorf("remove old task %s error: %v", t.ID, err)
-				return
-			}
+	if soak == 0 {
+		soak = types.DefaultCanarySoak
+	}
+
+	// mark app db status
+	if err := r.memoAppStatus(appId, types.OpStatusCanaryUpdating, ""); err != nil {
+		http.Error(w, fmt.Sprintf("update app opstatus to canary-update got error: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	var (
+		d = r.beginDeployment(appId, types.DeploymentCanary, r.triggeredBy(req), app.Version, newVer.ID)
+		o = r.startOperation(appId, types.OpStatusCanaryUpdating)
+	)
+
+	go func() {
+		var (
+			err  error
+			done bool
+		)
+
+		defer func() {
+			r.finishOperation(o)
+			r.endDeployment(d, err)
 
 			var (
-				name    = t.Name
-				id      = fmt.Sprintf("%s.%s", utils.RandomString(12), name)
-				restart = newVer.RestartPolicy
-				retries = 3
+				errmsg   string
+				opStatus = types.OpStatusCanaryUnfinished
 			)
 
-			if restart != nil && restart.Retries >= 0 {
-				retries = restart.Retries
+			if err != nil {
+				log.Errorf("canary plan of app %s error: %v", appId, err)
+				errmsg = fmt.Sprintf("canary update error: %v", err)
+			} else {
+				log.Printf("canary plan of app %s succeed", appId)
 			}
 
-			// db save new task
-			task := &types.Task{
-				ID:         id,
-				Name:       name,
-				Weight:     newWeight,
-				Healthy:    types.TaskHealthyUnset,
-				Version:    newVer.ID,
-				MaxRetries: retries,
-				Created:    t.Created,
-				Updated:    time.Now(),
+			if done {
+				opStatus = types.OpStatusNoop
 			}
-			if newVer.IsHealthSet() {
-				task.Healthy = types.TaskUnHealthy
+
+			r.memoAppStatus(appId, opStatus, errmsg)
+		}()
+
+		log.Printf("Preparing to run %d steps canary plan for App %s", len(plan.Steps), appId)
+
+		for n, step := range plan.Steps {
+			goal := step.Instances
+			if goal == 0 {
+				goal = int(math.Ceil(float64(total) * step.Value))
 			}
 
-			if err = r.db.CreateTask(appId, task); err != nil {
-				err = fmt.Errorf("create db task %s error: %v", id, err)
+			log.Printf("canary plan step %d of app %s: instances=%d value=%.2f", n+1, appId, goal, step.Value)
+
+			if done, err = r.canaryStep(o, d, appId, newVer, goal, step.Value, onfailure, delay); err != nil {
+				if err != errOperationCanceled {
+					err = fmt.Errorf("step %d: %v", n+1, err)
+				}
 				return
 			}
 
-			// launch new runtime task
-			cfg := types.NewTaskConfig(newVer, i+new)
-			m := mesos.NewTask(cfg, task.ID, task.Name)
-			tasks := []*mesos.Task{m}
+			// nothing to go back
+			if done {
+				return
+			}
 
-			if err = r.driver.LaunchTasks(tasks); err != nil {
-				task.Status = "Failed"
-				task.ErrMsg = err.Error()
-				if err = r.db.UpdateTask(appId, task); err != nil {
-					log.Errorf("update task %s got error: %v", id, err)
+			if err = r.canarySoak(o, appId, newVer.ID, soak, plan.MaxErrorRate); err != nil {
+				if err == errOperationCanceled {
+					return
 				}
 
-				if onfailure == types.CanaryUpdateOnFailureStop {
-					return
+				err = fmt.Errorf("step %d aborted: %v", n+1, err)
+
+				if werr := r.updateTaskWeights(appId, newVer.ID, 0); werr != nil {
+					err = fmt.Errorf("%v, restore weights error: %v", err, werr)
 				}
+				return
 			}
+		}
+	}()
 
-			time.Sleep(time.Duration(delay) * time.Second)
+	writeJSON(w, http.StatusAccepted, "accepted")
+}
+
+// canaryStep update the first `goal` tasks to the new version if they're not yet,
+// and shift `value` of traffic weights to the new version tasks. it returns true
+// if all of the tasks have been updated to the new version.
+func (r *Server) canaryStep(o *operation, d *types.Deployment, appId string, newVer *types.Version, goal int, value float64, onfailure string, delay float64) (bool, error) {
+	tasks, err := r.db.ListTasks(appId)
+	if err != nil {
+		return false, fmt.Errorf("list tasks got error: %v", err)
+	}
+
+	types.TaskList(tasks).Sort()
+
+	// current progress
+	new := 0
+	for _, task := range tasks {
+		if task.Version == newVer.ID {
+			new++
+		}
+	}
+
+	total := len(tasks)
+
+	if goal >= total {
+		goal = total
+		if value != 1 {
+			value = 1
+		}
+	}
+
+	var (
+		newWeight = utils.ComputeWeight(float64(goal), float64(total), value)
+		pending   = tasks[:goal]
+		oldTasks  = tasks[goal:]
+		progress  int
+	)
+
+	for i, t := range pending {
+		if err := o.checkpoint(); err != nil {
+			return progress >= total, err
+		}
+
+		progress = i + 1
+
+		if t.Version == newVer.ID {
+			t.Weight = newWeight
+
+			if err := r.db.UpdateTask(appId, t); err != nil {
+				return progress >= total, fmt.Errorf("update task %s weight got error: %v", t.ID, err)
+			}
+
+			log.Debugf("Sending task event to proxy for weight changed. taskId: %s weight: %.f", t.ID, newWeight)
+			if err := r.driver.SendEvent(appId, t); err != nil {
+				log.Errorf("Sending event got error: %v", err)
+			}
+
+			continue
+		}
+
+		// remove old task
+		err := r.delTask(appId, t)
+		d.AddTask(t.ID, t.Name, t.Version, types.DeploymentTaskKill, err)
+		if err != nil {
+			return progress >= total, fmt.Errorf("remove old task %s error: %v", t.ID, err)
+		}
+
+		var (
+			name    = t.Name
+			id      = fmt.Sprintf("%s.%s", utils.RandomString(12), name)
+			restart = newVer.RestartPolicy
+			retries = 3
+		)
+
+		if restart != nil && restart.Retries >= 0 {
+			retries = restart.Retries
+		}
+
+		// db save new task
+		task := &types.Task{
+			ID:         id,
+			Name:       name,
+			Weight:     newWeight,
+			Healthy:    types.TaskHealthyUnset,
+			Version:    newVer.ID,
+			MaxRetries: retries,
+			Created:    t.Created,
+			Updated:    time.Now(),
+		}
+		if newVer.IsHealthSet() {
+			task.Healthy = types.TaskUnHealthy
+		}
+
+		if err := r.db.CreateTask(appId, task); err != nil {
+			return progress >= total, fmt.Errorf("create db task %s error: %v", id, err)
 		}
 
-		// reset the rest of task's weight to 100.
-		for _, task := range oldTasks {
-			if task.Weight == 0 {
-				task.Weight = 100
+		// launch new runtime task
+		cfg := types.NewTaskConfig(newVer, i+new)
+		m := mesos.NewTask(cfg, task.ID, task.Name)
+		tasks := []*mesos.Task{m}
+
+		err = r.driver.LaunchTasks(tasks)
+		d.AddTask(task.ID, task.Name, newVer.ID, types.DeploymentTaskLaunch, err)
+		if err != nil {
+			task.Status = "Failed"
+			task.ErrMsg = err.Error()
+			if err := r.db.UpdateTask(appId, task); err != nil {
+				log.Errorf("update task %s got error: %v", id, err)
 			}
 
-			log.Debugf("updating weight to 100 for task %s", task.ID)
-			if uerr := r.db.UpdateTask(appId, task); uerr != nil {
-				err = fmt.Errorf("update task %s weight got error: %v", task.ID, uerr)
-				return
+			if onfailure == types.CanaryUpdateOnFailureStop {
+				return progress >= total, fmt.Errorf("launch task %s error: %v", id, err)
 			}
+		}
 
-			// notify proxy
-			log.Debugf("Sending task event to proxy for weight changed. taskId: %s weight: 100", task.ID)
-			if err := r.driver.SendEvent(appId, task); err != nil {
-				log.Errorf("updateWeights(): sending task %s event failed: %v", task.ID, err)
+		time.Sleep(time.Duration(delay) * time.Second)
+	}
+
+	// reset the rest of task's weight to 100.
+	for _, task := range oldTasks {
+		if task.Weight == 0 {
+			task.Weight = 100
+		}
+
+		log.Debugf("updating weight to 100 for task %s", task.ID)
+		if err := r.db.UpdateTask(appId, task); err != nil {
+			return progress >= total, fmt.Errorf("update task %s weight got error: %v", task.ID, err)
+		}
+
+		// notify proxy
+		log.Debugf("Sending task event to proxy for weight changed. taskId: %s weight: 100", task.ID)
+		if err := r.driver.SendEvent(appId, task); err != nil {
+			log.Errorf("updateWeights(): sending task %s event failed: %v", task.ID, err)
+		}
+	}
+
+	return progress >= total, nil
+}
+
+// canarySoak keeps watching the new version tasks for `soak` seconds, it fails
+// once any of them turns unhealthy or not running, or the proxy error rate of
+// them during the soak exceeds `maxErrRate`.
+func (r *Server) canarySoak(o *operation, appId, verId string, soak, maxErrRate float64) error {
+	var (
+		deadline = time.Now().Add(time.Duration(soak * float64(time.Second)))
+		base     map[string]*proxyCounter // the counters when the soak started
+	)
+
+	if maxErrRate > 0 {
+		base = r.getAppProxyCounters(appId)
+	}
+
+	for {
+		if err := o.checkpoint(); err != nil {
+			return err
+		}
+
+		tasks, err := r.db.ListTasks(appId)
+		if err != nil {
+			return fmt.Errorf("list tasks got error: %v", err)
+		}
+
+		backends := make(map[string]bool)
+		for _, task := range tasks {
+			if task.Version != verId {
+				continue
+			}
+
+			if task.Status != "TASK_RUNNING" {
+				return fmt.Errorf("task %s is %s", task.ID, task.Status)
+			}
+
+			if task.Healthy == types.TaskUnHealthy {
+				return fmt.Errorf("task %s is unhealthy", task.ID)
 			}
 
+			backends[task.ID] = true
 		}
 
-	}()
+		if maxErrRate > 0 {
+			if rate, ok := soakErrRate(base, r.getAppProxyCounters(appId), backends); ok && rate > maxErrRate {
+				return fmt.Errorf("proxy error rate %.4f exceeds %.4f", rate, maxErrRate)
+			}
+		}
 
-	writeJSON(w, http.StatusAccepted, "accepted")
+		if time.Now().After(deadline) {
+			return nil
+		}
+
+		time.Sleep(canarySoakInterval)
+	}
+}
+
+// soakErrRate computes the error rate of the backends since the base counters,
+// both of the proxy failures and the 5xx responses are counted as errors. false
+// if no requests yet.
+func soakErrRate(base, current map[string]*proxyCounter, backends map[string]bool) (float64, bool) {
+	var reqs, fails uint64
+
+	for id, c := range current {
+		if !backends[id] {
+			continue
+		}
+
+		var (
+			nReq  = c.Requests
+			nFail = c.Fails + c.Status5xx
+		)
+
+		// the counters may be reset by the agent restarts
+		if b, ok := base[id]; ok && b.Requests <= nReq && b.Fails+b.Status5xx <= nFail {
+			nReq -= b.Requests
+			nFail -= b.Fails + b.Status5xx
+		}
+
+		reqs += nReq
+		fails += nFail
+	}
+
+	if reqs == 0 {
+		return 0, false
+	}
+	return float64(fails) / float64(reqs), true
 }
 
 func (r *Server) rollback(w http.ResponseWriter, req *http.Request) {
@@ -1023,6 +1454,12 @@ func (r *Server) rollback(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	deadline, err := launchDeadline(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	appId := mux.Vars(req)["app_id"]
 
 	app, err := r.db.GetApp(appId)
@@ -1087,10 +1524,18 @@ func (r *Server) rollback(w http.ResponseWriter, req *http.Request) {
 	// TODO
 	types.TaskList(tasks).Reverse()
 
+	var (
+		d = r.beginDeployment(appId, types.DeploymentRollback, r.triggeredBy(req), app.Version, desired.ID)
+		o = r.startOperation(appId, types.OpStatusRollback)
+	)
+
 	go func() {
 		var err error
 
 		defer func() {
+			r.finishOperation(o)
+			r.endDeployment(d, err)
+
 			if err != nil {
 				log.Errorf("rollback app %s error: %v", appId, err)
 				r.memoAppStatus(appId, types.OpStatusNoop, fmt.Sprintf("rollback app error: %v", err))
@@ -1103,9 +1548,14 @@ func (r *Server) rollback(w http.ResponseWriter, req *http.Request) {
 		log.Printf("Preparing to rollback App %s", appId)
 
 		for i, t := range tasks {
+			if err = o.checkpoint(); err != nil {
+				return
+			}
 
 			// remove old task
-			if err = r.delTask(appId, t); err != nil {
+			err = r.delTask(appId, t)
+			d.AddTask(t.ID, t.Name, t.Version, types.DeploymentTaskKill, err)
+			if err != nil {
 				err = fmt.Errorf("remove old task error: %v", err)
 				return
 			}
@@ -1146,9 +1596,12 @@ func (r *Server) rollback(w http.ResponseWriter, req *http.Request) {
 			// launch runtime task
 			cfg := types.NewTaskConfig(desired, i)
 			m := mesos.NewTask(cfg, task.ID, task.Name)
+			m.SetDeadline(deadline)
 			tasks := []*mesos.Task{m}
 
-			if err = r.driver.LaunchTasks(tasks); err != nil {
+			err = r.driver.LaunchTasks(tasks)
+			d.AddTask(task.ID, task.Name, desired.ID, types.DeploymentTaskLaunch, err)
+			if err != nil {
 				err = fmt.Errorf("launch runtime task %s error: %v", task.ID, err)
 
 				task.Status = "Failed"
@@ -1234,12 +1687,6 @@ func (r *Server) updateWeights(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
-	var (
-		total     = len(tasks)
-		pending   = tasks[:new]
-		newWeight = utils.ComputeWeight(float64(new), float64(total), value)
-	)
-
 	// mark app db status
 	if err := r.memoAppStatus(appId, types.OpStatusWeightUpdating, ""); err != nil {
 		http.Error(w, fmt.Sprintf("update app opstatus to weight-updating got error: %v", err), http.StatusInternalServerError)
@@ -1254,56 +1701,86 @@ func (r *Server) updateWeights(w http.ResponseWriter, req *http.Request) {
 
 		defer func() {
 			if errmsg != "" {
-				log.Errorf("weight-updating app %s error: %v", appId, err)
+				log.Errorf("weight-updating app %s error: %v", appId, errmsg)
 			} else {
-				log.Errorf("weight-updating app %s succeed", appId)
+				log.Printf("weight-updating app %s succeed", appId)
 			}
 			r.memoAppStatus(appId, opStatus, errmsg)
 		}()
 
 		log.Printf("Preparing to weight-updating App %s", appId)
 
-		for _, task := range pending {
-			task.Weight = newWeight
+		if err := r.updateTaskWeights(appId, newVer.ID, value); err != nil {
+			errmsg = err.Error()
+		}
+	}()
 
-			log.Debugf("updating weight to %f for task %s", newWeight, task.ID)
-			if err := r.db.UpdateTask(appId, task); err != nil {
-				errmsg = fmt.Sprintf("update task %s weight got error: %v", task.ID, err)
-				log.Error(errmsg)
-				return
-			}
+	writeJSON(w, http.StatusAccepted, "accepted")
+}
 
-			// notify proxy
-			log.Debugf("Sending task event to proxy for weight changed. taskId: %s weight: %.f", task.ID, newWeight)
-			if err := r.driver.SendEvent(appId, task); err != nil {
-				log.Errorf("updateWeights(): sending task %s event failed: %v", task.ID, err)
-			}
+// updateTaskWeights shift `value` of traffic weights to the tasks of specified
+// version, the others share the rest. value 0 means all of traffics go to the others.
+func (r *Server) updateTaskWeights(appId, verId string, value float64) error {
+	tasks, err := r.db.ListTasks(appId)
+	if err != nil {
+		return fmt.Errorf("list tasks got error: %v", err)
+	}
+
+	var news, olds []*types.Task
+	for _, task := range tasks {
+		if task.Version == verId {
+			news = append(news, task)
+		} else {
+			olds = append(olds, task)
 		}
+	}
 
-		// set the old task's weight to 0 if the new tasks want 100% traffics.
-		if value == 1 {
-			oldTasks := tasks[new:]
-			for _, task := range oldTasks {
-				task.Weight = 0
+	var (
+		newWeight = utils.ComputeWeight(float64(len(news)), float64(len(tasks)), value)
+		oldWeight = float64(100)
+	)
 
-				log.Debugf("updating weight to 0 for task %s", task.ID)
-				if err := r.db.UpdateTask(appId, task); err != nil {
-					errmsg = fmt.Sprintf("update task %s weight got error: %v", task.ID, err)
-					log.Error(errmsg)
-					return
-				}
+	switch value {
+	case 0:
+		newWeight = 0
+	case 1: // set the old task's weight to 0 if the new tasks want 100% traffics.
+		oldWeight = 0
+	}
 
-				// notify proxy
-				log.Debugf("Sending task event to proxy for weight changed. taskId: %s weight: 0", task.ID)
-				if err := r.driver.SendEvent(appId, task); err != nil {
-					log.Errorf("updateWeights(): sending task %s event failed: %v", task.ID, err)
-				}
+	for _, task := range news {
+		if err := r.setTaskWeight(appId, task, newWeight); err != nil {
+			return err
+		}
+	}
 
-			}
+	for _, task := range olds {
+		if task.Weight == oldWeight {
+			continue
 		}
-	}()
 
-	writeJSON(w, http.StatusAccepted, "accepted")
+		if err := r.setTaskWeight(appId, task, oldWeight); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *Server) setTaskWeight(appId string, task *types.Task, weight float64) error {
+	task.Weight = weight
+
+	log.Debugf("updating weight to %f for task %s", weight, task.ID)
+	if err := r.db.UpdateTask(appId, task); err != nil {
+		return fmt.Errorf("update task %s weight got error: %v", task.ID, err)
+	}
+
+	// notify proxy
+	log.Debugf("Sending task event to proxy for weight changed. taskId: %s weight: %.f", task.ID, weight)
+	if err := r.driver.SendEvent(appId, task); err != nil {
+		log.Errorf("updateWeights(): sending task %s event failed: %v", task.ID, err)
+	}
+
+	return nil
 }
 
 func (r *Server) getTasks(w http.ResponseWriter, req *http.Request) {
@@ -1518,6 +1995,12 @@ func (r *Server) updateTask(w http.ResponseWriter, req *http.Request) {
 		taskId = vars["task_id"]
 	)
 
+	deadline, err := launchDeadline(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	var version types.Version
 	if err := decode(req.Body, &version); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
@@ -1577,6 +2060,7 @@ func (r *Server) updateTask(w http.ResponseWriter, req *http.Request) {
 	}
 
 	m := mesos.NewTask(cfg, task.ID, task.Name)
+	m.SetDeadline(deadline)
 	tasks := []*mesos.Task{m}
 
 	if err := r.driver.LaunchTasks(tasks); err != nil {
@@ -1607,6 +2091,12 @@ func (r *Server) rollbackTask(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	deadline, err := launchDeadline(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	app, err := r.db.GetApp(appId)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -1707,6 +2197,7 @@ func (r *Server) rollbackTask(w http.ResponseWriter, req *http.Request) {
 	}
 
 	m := mesos.NewTask(cfg, task.ID, task.Name)
+	m.SetDeadline(deadline)
 
 	tasks := []*mesos.Task{m}
 
@@ -1769,6 +2260,9 @@ func (r *Server) delApp(appId string, tasks []*types.Task, versions []*types.Ver
 		return fmt.Errorf("Delete app %s got error: %v", appId, err)
 	}
 
+	// release the reservations & volumes left by the app
+	r.driver.CleanupReservations(appId)
+
 	return nil
 }
 
@@ -1806,6 +2300,111 @@ func (s *Server) delTask(appId string, task *types.Task) error {
 	return nil
 }
 
+// waitTaskHealthy wait until the db task turns healthy. it fails if the task
+// terminated, or the health check didn't pass within the max time allowed by
+// the health check settings.
+func (r *Server) waitTaskHealthy(appId, taskId string, hc *types.HealthCheck) error {
+	var (
+		failures = float64(hc.ConsecutiveFailures + 1)
+		maxWait  = hc.DelaySeconds + hc.GracePeriodSeconds + failures*(hc.IntervalSeconds+hc.TimeoutSeconds)
+		deadline = time.Now().Add(time.Duration(maxWait) * time.Second)
+	)
+
+	for {
+		task, err := r.db.GetTask(appId, taskId)
+		if err != nil {
+			return err
+		}
+
+		if task.Healthy == types.TaskHealthy {
+			return nil
+		}
+
+		switch task.Status {
+		case "pending", "updating", "TASK_STAGING", "TASK_STARTING", "TASK_RUNNING":
+		default:
+			return fmt.Errorf("task status %s: %s", task.Status, task.ErrMsg)
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timeout after %.1f seconds", maxWait)
+		}
+
+		time.Sleep(time.Second)
+	}
+}
+
+// rollbackTasks roll back the given updated tasks in reverse order, each of them
+// is replaced by a task running on the version it was updated from.
+func (r *Server) rollbackTasks(d *types.Deployment, appId string, updated []string, previous map[string]string) error {
+	for i := len(updated) - 1; i >= 0; i-- {
+		taskId := updated[i]
+
+		ver, err := r.db.GetVersion(appId, previous[taskId])
+		if err != nil {
+			return fmt.Errorf("get previous version of task %s error: %v", taskId, err)
+		}
+
+		t, err := r.db.GetTask(appId, taskId)
+		if err != nil {
+			return fmt.Errorf("get updated task %s error: %v", taskId, err)
+		}
+
+		// remove updated task
+		err = r.delTask(appId, t)
+		d.AddTask(t.ID, t.Name, t.Version, types.DeploymentTaskKill, err)
+		if err != nil {
+			return fmt.Errorf("remove updated task %s error: %v", taskId, err)
+		}
+
+		// save db task
+		var (
+			name    = t.Name
+			id      = fmt.Sprintf("%s.%s", utils.RandomString(12), name)
+			restart = ver.RestartPolicy
+			retries = 3
+		)
+
+		if restart != nil && restart.Retries >= 0 {
+			retries = restart.Retries
+		}
+
+		task := &types.Task{
+			ID:         id,
+			Name:       name,
+			Weight:     100,
+			Status:     "updating",
+			Version:    ver.ID,
+			Healthy:    types.TaskHealthyUnset,
+			MaxRetries: retries,
+			Created:    t.Created,
+			Updated:    time.Now(),
+		}
+
+		if ver.IsHealthSet() {
+			task.Healthy = types.TaskUnHealthy
+		}
+
+		if err := r.db.CreateTask(appId, task); err != nil {
+			return fmt.Errorf("create db task error: %v", err)
+		}
+
+		// launch runtime task
+		seq := strings.SplitN(name, ".", 2)[0]
+		idx, _ := strconv.Atoi(seq)
+		cfg := types.NewTaskConfig(ver, idx)
+		m := mesos.NewTask(cfg, task.ID, task.Name)
+
+		err = r.driver.LaunchTasks([]*mesos.Task{m})
+		d.AddTask(task.ID, task.Name, ver.ID, types.DeploymentTaskLaunch, err)
+		if err != nil {
+			return fmt.Errorf("launch runtime task %s error: %v", task.ID, err)
+		}
+	}
+
+	return nil
+}
+
 // short hands to memo update App.OpStatus & App.ErrMsg
 // it's the caller responsibility to process the db error.
 func (r *Server) memoAppStatus(appId, op, errmsg string) error {
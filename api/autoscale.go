@@ -0,0 +1,175 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/Dataman-Cloud/swan/types"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+const (
+	autoScaleInterval = time.Second * 30
+	autoScaleTrigger  = "autoscaler"
+)
+
+// autoScaler evaluates the apps' autoscale policy periodically, it only runs
+// on the leader manager.
+type autoScaler struct {
+	sync.Mutex
+	stopCh chan struct{}
+}
+
+// StartAutoScale starts the autoscale controller, it's a noop if already started.
+func (r *Server) StartAutoScale() {
+	r.autoscaler.Lock()
+	defer r.autoscaler.Unlock()
+
+	if r.autoscaler.stopCh != nil {
+		return
+	}
+
+	stopCh := make(chan struct{})
+	r.autoscaler.stopCh = stopCh
+
+	go func() {
+		log.Println("autoscale controller started")
+
+		ticker := time.NewTicker(autoScaleInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				r.autoScaleApps()
+			case <-stopCh:
+				log.Println("autoscale controller stopped")
+				return
+			}
+		}
+	}()
+}
+
+// StopAutoScale stops the autoscale controller.
+func (r *Server) StopAutoScale() {
+	r.autoscaler.Lock()
+	defer r.autoscaler.Unlock()
+
+	if r.autoscaler.stopCh != nil {
+		close(r.autoscaler.stopCh)
+		r.autoscaler.stopCh = nil
+	}
+}
+
+func (r *Server) autoScaleApps() {
+	apps, err := r.db.ListApps()
+	if err != nil {
+		log.Errorf("autoscale list apps error: %v", err)
+		return
+	}
+
+	for _, app := range apps {
+		if err := r.autoScaleApp(app); err != nil {
+			log.Errorf("autoscale app %s error: %v", app.ID, err)
+		}
+	}
+}
+
+func (r *Server) autoScaleApp(app *types.Application) error {
+	// skip the app in any operation or with multiple versions (in canary or unfinished update)
+	if app.OpStatus != types.OpStatusNoop || len(app.Version) != 1 {
+		return nil
+	}
+
+	ver, err := r.db.GetVersion(app.ID, app.Version[0])
+	if err != nil {
+		return err
+	}
+
+	policy := ver.AutoScale
+	if policy == nil {
+		return nil
+	}
+
+	cooldown := policy.Cooldown
+	if cooldown == 0 {
+		cooldown = types.DefaultAutoScaleCooldown
+	}
+
+	// the app updated time is refreshed once each operation finished
+	if time.Since(app.UpdatedAt) < time.Duration(cooldown*float64(time.Second)) {
+		return nil
+	}
+
+	tasks, err := r.db.ListTasks(app.ID)
+	if err != nil {
+		return err
+	}
+
+	var (
+		counters = r.getAppProxyCounters(app.ID)
+		current  = len(tasks)
+		running  int
+		rps      float64
+	)
+
+	for _, task := range tasks {
+		if task.Status != "TASK_RUNNING" {
+			continue
+		}
+
+		running++
+
+		if c, ok := counters[task.ID]; ok {
+			rps += float64(c.ReqRate)
+		}
+	}
+
+	goal := current
+	if running > 0 {
+		goal = int(math.Ceil(rps / policy.TargetRPS))
+	}
+
+	if goal < policy.MinInstances {
+		goal = policy.MinInstances
+	}
+
+	if goal > policy.MaxInstances {
+		goal = policy.MaxInstances
+	}
+
+	if goal == current {
+		return nil
+	}
+
+	log.Printf("autoscale app %s from %d to %d instances, requests rate %.0f/s, target %.0f/s per task",
+		app.ID, current, goal, rps, policy.TargetRPS)
+
+	ev := &types.AppEvent{
+		Type:    types.EventTypeAppAutoScale,
+		AppID:   app.ID,
+		Message: fmt.Sprintf("scale from %d to %d instances", current, goal),
+		Data: map[string]interface{}{
+			"from":          current,
+			"to":            goal,
+			"running":       running,
+			"requests_rate": rps,
+			"target_rps":    policy.TargetRPS,
+		},
+		Time: time.Now(),
+	}
+
+	err = r.scale(app, tasks, goal, nil, autoScaleTrigger, time.Time{})
+	if err != nil {
+		ev.Message = fmt.Sprintf("scale from %d to %d instances failed: %v", current, goal, err)
+	}
+
+	if err := r.driver.SendAppEvent(ev); err != nil {
+		log.Errorf("send app %s autoscale event error: %v", app.ID, err)
+	}
+
+	return err
+}
@@ -0,0 +1,66 @@
+package api
+
+import "testing"
+
+func TestSoakErrRate(t *testing.T) {
+	backends := map[string]bool{"new1": true, "new2": true}
+
+	tests := []struct {
+		name    string
+		base    map[string]*proxyCounter
+		current map[string]*proxyCounter
+		want    float64
+		wantOk  bool
+	}{
+		{
+			name:    "no requests",
+			base:    map[string]*proxyCounter{"new1": {Requests: 10}},
+			current: map[string]*proxyCounter{"new1": {Requests: 10}},
+			wantOk:  false,
+		},
+		{
+			name:    "delta since the soak started",
+			base:    map[string]*proxyCounter{"new1": {Requests: 100, Fails: 50}},
+			current: map[string]*proxyCounter{"new1": {Requests: 200, Fails: 60}},
+			want:    0.1,
+			wantOk:  true,
+		},
+		{
+			name:    "5xx counted as failures",
+			base:    map[string]*proxyCounter{},
+			current: map[string]*proxyCounter{"new1": {Requests: 10, Fails: 1, Status5xx: 4}},
+			want:    0.5,
+			wantOk:  true,
+		},
+		{
+			name: "old version backends ignored",
+			base: map[string]*proxyCounter{},
+			current: map[string]*proxyCounter{
+				"new1": {Requests: 10},
+				"new2": {Requests: 10, Status5xx: 2},
+				"old1": {Requests: 10, Fails: 10},
+			},
+			want:   0.1,
+			wantOk: true,
+		},
+		{
+			name:    "counters reset",
+			base:    map[string]*proxyCounter{"new1": {Requests: 100, Fails: 1}},
+			current: map[string]*proxyCounter{"new1": {Requests: 4, Fails: 1}},
+			want:    0.25,
+			wantOk:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := soakErrRate(tt.base, tt.current, backends)
+			if ok != tt.wantOk {
+				t.Fatalf("soakErrRate() ok = %v, want %v", ok, tt.wantOk)
+			}
+			if diff := got - tt.want; diff > 1e-9 || diff < -1e-9 {
+				t.Errorf("soakErrRate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -25,11 +25,17 @@ func (r *Server) listAgents(w http.ResponseWriter, req *http.Request) {
 		debug, _ = strconv.ParseBool(req.Form.Get("debug"))
 	)
 
+	windows, err := r.driver.MaintenanceSchedule()
+	if err != nil {
+		log.Errorf("get mesos maintenance schedule error: %v", err)
+	}
+
 	for id := range r.driver.ClusterAgents() {
 		info, err := r.getAgentInfo(id)
 		if err != nil {
 			ret[id] = err.Error()
 		} else {
+			info.Maintenance = agentWindows(windows, info)
 			ret[id] = info
 			normals[id] = info
 		}
@@ -43,6 +49,23 @@ func (r *Server) listAgents(w http.ResponseWriter, req *http.Request) {
 	writeJSON(w, http.StatusOK, normals)
 }
 
+// agentWindows picks the maintenance windows of the agent by its hostname & ips.
+func agentWindows(windows []*types.MaintenanceWindow, info *types.SysInfo) []*types.MaintenanceWindow {
+	var ips []string
+	for _, addrs := range info.IPs {
+		ips = append(ips, addrs...)
+	}
+
+	var ret []*types.MaintenanceWindow
+	for _, w := range windows {
+		if w.Match(info.Hostname, ips...) {
+			ret = append(ret, w)
+		}
+	}
+
+	return ret
+}
+
 func (r *Server) queryAgentID(w http.ResponseWriter, req *http.Request) {
 	if err := req.ParseForm(); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -360,6 +383,49 @@ func (r *Server) getAppProxyTrafficInfo(agentId, appId string) (map[string]inter
 	return info, err
 }
 
+// proxyCounter is the per backend counter of the agent proxy, see agent/janitor/stats.BackendCounter
+type proxyCounter struct {
+	ID            string `json:"-"`
+	ActiveClients uint   `json:"active_clients"`
+	Requests      uint64 `json:"requests"`
+	Fails         uint64 `json:"fails"`
+	Status5xx     uint64 `json:"status_5xx"`
+	ReqRate       uint   `json:"requests_rate"`
+	FailRate      uint   `json:"fails_rate"`
+}
+
+// getAppProxyCounters collect the proxy counters of app backends from all of agents,
+// the counters of the same backend on different agents are summed up.
+func (r *Server) getAppProxyCounters(appId string) map[string]*proxyCounter {
+	ret := make(map[string]*proxyCounter)
+
+	for id := range r.driver.ClusterAgents() {
+		agentReq, _ := http.NewRequest("GET", fmt.Sprintf("http://%s/proxy/stats/%s", id, appId), nil)
+
+		var info map[string]*proxyCounter
+		if err := r.requestAgentResource(id, agentReq, 200, &info); err != nil {
+			log.Warnf("get app %s proxy counters from agent %s error: %v", appId, id, err)
+			continue
+		}
+
+		for bid, c := range info {
+			sum, ok := ret[bid]
+			if !ok {
+				sum = &proxyCounter{ID: bid}
+				ret[bid] = sum
+			}
+			sum.ActiveClients += c.ActiveClients
+			sum.Requests += c.Requests
+			sum.Fails += c.Fails
+			sum.Status5xx += c.Status5xx
+			sum.ReqRate += c.ReqRate
+			sum.FailRate += c.FailRate
+		}
+	}
+
+	return ret
+}
+
 func (r *Server) getAppDNSTrafficInfo(agentId, appId string) (map[string]interface{}, error) {
 	agentReq, _ := http.NewRequest("GET", fmt.Sprintf("http://%s/dns/stats/%s", agentId, appId), nil)
 	var info map[string]interface{}
@@ -0,0 +1,120 @@
+package api
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/Dataman-Cloud/swan/mesos"
+	"github.com/Dataman-Cloud/swan/types"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/gorilla/mux"
+)
+
+func (r *Server) listDeployments(w http.ResponseWriter, req *http.Request) {
+	appId := mux.Vars(req)["app_id"]
+
+	if _, err := r.db.GetApp(appId); err != nil {
+		if r.db.IsErrNotFound(err) {
+			http.Error(w, fmt.Sprintf("app %s not exists", appId), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	deployments, err := r.db.ListDeployments(appId)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("list deployments got error: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, deployments)
+}
+
+// beginDeployment create & save a deployment record for the app operation.
+// the db error is only logged as the record shouldn't block the operation.
+func (r *Server) beginDeployment(appId, typ, triggeredBy string, from []string, to string) *types.Deployment {
+	d := types.NewDeployment(appId, typ, triggeredBy, from, to)
+
+	if err := r.db.CreateDeployment(appId, d); err != nil {
+		log.Errorf("create app %s %s deployment record error: %v", appId, typ, err)
+	}
+
+	return d
+}
+
+// endDeployment finish & save the deployment record with the operation error.
+func (r *Server) endDeployment(d *types.Deployment, err error) {
+	d.Finish(err)
+
+	if err := r.db.UpdateDeployment(d.AppID, d); err != nil {
+		log.Errorf("update app %s deployment %s record error: %v", d.AppID, d.ID, err)
+	}
+}
+
+// memoLaunched memo the launch outcome of each runtime task by its db task status,
+// the failed tasks are marked by scheduler, or have been rescheduled.
+func (r *Server) memoLaunched(d *types.Deployment, verId string, tasks []*mesos.Task) {
+	for _, t := range tasks {
+		var (
+			id   = t.ID()
+			name = t.GetName()
+		)
+
+		task, err := r.db.GetTask(d.AppID, id)
+		if err != nil {
+			d.AddTask(id, name, verId, types.DeploymentTaskLaunch, fmt.Errorf("task not found, may be rescheduled: %v", err))
+			continue
+		}
+
+		if task.Status == "failed" {
+			err = fmt.Errorf("%s", task.ErrMsg)
+		}
+
+		d.AddTask(id, name, verId, types.DeploymentTaskLaunch, err)
+	}
+}
+
+// triggeredBy figure out who triggered the request, the basic auth user if
+// provided, otherwise the client address. the X-Forwarded-For is only taken
+// from the trusted proxies, eg: the other managers forwarding to the leader.
+func (r *Server) triggeredBy(req *http.Request) string {
+	if user, _, ok := req.BasicAuth(); ok && user != "" {
+		return user
+	}
+
+	addr := req.RemoteAddr
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+
+	// walk back the forwarded addresses until the first untrusted one
+	fwds := strings.Split(strings.Join(req.Header["X-Forwarded-For"], ","), ",")
+	for i := len(fwds) - 1; i >= 0 && r.trustedProxy(addr); i-- {
+		fwd := strings.TrimSpace(fwds[i])
+		if fwd == "" {
+			break
+		}
+		addr = fwd
+	}
+
+	return addr
+}
+
+// trustedProxy tells whether the address is one of the trusted proxies.
+func (r *Server) trustedProxy(addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+
+	for _, n := range r.trusted {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
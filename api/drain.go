@@ -0,0 +1,374 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Dataman-Cloud/swan/mesos"
+	"github.com/Dataman-Cloud/swan/types"
+	"github.com/Dataman-Cloud/swan/utils"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/gorilla/mux"
+)
+
+const (
+	drainTrigger  = "drain"
+	drainInterval = time.Second * 10
+)
+
+// drainer runs the agents draining, it only runs on the leader manager.
+type drainer struct {
+	sync.Mutex
+	stopCh  chan struct{}
+	running map[string]bool // agent ids being drained
+}
+
+func (r *Server) drainAgent(w http.ResponseWriter, req *http.Request) {
+	agentId := mux.Vars(req)["agent_id"]
+
+	if _, err := r.db.GetDrain(agentId); err == nil {
+		http.Error(w, fmt.Sprintf("agent %s is already drained", agentId), http.StatusConflict)
+		return
+	}
+
+	d := &types.Drain{
+		AgentID: agentId,
+		Status:  types.DrainStatusDraining,
+		Created: time.Now(),
+		Updated: time.Now(),
+	}
+
+	if err := r.db.CreateDrain(d); err != nil {
+		http.Error(w, fmt.Sprintf("create agent %s drain error: %v", agentId, err), http.StatusInternalServerError)
+		return
+	}
+
+	go r.drain(agentId)
+
+	writeJSON(w, http.StatusAccepted, "accepted")
+}
+
+func (r *Server) getDrain(w http.ResponseWriter, req *http.Request) {
+	agentId := mux.Vars(req)["agent_id"]
+
+	d, err := r.db.GetDrain(agentId)
+	if err != nil {
+		if r.db.IsErrNotFound(err) {
+			http.Error(w, fmt.Sprintf("agent %s is not drained", agentId), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, d)
+}
+
+// undrainAgent makes the agent schedulable again, the draining in progress
+// stops before moving the next task.
+func (r *Server) undrainAgent(w http.ResponseWriter, req *http.Request) {
+	agentId := mux.Vars(req)["agent_id"]
+
+	if _, err := r.db.GetDrain(agentId); err != nil {
+		if r.db.IsErrNotFound(err) {
+			http.Error(w, fmt.Sprintf("agent %s is not drained", agentId), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := r.db.DeleteDrain(agentId); err != nil {
+		http.Error(w, fmt.Sprintf("delete agent %s drain error: %v", agentId, err), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusNoContent, "")
+}
+
+// StartDrains starts running the agents draining, it's a noop if already started.
+// the draining interrupted by the manager failover is resumed, and the agents
+// requested back by the mesos inverse offers are picked up periodically.
+func (r *Server) StartDrains() {
+	r.drainer.Lock()
+	defer r.drainer.Unlock()
+
+	if r.drainer.stopCh != nil {
+		return
+	}
+
+	stopCh := make(chan struct{})
+	r.drainer.stopCh = stopCh
+
+	go func() {
+		log.Println("agents drainer started")
+
+		ticker := time.NewTicker(drainInterval)
+		defer ticker.Stop()
+
+		for {
+			r.runDrains()
+
+			select {
+			case <-ticker.C:
+			case <-stopCh:
+				log.Println("agents drainer stopped")
+				return
+			}
+		}
+	}()
+}
+
+// StopDrains stops picking up the agents draining.
+func (r *Server) StopDrains() {
+	r.drainer.Lock()
+	defer r.drainer.Unlock()
+
+	if r.drainer.stopCh != nil {
+		close(r.drainer.stopCh)
+		r.drainer.stopCh = nil
+	}
+}
+
+func (r *Server) runDrains() {
+	drains, err := r.db.ListDrains()
+	if err != nil {
+		log.Errorf("list drains error: %v", err)
+		return
+	}
+
+	for _, d := range drains {
+		if d.Status == types.DrainStatusDraining {
+			go r.drain(d.AgentID)
+		}
+	}
+}
+
+// drain moves the tasks on the agent to the other agents app by app.
+func (r *Server) drain(agentId string) {
+	r.drainer.Lock()
+	if r.drainer.running[agentId] {
+		r.drainer.Unlock()
+		return
+	}
+	r.drainer.running[agentId] = true
+	r.drainer.Unlock()
+
+	defer func() {
+		r.drainer.Lock()
+		delete(r.drainer.running, agentId)
+		r.drainer.Unlock()
+	}()
+
+	d, err := r.db.GetDrain(agentId)
+	if err != nil {
+		log.Errorf("get agent %s drain error: %v", agentId, err)
+		return
+	}
+
+	apps, err := r.db.ListApps()
+	if err != nil {
+		d.Status = types.DrainStatusFailed
+		d.ErrMsg = fmt.Sprintf("list apps error: %v", err)
+		r.updateDrain(d)
+		return
+	}
+
+	log.Printf("Draining agent %s", agentId)
+
+	errs := make([]string, 0)
+	for _, app := range apps {
+		if r.drainCanceled(agentId) {
+			log.Printf("drain agent %s canceled", agentId)
+			return
+		}
+
+		moved, pinned, err := r.drainApp(app.ID, agentId)
+		d.Moved += moved
+		d.Pinned = append(d.Pinned, pinned...)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("app %s: %v", app.ID, err))
+		}
+
+		r.updateDrain(d)
+	}
+
+	d.Status = types.DrainStatusDrained
+	if len(errs) > 0 {
+		d.Status = types.DrainStatusFailed
+		d.ErrMsg = strings.Join(errs, "; ")
+	}
+
+	log.Printf("Drain agent %s %s, %d tasks moved", agentId, d.Status, d.Moved)
+
+	r.updateDrain(d)
+}
+
+// drainApp moves the app's tasks on the agent one by one, the next task is
+// moved only after the previous one became healthy, so at most one instance
+// of the app is down. the tasks bound to persistent volumes are left.
+func (r *Server) drainApp(appId, agentId string) (moved int, pinned []string, err error) {
+	tasks, err := r.db.ListTasks(appId)
+	if err != nil {
+		return 0, nil, fmt.Errorf("list tasks error: %v", err)
+	}
+
+	on := make([]*types.Task, 0)
+	for _, t := range tasks {
+		if t.AgentId == agentId {
+			on = append(on, t)
+		}
+	}
+
+	if len(on) == 0 {
+		return 0, nil, nil
+	}
+
+	app, err := r.waitAppNoop(appId, agentId)
+	if err != nil || app == nil {
+		return 0, nil, err
+	}
+
+	if err := r.memoAppStatus(appId, types.OpStatusDraining, ""); err != nil {
+		return 0, nil, fmt.Errorf("update app opstatus to draining got error: %v", err)
+	}
+
+	d := r.beginDeployment(appId, types.DeploymentDrain, drainTrigger, app.Version, app.Version[0])
+
+	defer func() {
+		r.endDeployment(d, err)
+
+		if err != nil {
+			r.memoAppStatus(appId, types.OpStatusNoop, fmt.Sprintf("drain agent %s error: %v", agentId, err))
+		} else {
+			r.memoAppStatus(appId, types.OpStatusNoop, "")
+		}
+	}()
+
+	types.TaskList(on).Sort()
+
+	for _, t := range on {
+		if r.drainCanceled(agentId) {
+			return
+		}
+
+		ver, verr := r.db.GetVersion(appId, t.Version)
+		if verr != nil {
+			err = fmt.Errorf("get version %s error: %v", t.Version, verr)
+			return
+		}
+
+		if len(ver.PersistentVolumes()) > 0 {
+			pinned = append(pinned, t.ID)
+			continue
+		}
+
+		if err = r.moveTask(d, appId, ver, t); err != nil {
+			return
+		}
+
+		moved++
+	}
+
+	return
+}
+
+// moveTask replaces the task with a new one, which is placed on the other
+// agents as the drained agent is unschedulable.
+func (r *Server) moveTask(d *types.Deployment, appId string, ver *types.Version, t *types.Task) error {
+	err := r.delTask(appId, t)
+	d.AddTask(t.ID, t.Name, t.Version, types.DeploymentTaskKill, err)
+	if err != nil {
+		return fmt.Errorf("remove task %s error: %v", t.ID, err)
+	}
+
+	var (
+		name    = t.Name
+		id      = fmt.Sprintf("%s.%s", utils.RandomString(12), name)
+		restart = ver.RestartPolicy
+		retries = 3
+		idx, _  = strconv.Atoi(strings.SplitN(name, ".", 2)[0])
+	)
+
+	if restart != nil && restart.Retries >= 0 {
+		retries = restart.Retries
+	}
+
+	task := &types.Task{
+		ID:         id,
+		Name:       name,
+		Weight:     100,
+		Status:     "pending",
+		Healthy:    types.TaskHealthyUnset,
+		Version:    ver.ID,
+		MaxRetries: retries,
+		Created:    t.Created,
+		Updated:    time.Now(),
+	}
+	if ver.IsHealthSet() {
+		task.Healthy = types.TaskUnHealthy
+	}
+
+	if err := r.db.CreateTask(appId, task); err != nil {
+		return fmt.Errorf("create new db task error: %v", err)
+	}
+
+	cfg := types.NewTaskConfig(ver, idx)
+	m := mesos.NewTask(cfg, task.ID, task.Name)
+
+	err = r.driver.LaunchTasks([]*mesos.Task{m})
+	d.AddTask(task.ID, task.Name, ver.ID, types.DeploymentTaskLaunch, err)
+	if err != nil {
+		return fmt.Errorf("launch task %s error: %v", task.ID, err)
+	}
+
+	if ver.IsHealthSet() {
+		if err := r.waitTaskHealthy(appId, task.ID, ver.HealthCheck); err != nil {
+			return fmt.Errorf("task %s never become healthy: %v", task.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// waitAppNoop waits for the in-flight operation of the app, returns nil app if
+// the app has gone or the drain canceled.
+func (r *Server) waitAppNoop(appId, agentId string) (*types.Application, error) {
+	for {
+		app, err := r.db.GetApp(appId)
+		if err != nil {
+			if r.db.IsErrNotFound(err) {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("get app error: %v", err)
+		}
+
+		if app.OpStatus == types.OpStatusNoop {
+			return app, nil
+		}
+
+		if r.drainCanceled(agentId) {
+			return nil, nil
+		}
+
+		log.Debugf("drain agent %s waiting for app %s %s", agentId, appId, app.OpStatus)
+		time.Sleep(time.Second * 5)
+	}
+}
+
+func (r *Server) drainCanceled(agentId string) bool {
+	_, err := r.db.GetDrain(agentId)
+	return err != nil && r.db.IsErrNotFound(err)
+}
+
+func (r *Server) updateDrain(d *types.Drain) {
+	d.Updated = time.Now()
+	if err := r.db.UpdateDrain(d); err != nil && !r.db.IsErrNotFound(err) {
+		log.Errorf("update agent %s drain error: %v", d.AgentID, err)
+	}
+}
@@ -18,6 +18,7 @@ type Driver interface {
 	SubscribeEvent(io.Writer, string) error
 	FullTaskEventsAndRecords() []*types.CombinedEvents
 	SendEvent(string, *types.Task) error
+	SendAppEvent(*types.AppEvent) error
 
 	ClusterAgents() map[string]*mole.ClusterAgent
 	ClusterAgent(id string) *mole.ClusterAgent
@@ -28,6 +29,16 @@ type Driver interface {
 	// for debug convenience
 	Dump() interface{}
 	Offers() interface{}
+	OfferStats() *types.OfferStats
+	OfferScores(appId string) (interface{}, error)
 	Load() map[string]interface{}
 	FrameworkInfo() *types.FrameworkInfo
+	Quota() (interface{}, error)
+
+	MaintenanceSchedule() ([]*types.MaintenanceWindow, error)
+
+	Reconciliation() (*types.ReconcileReport, error)
+	RepairTask(*types.ReconcileRepair) error
+
+	CleanupReservations(appId string)
 }
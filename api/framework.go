@@ -1,6 +1,7 @@
 package api
 
 import (
+	"fmt"
 	"net/http"
 )
 
@@ -8,3 +9,13 @@ func (r *Server) getFrameworkInfo(w http.ResponseWriter, req *http.Request) {
 	info := r.driver.FrameworkInfo()
 	writeJSON(w, http.StatusOK, info)
 }
+
+func (r *Server) getFrameworkQuota(w http.ResponseWriter, req *http.Request) {
+	quota, err := r.driver.Quota()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("get framework quota error: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, quota)
+}
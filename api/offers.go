@@ -1,7 +1,22 @@
 package api
 
-import "net/http"
+import (
+	"fmt"
+	"net/http"
+)
 
 func (s *Server) offers(w http.ResponseWriter, r *http.Request) {
+	// explain the agents ranking for the app
+	if appId := r.URL.Query().Get("app_id"); appId != "" {
+		scores, err := s.driver.OfferScores(appId)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("get app %s offer scores error: %v", appId, err), http.StatusInternalServerError)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, scores)
+		return
+	}
+
 	writeJSON(w, http.StatusOK, s.driver.Offers())
 }
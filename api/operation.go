@@ -0,0 +1,170 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/Dataman-Cloud/swan/types"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/gorilla/mux"
+)
+
+var errOperationCanceled = errors.New("operation canceled")
+
+// operation controls an in-flight app operation, the background loop of the
+// operation calls checkpoint() between task steps, which blocks while paused
+// and returns errOperationCanceled once canceled.
+type operation struct {
+	sync.Mutex
+
+	appId    string
+	op       string        // app op status while running
+	resume   chan struct{} // closed on resume or cancel, nil if not paused
+	canceled bool
+	done     bool
+}
+
+func (o *operation) checkpoint() error {
+	for {
+		o.Lock()
+		if o.canceled {
+			o.Unlock()
+			return errOperationCanceled
+		}
+
+		ch := o.resume
+		o.Unlock()
+
+		if ch == nil {
+			return nil
+		}
+
+		log.Printf("app %s operation %s paused, waiting for resume", o.appId, o.op)
+		<-ch
+	}
+}
+
+// startOperation register a controllable operation for the app.
+func (r *Server) startOperation(appId, op string) *operation {
+	o := &operation{
+		appId: appId,
+		op:    op,
+	}
+
+	r.Lock()
+	r.ops[appId] = o
+	r.Unlock()
+
+	return o
+}
+
+// finishOperation unregister the app operation, it must be called before the
+// operation finally memo the app op status, so the pause won't override it.
+func (r *Server) finishOperation(o *operation) {
+	o.Lock()
+	o.done = true
+	o.Unlock()
+
+	r.Lock()
+	if r.ops[o.appId] == o {
+		delete(r.ops, o.appId)
+	}
+	r.Unlock()
+}
+
+func (r *Server) getOperation(appId string) *operation {
+	r.Lock()
+	defer r.Unlock()
+
+	return r.ops[appId]
+}
+
+func (r *Server) pauseOperation(w http.ResponseWriter, req *http.Request) {
+	appId := mux.Vars(req)["app_id"]
+
+	o := r.getOperation(appId)
+	if o == nil {
+		http.Error(w, fmt.Sprintf("app %s has no operation in flight", appId), http.StatusConflict)
+		return
+	}
+
+	o.Lock()
+	defer o.Unlock()
+
+	if o.done || o.canceled {
+		http.Error(w, fmt.Sprintf("app %s operation %s is finishing", appId, o.op), http.StatusConflict)
+		return
+	}
+
+	if o.resume != nil {
+		http.Error(w, fmt.Sprintf("app %s operation %s already paused", appId, o.op), http.StatusConflict)
+		return
+	}
+
+	if err := r.memoAppStatus(appId, types.OpStatusPaused, ""); err != nil {
+		http.Error(w, fmt.Sprintf("update app opstatus to paused got error: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	o.resume = make(chan struct{})
+
+	writeJSON(w, http.StatusAccepted, "accepted")
+}
+
+func (r *Server) resumeOperation(w http.ResponseWriter, req *http.Request) {
+	appId := mux.Vars(req)["app_id"]
+
+	o := r.getOperation(appId)
+	if o == nil {
+		http.Error(w, fmt.Sprintf("app %s has no operation in flight", appId), http.StatusConflict)
+		return
+	}
+
+	o.Lock()
+	defer o.Unlock()
+
+	if o.resume == nil {
+		http.Error(w, fmt.Sprintf("app %s operation %s is not paused", appId, o.op), http.StatusConflict)
+		return
+	}
+
+	if err := r.memoAppStatus(appId, o.op, ""); err != nil {
+		http.Error(w, fmt.Sprintf("update app opstatus to %s got error: %v", o.op, err), http.StatusInternalServerError)
+		return
+	}
+
+	close(o.resume)
+	o.resume = nil
+
+	writeJSON(w, http.StatusAccepted, "accepted")
+}
+
+func (r *Server) cancelOperation(w http.ResponseWriter, req *http.Request) {
+	appId := mux.Vars(req)["app_id"]
+
+	o := r.getOperation(appId)
+	if o == nil {
+		http.Error(w, fmt.Sprintf("app %s has no operation in flight", appId), http.StatusConflict)
+		return
+	}
+
+	o.cancel()
+
+	writeJSON(w, http.StatusAccepted, "accepted")
+}
+
+// cancel the operation, the paused operation is woken up to quit.
+func (o *operation) cancel() {
+	o.Lock()
+	defer o.Unlock()
+
+	o.canceled = true
+
+	if o.resume != nil {
+		close(o.resume)
+		o.resume = nil
+	}
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Dataman-Cloud/swan/types"
+)
+
+func (r *Server) getReconcileReport(w http.ResponseWriter, req *http.Request) {
+	report, err := r.driver.Reconciliation()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("reconcile tasks error: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, report)
+}
+
+func (r *Server) repairReconcileTask(w http.ResponseWriter, req *http.Request) {
+	if err := checkForJSON(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var repair types.ReconcileRepair
+	if err := decode(req.Body, &repair); err != nil {
+		http.Error(w, fmt.Sprintf("decode repair param error: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if err := repair.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := r.driver.RepairTask(&repair); err != nil {
+		http.Error(w, fmt.Sprintf("repair task %s by %s error: %v", repair.TaskID, repair.Action, err), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, "accepted")
+}
@@ -28,6 +28,11 @@ func (r *Server) resetStatus(w http.ResponseWriter, req *http.Request) {
 		desired = types.OpStatusNoop
 	)
 
+	// quit the in-flight operation if any, otherwise it keeps running in background.
+	if o := r.getOperation(id); o != nil {
+		o.cancel()
+	}
+
 	app.OpStatus = desired
 	if err := r.db.UpdateApp(app); err != nil {
 		log.Errorf("reset app's op-status to noop got error: %v", err)
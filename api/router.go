@@ -19,6 +19,9 @@ func (s *Server) setupRoutes(mux *mux.Router) {
 		NewRoute("POST", "/v1/apps/{app_id}/rollback", s.rollback),
 		NewRoute("PUT", "/v1/apps/{app_id}/weights", s.updateWeights),
 		NewRoute("POST", "/v1/apps/{app_id}/reset", s.resetStatus),
+		NewRoute("POST", "/v1/apps/{app_id}/operation/pause", s.pauseOperation),
+		NewRoute("POST", "/v1/apps/{app_id}/operation/resume", s.resumeOperation),
+		NewRoute("POST", "/v1/apps/{app_id}/operation/cancel", s.cancelOperation),
 
 		NewRoute("GET", "/v1/apps/{app_id}/tasks", s.getTasks),
 		NewRoute("GET", "/v1/apps/{app_id}/tasks/{task_id}", s.getTask),
@@ -32,6 +35,13 @@ func (s *Server) setupRoutes(mux *mux.Router) {
 		NewRoute("GET", "/v1/apps/{app_id}/versions/{version_id}", s.getVersion),
 		NewRoute("POST", "/v1/apps/{app_id}/versions", s.createVersion),
 
+		NewRoute("GET", "/v1/apps/{app_id}/deployments", s.listDeployments),
+
+		NewRoute("POST", "/v1/apps/{app_id}/schedules", s.createSchedule),
+		NewRoute("GET", "/v1/apps/{app_id}/schedules", s.listSchedules),
+		NewRoute("GET", "/v1/apps/{app_id}/schedules/{schedule_id}", s.getSchedule),
+		NewRoute("DELETE", "/v1/apps/{app_id}/schedules/{schedule_id}", s.deleteSchedule),
+
 		// Deprecated, Remove Later
 		NewRoute("POST", "/v1/compose", s.runCompose),
 		NewRoute("POST", "/v1/compose/parse", s.parseYAML),
@@ -56,6 +66,9 @@ func (s *Server) setupRoutes(mux *mux.Router) {
 		NewRoute("POST", "/v1/purge", s.purge),
 
 		NewRoute("GET", "/v1/framework", s.getFrameworkInfo),
+		NewRoute("GET", "/v1/framework/quota", s.getFrameworkQuota),
+		NewRoute("GET", "/v1/reconcile", s.getReconcileReport),
+		NewRoute("POST", "/v1/reconcile/repair", s.repairReconcileTask),
 		NewRoute("GET", "/v1/debug/dump", s.dump),
 		NewRoute("GET", "/v1/debug/load", s.load),
 		NewRoute("GET", "/v1/debug/offers", s.offers),
@@ -69,6 +82,9 @@ func (s *Server) setupRoutes(mux *mux.Router) {
 		NewRoute("GET", "/v1/agents/networks", s.listAgentNetworks),
 		NewRoute("GET", "/v1/agents/{agent_id}", s.getAgent),
 		NewRoute("DELETE", "/v1/agents/{agent_id}", s.closeAgent),
+		NewRoute("GET", "/v1/agents/{agent_id}/drain", s.getDrain),
+		NewRoute("POST", "/v1/agents/{agent_id}/drain", s.drainAgent),
+		NewRoute("DELETE", "/v1/agents/{agent_id}/drain", s.undrainAgent),
 		NewRoute("GET", "/v1/agents/{agent_id}/sysinfo", s.getAgent),
 		NewRoute("GET", "/v1/agents/{agent_id}/configs", s.getAgentConfigs),
 		NewPrefixRoute("ANY", "/v1/agents/{agent_id}/proxy", s.redirectAgentProxy),
@@ -0,0 +1,280 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Dataman-Cloud/swan/types"
+
+	log "github.com/Sirupsen/logrus"
+	"github.com/gorilla/mux"
+)
+
+const (
+	scheduleInterval = time.Second * 10
+	// the due action is retried while the app is in other operation,
+	// it's given up after this timeout.
+	scheduleBusyTimeout = time.Minute * 5
+)
+
+var errAppBusy = errors.New("app is in other operation")
+
+// scheduler runs the apps' cron schedules, it only runs on the leader manager.
+type scheduler struct {
+	sync.Mutex
+	stopCh chan struct{}
+}
+
+func (r *Server) createSchedule(w http.ResponseWriter, req *http.Request) {
+	appId := mux.Vars(req)["app_id"]
+
+	if _, err := r.db.GetApp(appId); err != nil {
+		if r.db.IsErrNotFound(err) {
+			http.Error(w, fmt.Sprintf("app %s not exists", appId), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var sched types.Schedule
+	if err := decode(req.Body, &sched); err != nil {
+		http.Error(w, fmt.Sprintf("decode schedule param error: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if err := sched.Valid(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+
+	sched.ID = fmt.Sprintf("%d", now.UTC().UnixNano())
+	sched.CreatedAt = now
+	sched.LastRunAt = time.Time{}
+	sched.NextRunAt = sched.Next(now)
+	sched.ErrMsg = ""
+
+	if err := r.db.CreateSchedule(appId, &sched); err != nil {
+		http.Error(w, fmt.Sprintf("create schedule got error: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]string{"id": sched.ID})
+}
+
+func (r *Server) listSchedules(w http.ResponseWriter, req *http.Request) {
+	appId := mux.Vars(req)["app_id"]
+
+	schedules, err := r.db.ListSchedules(appId)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("list schedules got error: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, schedules)
+}
+
+func (r *Server) getSchedule(w http.ResponseWriter, req *http.Request) {
+	var (
+		vars  = mux.Vars(req)
+		appId = vars["app_id"]
+		id    = vars["schedule_id"]
+	)
+
+	sched, err := r.db.GetSchedule(appId, id)
+	if err != nil {
+		if r.db.IsErrNotFound(err) {
+			http.Error(w, fmt.Sprintf("schedule %s not exists", id), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, sched)
+}
+
+func (r *Server) deleteSchedule(w http.ResponseWriter, req *http.Request) {
+	var (
+		vars  = mux.Vars(req)
+		appId = vars["app_id"]
+		id    = vars["schedule_id"]
+	)
+
+	if err := r.db.DeleteSchedule(appId, id); err != nil {
+		if r.db.IsErrNotFound(err) {
+			http.Error(w, fmt.Sprintf("schedule %s not exists", id), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusNoContent, "")
+}
+
+// StartSchedules starts running the apps' schedules, it's a noop if already started.
+func (r *Server) StartSchedules() {
+	r.scheduler.Lock()
+	defer r.scheduler.Unlock()
+
+	if r.scheduler.stopCh != nil {
+		return
+	}
+
+	stopCh := make(chan struct{})
+	r.scheduler.stopCh = stopCh
+
+	go func() {
+		log.Println("app schedules runner started")
+
+		ticker := time.NewTicker(scheduleInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				r.runSchedules()
+			case <-stopCh:
+				log.Println("app schedules runner stopped")
+				return
+			}
+		}
+	}()
+}
+
+// StopSchedules stops running the apps' schedules.
+func (r *Server) StopSchedules() {
+	r.scheduler.Lock()
+	defer r.scheduler.Unlock()
+
+	if r.scheduler.stopCh != nil {
+		close(r.scheduler.stopCh)
+		r.scheduler.stopCh = nil
+	}
+}
+
+func (r *Server) runSchedules() {
+	apps, err := r.db.ListApps()
+	if err != nil {
+		log.Errorf("schedules list apps error: %v", err)
+		return
+	}
+
+	for _, app := range apps {
+		schedules, err := r.db.ListSchedules(app.ID)
+		if err != nil {
+			log.Errorf("list app %s schedules error: %v", app.ID, err)
+			continue
+		}
+
+		for _, sched := range schedules {
+			if err := r.runSchedule(app.ID, sched); err != nil {
+				log.Errorf("run app %s schedule %s error: %v", app.ID, sched.ID, err)
+			}
+		}
+	}
+}
+
+// runSchedule runs the schedule if it's due. The due time is persisted as
+// LastRunAt after the action is taken, so the new leader continues from there
+// after failover. Only the latest due time is caught up if several are missed.
+//
+// As the actions are declarative (scale to N, start or stop), an action taken
+// by the previous leader right before it died is a noop on the new leader.
+func (r *Server) runSchedule(appId string, sched *types.Schedule) error {
+	var (
+		now  = time.Now()
+		base = sched.LastRunAt
+	)
+
+	if base.IsZero() {
+		base = sched.CreatedAt
+	}
+
+	due := sched.Next(base)
+	if due.IsZero() || due.After(now) {
+		return nil
+	}
+
+	for {
+		next := sched.Next(due)
+		if next.IsZero() || next.After(now) {
+			break
+		}
+		due = next
+	}
+
+	err := r.scheduleAction(appId, sched)
+	if err == errAppBusy && now.Sub(due) < scheduleBusyTimeout {
+		return nil // retry on next round
+	}
+
+	sched.LastRunAt = due
+	sched.NextRunAt = sched.Next(now)
+	sched.ErrMsg = ""
+	if err != nil {
+		sched.ErrMsg = err.Error()
+	}
+
+	log.Printf("app %s schedule %s %s due at %s done, error: %v", appId, sched.ID, sched.Action, due, err)
+
+	return r.db.UpdateSchedule(appId, sched)
+}
+
+func (r *Server) scheduleAction(appId string, sched *types.Schedule) error {
+	app, err := r.db.GetApp(appId)
+	if err != nil {
+		return err
+	}
+
+	if app.OpStatus != types.OpStatusNoop {
+		return errAppBusy
+	}
+
+	tasks, err := r.db.ListTasks(appId)
+	if err != nil {
+		return err
+	}
+
+	triggered := "schedule:" + sched.ID
+
+	switch sched.Action {
+	case types.ScheduleScale:
+		if len(tasks) == sched.Instances {
+			return nil
+		}
+
+		ver, err := r.db.GetVersion(appId, app.Version[0])
+		if err != nil {
+			return err
+		}
+
+		if net := ver.Container.Network(); sched.Instances > len(tasks) && net != "host" && net != "bridge" {
+			return fmt.Errorf("scale up app with %s network requires ips", net)
+		}
+
+		return r.scale(app, tasks, sched.Instances, nil, triggered, time.Time{})
+
+	case types.ScheduleStart:
+		if len(tasks) > 0 {
+			return nil
+		}
+
+		return r.start(app, triggered, time.Time{})
+
+	case types.ScheduleStop:
+		if len(tasks) == 0 {
+			return nil
+		}
+
+		return r.stop(app, tasks, triggered)
+	}
+
+	return fmt.Errorf("unsupported schedule action: %s", sched.Action)
+}
@@ -16,8 +16,9 @@ import (
 )
 
 type Config struct {
-	Advertise string
-	LogLevel  string
+	Advertise      string
+	LogLevel       string
+	TrustedProxies []string // ips or cidrs whose X-Forwarded-For is trusted
 }
 
 type Server struct {
@@ -27,6 +28,12 @@ type Server struct {
 	server   *http.Server
 	driver   Driver
 	db       store.Store
+	ops      map[string]*operation // app id -> in-flight operation
+	trusted  []*net.IPNet          // trusted proxies
+
+	autoscaler autoScaler
+	scheduler  scheduler
+	drainer    drainer
 
 	sync.Mutex
 }
@@ -38,6 +45,9 @@ func NewServer(cfg *Config, l net.Listener, driver Driver, db store.Store) *Serv
 		leader:   "",
 		driver:   driver,
 		db:       db,
+		ops:      make(map[string]*operation),
+		drainer:  drainer{running: make(map[string]bool)},
+		trusted:  parseTrustedProxies(cfg.TrustedProxies),
 	}
 
 	s.server = &http.Server{
@@ -145,6 +155,30 @@ func (s *Server) UpdateLeader(leader string) {
 	s.leader = leader
 }
 
+// parseTrustedProxies parses the trusted proxy ips or cidrs, the invalid
+// ones are ignored as the config has been validated.
+func parseTrustedProxies(proxies []string) []*net.IPNet {
+	nets := make([]*net.IPNet, 0, len(proxies))
+	for _, p := range proxies {
+		if _, n, err := net.ParseCIDR(p); err == nil {
+			nets = append(nets, n)
+			continue
+		}
+
+		ip := net.ParseIP(p)
+		if ip == nil {
+			continue
+		}
+
+		bits := 8 * net.IPv6len
+		if ip.To4() != nil {
+			ip, bits = ip.To4(), 8*net.IPv4len
+		}
+		nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+	}
+	return nets
+}
+
 func (s *Server) GetLeader() string {
 	s.Lock()
 	defer s.Unlock()
@@ -161,6 +195,14 @@ func (s *Server) forwardRequest(w http.ResponseWriter, r *http.Request) {
 	}
 	defer dst.Close()
 
+	// keep the original client address for the leader
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		if prior, ok := r.Header["X-Forwarded-For"]; ok {
+			host = strings.Join(prior, ", ") + ", " + host
+		}
+		r.Header.Set("X-Forwarded-For", host)
+	}
+
 	err = r.WriteProxy(dst) // send original request
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
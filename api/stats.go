@@ -5,5 +5,7 @@ import (
 )
 
 func (r *Server) stats(w http.ResponseWriter, req *http.Request) {
-
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"offers": r.driver.OfferStats(),
+	})
 }
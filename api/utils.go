@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"time"
 )
 
 // WriteJSON write response as json format.
@@ -34,3 +35,20 @@ func decode(b io.ReadCloser, v interface{}) error {
 
 	return dec.Decode(&v)
 }
+
+// launchDeadline returns the deadline of waiting offers for the tasks launched
+// by the operation, from the optional `timeout` query, eg: ?timeout=5m.
+// zero if not specified, then the scheduler's default launch timeout is used.
+func launchDeadline(req *http.Request) (time.Time, error) {
+	v := req.URL.Query().Get("timeout")
+	if v == "" {
+		return time.Time{}, nil
+	}
+
+	timeout, err := time.ParseDuration(v)
+	if err != nil || timeout <= 0 {
+		return time.Time{}, fmt.Errorf("invalid timeout %q, should be a positive duration, eg: 5m", v)
+	}
+
+	return time.Now().Add(timeout), nil
+}
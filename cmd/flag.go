@@ -1,6 +1,8 @@
 package cmd
 
 import (
+	"time"
+
 	"github.com/urfave/cli"
 )
 
@@ -101,6 +103,33 @@ func FlagMaxTasksPerOffer() cli.Flag {
 	}
 }
 
+func FlagLaunchTimeout() cli.Flag {
+	return cli.DurationFlag{
+		Name:   "launch-timeout",
+		Usage:  "Default deadline of waiting offers to launch tasks, eg: 10m",
+		EnvVar: "SWAN_LAUNCH_TIMEOUT",
+		Value:  time.Minute * 10,
+	}
+}
+
+func FlagOfferRefuseSeconds() cli.Flag {
+	return cli.Float64Flag{
+		Name:   "offer-refuse-seconds",
+		Usage:  "Seconds mesos won't re-offer the declined resources to the framework",
+		EnvVar: "SWAN_OFFER_REFUSE_SECONDS",
+		Value:  5,
+	}
+}
+
+func FlagMesosRole() cli.Flag {
+	return cli.StringFlag{
+		Name:   "mesos-role",
+		Usage:  "The mesos role the framework subscribes with",
+		EnvVar: "SWAN_MESOS_ROLE",
+		Value:  "*",
+	}
+}
+
 func FlagEnableCapabilityKilling() cli.Flag {
 	return cli.StringFlag{
 		Name:   "enable-capability-killing",
@@ -119,6 +148,14 @@ func FlagEnableCheckPoint() cli.Flag {
 	}
 }
 
+func FlagTrustedProxies() cli.Flag {
+	return cli.StringFlag{
+		Name:   "trusted-proxies",
+		Usage:  "The ips or cidrs of the proxies in front of the managers splited by ',', eg: the other managers. the client address forwarded by them is taken",
+		EnvVar: "SWAN_TRUSTED_PROXIES",
+	}
+}
+
 func FlagJoinAddrs() cli.Flag {
 	return cli.StringFlag{
 		Name:   "join-addrs",
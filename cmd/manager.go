@@ -32,8 +32,12 @@ func ManagerCmd() cli.Command {
 		FlagReconciliationStepDelay(),
 		FlagHeartbeatTimeout(),
 		FlagMaxTasksPerOffer(),
+		FlagLaunchTimeout(),
+		FlagOfferRefuseSeconds(),
+		FlagMesosRole(),
 		FlagEnableCapabilityKilling(),
 		FlagEnableCheckPoint(),
+		FlagTrustedProxies(),
 	}
 
 	return cmd
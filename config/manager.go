@@ -2,9 +2,11 @@ package config
 
 import (
 	"fmt"
+	"net"
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/urfave/cli"
 )
@@ -23,19 +25,24 @@ type ManagerConfig struct {
 
 	Strategy string `json:"strategy"`
 
-	ReconciliationInterval  float64 `json:"reconciliationInterval"`
-	ReconciliationStep      int64   `json:"reconciliationStep"`
-	ReconciliationStepDelay float64 `json:"reconciliationStepDelay"`
-	HeartbeatTimeout        float64 `json:"heartbeatTimeout"`
-	MaxTasksPerOffer        int     `json:"maxTasksPerOffer"`
-	EnableCapabilityKilling bool    `json:"enableCapabilityKilling"`
-	EnableCheckPoint        bool    `json:"enableCheckPoint"`
+	ReconciliationInterval  float64       `json:"reconciliationInterval"`
+	ReconciliationStep      int64         `json:"reconciliationStep"`
+	ReconciliationStepDelay float64       `json:"reconciliationStepDelay"`
+	HeartbeatTimeout        float64       `json:"heartbeatTimeout"`
+	MaxTasksPerOffer        int           `json:"maxTasksPerOffer"`
+	LaunchTimeout           time.Duration `json:"launchTimeout"`
+	RefuseSeconds           float64       `json:"refuseSeconds"`
+	Role                    string        `json:"role"`
+	EnableCapabilityKilling bool          `json:"enableCapabilityKilling"`
+	EnableCheckPoint        bool          `json:"enableCheckPoint"`
+	TrustedProxies          []string      `json:"trustedProxies"`
 }
 
 func NewManagerConfig(c *cli.Context) (*ManagerConfig, error) {
 	cfg := &ManagerConfig{
 		LogLevel: "info",
 		Listen:   "0.0.0.0:9999",
+		Role:     "*",
 	}
 
 	var err error
@@ -96,6 +103,18 @@ func NewManagerConfig(c *cli.Context) (*ManagerConfig, error) {
 		cfg.MaxTasksPerOffer = max
 	}
 
+	if timeout := c.Duration("launch-timeout"); timeout != 0 {
+		cfg.LaunchTimeout = timeout
+	}
+
+	if refuse := c.Float64("offer-refuse-seconds"); refuse != 0 {
+		cfg.RefuseSeconds = refuse
+	}
+
+	if role := c.String("mesos-role"); role != "" {
+		cfg.Role = role
+	}
+
 	if killing := c.String("enable-capability-killing"); killing != "" {
 		cfg.EnableCapabilityKilling, _ = strconv.ParseBool(killing)
 	}
@@ -104,6 +123,10 @@ func NewManagerConfig(c *cli.Context) (*ManagerConfig, error) {
 		cfg.EnableCheckPoint, _ = strconv.ParseBool(ckpoint)
 	}
 
+	if proxies := c.String("trusted-proxies"); proxies != "" {
+		cfg.TrustedProxies = strings.Split(proxies, ",")
+	}
+
 	if err := cfg.validate(); err != nil {
 		return nil, err
 	}
@@ -127,8 +150,26 @@ func (c *ManagerConfig) validate() error {
 		return fmt.Errorf("zk url not corrected. path must be provied")
 	}
 
-	if c.Strategy != "random" && c.Strategy != "spread" && c.Strategy != "binpack" {
-		return fmt.Errorf("strategy not supported. must be one of the 'random, spread, binpack'")
+	if c.Strategy != "random" && c.Strategy != "spread" && c.Strategy != "binpack" && c.Strategy != "weighted" {
+		return fmt.Errorf("strategy not supported. must be one of the 'random, spread, binpack, weighted'")
+	}
+
+	if c.RefuseSeconds < 0 {
+		return fmt.Errorf("offer refuse seconds can not be negative")
+	}
+
+	if c.LaunchTimeout < 0 {
+		return fmt.Errorf("launch timeout can not be negative")
+	}
+
+	if strings.ContainsAny(c.Role, " /") || c.Role == "." || c.Role == ".." || strings.HasPrefix(c.Role, "-") {
+		return fmt.Errorf("invalid mesos role: %s", c.Role)
+	}
+
+	for _, p := range c.TrustedProxies {
+		if _, _, err := net.ParseCIDR(p); err != nil && net.ParseIP(p) == nil {
+			return fmt.Errorf("invalid trusted proxy: %s", p)
+		}
 	}
 
 	if c.ReconciliationInterval <= 0 {
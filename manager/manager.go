@@ -66,6 +66,9 @@ func New(cfg *config.ManagerConfig) (*Manager, error) {
 		ReconciliationStepDelay: cfg.ReconciliationStepDelay,
 		HeartbeatTimeout:        cfg.HeartbeatTimeout,
 		MaxTasksPerOffer:        cfg.MaxTasksPerOffer,
+		LaunchTimeout:           cfg.LaunchTimeout,
+		RefuseSeconds:           cfg.RefuseSeconds,
+		Role:                    cfg.Role,
 		EnableCapabilityKilling: cfg.EnableCapabilityKilling,
 		EnableCheckPoint:        cfg.EnableCheckPoint,
 	}
@@ -77,8 +80,9 @@ func New(cfg *config.ManagerConfig) (*Manager, error) {
 
 	// api server setup
 	srvcfg := api.Config{
-		Advertise: cfg.Advertise,
-		LogLevel:  cfg.LogLevel,
+		Advertise:      cfg.Advertise,
+		LogLevel:       cfg.LogLevel,
+		TrustedProxies: cfg.TrustedProxies,
 	}
 	srv := api.NewServer(&srvcfg, hl, sched, db)
 
@@ -160,10 +164,16 @@ func (m *Manager) start() error {
 				}
 
 				m.apiserver.UpdateLeader(m.leader)
+				m.apiserver.StartAutoScale()
+				m.apiserver.StartSchedules()
+				m.apiserver.StartDrains()
 
 			case LeadershipFollower:
 				log.Warnln("became follower, closing all agents ...")
 				m.clusterMaster.CloseAllAgents()
+				m.apiserver.StopAutoScale()
+				m.apiserver.StopSchedules()
+				m.apiserver.StopDrains()
 				m.apiserver.UpdateLeader(m.leader)
 			}
 
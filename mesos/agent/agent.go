@@ -42,6 +42,10 @@ func (s *Agent) ID() string {
 	return s.id
 }
 
+func (s *Agent) Hostname() string {
+	return s.hostname
+}
+
 func (s *Agent) AddOffer(offer *Offer) {
 	s.Lock()
 	s.offers[offer.GetId()] = offer
@@ -101,6 +105,31 @@ func (s *Agent) Resources() (cpus, mem, disk float64, ports []uint64) {
 	return
 }
 
+// UsableResources sums up the usable resources in the offers, eg: the
+// resources reserved for the role.
+func (s *Agent) UsableResources(usable func(*mesosproto.Resource) bool) (cpus, gpus, mem, disk float64, ports []uint64) {
+	for _, offer := range s.GetOffers() {
+		c, g, m, d, p := offer.UsableResources(usable)
+		cpus += c
+		gpus += g
+		mem += m
+		disk += d
+		ports = append(ports, p...)
+	}
+
+	return
+}
+
+// GPUs returns the avaliable gpus in the offers, kept apart from the
+// Resources() as only a few agents have gpus.
+func (s *Agent) GPUs() (gpus float64) {
+	for _, offer := range s.GetOffers() {
+		gpus += offer.GetGpus()
+	}
+
+	return
+}
+
 func (s *Agent) Attributes() map[string]string {
 	attrs := make(map[string]string)
 
@@ -9,6 +9,7 @@ import (
 type Offer struct {
 	id         string
 	cpus       float64
+	gpus       float64
 	mem        float64
 	disk       float64
 	ports      []uint64
@@ -16,6 +17,7 @@ type Offer struct {
 	attrs      map[string]string
 	hostname   string
 	agentId    string
+	resources  []*mesosproto.Resource
 }
 
 type portRange struct {
@@ -29,15 +31,16 @@ func (r *portRange) MarshalJSON() ([]byte, error) {
 
 func NewOffer(offer *mesosproto.Offer) *Offer {
 	f := &Offer{
-		id:       offer.GetId().GetValue(),
-		hostname: offer.GetHostname(),
-		agentId:  offer.GetAgentId().GetValue(),
+		id:        offer.GetId().GetValue(),
+		hostname:  offer.GetHostname(),
+		agentId:   offer.GetAgentId().GetValue(),
+		resources: offer.GetResources(),
 	}
 
 	var (
-		cpus, mem, disk float64
-		ports           []uint64
-		portRanges      []*portRange
+		cpus, gpus, mem, disk float64
+		ports                 []uint64
+		portRanges            []*portRange
 	)
 
 	for _, resource := range offer.Resources {
@@ -45,6 +48,10 @@ func NewOffer(offer *mesosproto.Offer) *Offer {
 			cpus += *resource.Scalar.Value
 		}
 
+		if *resource.Name == "gpus" {
+			gpus += *resource.Scalar.Value
+		}
+
 		if *resource.Name == "mem" {
 			mem += *resource.Scalar.Value
 		}
@@ -70,6 +77,7 @@ func NewOffer(offer *mesosproto.Offer) *Offer {
 	}
 
 	f.cpus = cpus
+	f.gpus = gpus
 	f.mem = mem
 	f.disk = disk
 	f.ports = ports
@@ -96,6 +104,10 @@ func (f *Offer) GetCpus() float64 {
 	return f.cpus
 }
 
+func (f *Offer) GetGpus() float64 {
+	return f.gpus
+}
+
 func (f *Offer) GetMem() float64 {
 	return f.mem
 }
@@ -126,6 +138,58 @@ func (f *Offer) GetAttrs() map[string]string {
 	return f.attrs
 }
 
+// GetResources returns the raw offered resources, with the roles & reservations.
+func (f *Offer) GetResources() []*mesosproto.Resource {
+	return f.resources
+}
+
+// UsableResources sums up the offered resources which are usable.
+func (f *Offer) UsableResources(usable func(*mesosproto.Resource) bool) (cpus, gpus, mem, disk float64, ports []uint64) {
+	for _, r := range f.resources {
+		if !usable(r) {
+			continue
+		}
+
+		switch r.GetName() {
+		case "cpus":
+			cpus += r.GetScalar().GetValue()
+		case "gpus":
+			gpus += r.GetScalar().GetValue()
+		case "mem":
+			mem += r.GetScalar().GetValue()
+		case "disk":
+			disk += r.GetScalar().GetValue()
+		case "ports":
+			for _, rg := range r.GetRanges().GetRange() {
+				for i := rg.GetBegin(); i <= rg.GetEnd(); i++ {
+					ports = append(ports, i)
+				}
+			}
+		}
+	}
+
+	return
+}
+
+// reserved sums up the scalar resources reserved for each role.
+func (f *Offer) reserved() map[string]map[string]float64 {
+	ret := make(map[string]map[string]float64)
+
+	for _, r := range f.resources {
+		role := r.GetRole()
+		if role == "*" || r.GetScalar() == nil {
+			continue
+		}
+
+		if _, ok := ret[role]; !ok {
+			ret[role] = make(map[string]float64)
+		}
+		ret[role][r.GetName()] += r.GetScalar().GetValue()
+	}
+
+	return ret
+}
+
 func (f *Offer) GetHostname() string {
 	return f.hostname
 }
@@ -134,11 +198,13 @@ func (f *Offer) MarshalJSON() ([]byte, error) {
 	m := map[string]interface{}{
 		"id":       f.id,
 		"cpus":     f.cpus,
+		"gpus":     f.gpus,
 		"mem":      f.mem,
 		"disk":     f.disk,
 		"ports":    f.portRanges,
 		"hostname": f.hostname,
 		"attrs":    f.attrs,
+		"reserved": f.reserved(),
 	}
 
 	return json.Marshal(m)
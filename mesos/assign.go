@@ -0,0 +1,263 @@
+package mesos
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	magent "github.com/Dataman-Cloud/swan/mesos/agent"
+	"github.com/Dataman-Cloud/swan/mesos/filter"
+	"github.com/Dataman-Cloud/swan/mesos/strategy"
+	"github.com/Dataman-Cloud/swan/types"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// the default deadline of launching tasks if not specified by the operation
+const defaultLaunchTimeout = time.Minute * 10
+
+// assignment is the tasks assigned to one agent along with the agent's offers.
+type assignment struct {
+	agent  *magent.Agent
+	offers []*magent.Offer
+	tasks  []*Task
+}
+
+// offerArrival returns the channel to be closed once new offers arrived.
+func (s *Scheduler) offerArrival() <-chan struct{} {
+	s.offerMu.Lock()
+	defer s.offerMu.Unlock()
+
+	return s.offerArrived
+}
+
+// notifyOffers wakes up all of the launchers waiting for offers.
+func (s *Scheduler) notifyOffers() {
+	s.offerMu.Lock()
+	defer s.offerMu.Unlock()
+
+	close(s.offerArrived)
+	s.offerArrived = make(chan struct{})
+}
+
+// launchDeadline returns the earliest deadline of the tasks, or the default
+// launch timeout from now if none of them specified.
+func (s *Scheduler) launchDeadline(tasks []*Task) time.Time {
+	var deadline time.Time
+	for _, task := range tasks {
+		if d := task.deadline; !d.IsZero() && (deadline.IsZero() || d.Before(deadline)) {
+			deadline = d
+		}
+	}
+
+	if deadline.IsZero() {
+		timeout := s.cfg.LaunchTimeout
+		if timeout <= 0 {
+			timeout = defaultLaunchTimeout
+		}
+		deadline = time.Now().Add(timeout)
+	}
+
+	return deadline
+}
+
+// capacity returns how many tasks with the requirements the agent's offers could hold.
+func capacity(agent *magent.Agent, opts *filter.FilterOptions) int {
+	var (
+		cpus, gpus, mem, disk float64
+		ports                 []uint64
+		req                   = opts.ResRequired
+	)
+
+	if opts.Usable != nil {
+		cpus, gpus, mem, disk, ports = agent.UsableResources(opts.Usable)
+	} else {
+		cpus, mem, disk, ports = agent.Resources()
+		gpus = agent.GPUs()
+	}
+
+	n := math.MaxInt32
+	fit := func(avail, required float64) {
+		if required <= 0 {
+			return
+		}
+		if m := int(math.Floor(avail / required)); m < n {
+			n = m
+		}
+	}
+
+	fit(cpus, req.CPUs)
+	fit(gpus, req.GPUs)
+	fit(mem, req.Mem)
+	fit(disk, req.Disk)
+	fit(float64(len(ports)), float64(req.NumPort))
+
+	return n
+}
+
+// place assigns the tasks to the agents ranked by the strategy, the binpack
+// strategy fills up the top ranked agent first, others spread the tasks over
+// the agents one by one. the offers of the picked agents are taken out, and the
+// tasks could not be placed by now are returned.
+func (s *Scheduler) place(appId string, tasks []*Task, opts *filter.FilterOptions, strat strategy.Strategy, typ string) ([]*assignment, []*Task, error) {
+	s.lockOffer()
+	defer s.unlockOffer()
+
+	agents := s.getAgents()
+	if len(agents) == 0 {
+		return nil, tasks, fmt.Errorf("no agents avaliable")
+	}
+
+	// each candidate agent should hold one task at least
+	one := *opts
+	one.Replicas = 1
+
+	filtered, err := filter.ApplyFilters(s.filters, &one, agents)
+	if err != nil {
+		return nil, tasks, err
+	}
+
+	var (
+		scores = strategy.Scores(strat, filtered)
+		byId   = make(map[string]*magent.Agent)
+		ranked = make([]*assignment, 0, len(scores))
+		caps   = make([]int, 0, len(scores))
+	)
+
+	s.rankings.set(appId, &ranking{
+		Strategy: typ,
+		Picked:   scores[0].AgentID,
+		Scores:   scores,
+		Time:     time.Now(),
+	})
+
+	for _, agent := range filtered {
+		byId[agent.ID()] = agent
+	}
+
+	for _, score := range scores {
+		agent := byId[score.AgentID]
+		if agent == nil {
+			continue
+		}
+		// the filters ensured the agent holds one task at least
+		n := capacity(agent, opts)
+		if n < 1 {
+			n = 1
+		}
+
+		ranked = append(ranked, &assignment{agent: agent})
+		caps = append(caps, n)
+	}
+
+	left := tasks
+	for len(left) > 0 {
+		assigned := false
+
+		for i, p := range ranked {
+			if caps[i] <= 0 {
+				continue
+			}
+
+			n := 1
+			if typ == types.StrategyBinPack {
+				n = caps[i]
+			}
+			if n > len(left) {
+				n = len(left)
+			}
+
+			p.tasks = append(p.tasks, left[:n]...)
+			caps[i] -= n
+			left = left[n:]
+			assigned = true
+
+			if len(left) == 0 || typ == types.StrategyBinPack {
+				break
+			}
+		}
+
+		if !assigned {
+			break
+		}
+	}
+
+	placements := make([]*assignment, 0)
+	for _, p := range ranked {
+		if len(p.tasks) == 0 {
+			continue
+		}
+
+		p.offers = p.agent.GetOffers()
+		for _, offer := range p.offers {
+			s.removeOffer(offer)
+		}
+
+		placements = append(placements, p)
+	}
+
+	log.Debugf("Placed %d of %d tasks on %d agents by %s strategy", len(tasks)-len(left), len(tasks), len(placements), typ)
+
+	return placements, left, nil
+}
+
+// waitOffers waits until some of the tasks could be placed, it's woken up once
+// new offers arrived, and gives up when the deadline exceeded.
+// the app with priority preempts the lower priority tasks if it waits too long,
+// and the evicted tasks are requeued once the wait is over.
+func (s *Scheduler) waitOffers(appId string, tasks []*Task, opts *filter.FilterOptions, strat strategy.Strategy, typ string, priority int, deadline time.Time) ([]*assignment, []*Task, error) {
+	var (
+		timer   = time.NewTimer(deadline.Sub(time.Now()))
+		lastTry = time.Now()
+		evicted []*victim
+	)
+
+	defer timer.Stop()
+
+	defer func() {
+		if len(evicted) > 0 {
+			go s.requeue(appId, evicted)
+		}
+	}()
+
+	for {
+		// take the channel before placing, so the offers arrived during the
+		// placement won't be missed.
+		arrived := s.offerArrival()
+
+		placements, left, err := s.place(appId, tasks, opts, strat, typ)
+		if len(placements) > 0 {
+			return placements, left, nil
+		}
+
+		log.Warnf("app %s without proper offers: [%v], waiting ...", appId, err)
+
+		var preempting <-chan time.Time
+		if priority > 0 {
+			if wait := preemptDelay - time.Since(lastTry); wait > 0 {
+				preempting = time.After(wait)
+			} else {
+				lastTry = time.Now()
+
+				remain := *opts
+				remain.Replicas = len(tasks)
+				evicted = append(evicted, s.preempt(appId, &remain, priority)...)
+				continue
+			}
+		}
+
+		select {
+		case <-arrived:
+		case <-preempting:
+		case <-timer.C:
+			if err == nil {
+				err = fmt.Errorf("no proper offers")
+			}
+			return nil, tasks, fmt.Errorf("launch deadline %s exceeded with %d task(s) unplaced: %v",
+				deadline.Format(time.RFC3339), len(tasks), err)
+		}
+
+		// the agents may be drained during the wait
+		opts.Unschedulable = s.unschedulableAgents()
+	}
+}
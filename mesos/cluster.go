@@ -149,11 +149,16 @@ func (s *Scheduler) buildAgentDNSReq(ev *types.TaskEvent) (*http.Request, error)
 func (s *Scheduler) buildAgentProxyRecord(ev *types.TaskEvent) *upstream.BackendCombined {
 	return &upstream.BackendCombined{
 		Upstream: &upstream.Upstream{
-			Name:   ev.AppID,
-			Alias:  ev.AppAlias,
-			Listen: ev.AppListen,
-			Target: strconv.Itoa(int(ev.TargetPort)),
-			Sticky: ev.AppSticky,
+			Name:        ev.AppID,
+			Alias:       ev.AppAlias,
+			Listen:      ev.AppListen,
+			Target:      strconv.Itoa(int(ev.TargetPort)),
+			Sticky:      ev.AppSticky,
+			Rules:       upstreamRules(ev.AppRules),
+			HealthCheck: upstreamHealthCheck(ev.AppHealthCheck),
+			Retry:       upstreamRetry(ev.AppRetry),
+			Balance:     upstreamBalance(ev.AppBalance),
+			Stickiness:  upstreamStickiness(ev.AppStickiness),
 		},
 		Backend: &upstream.Backend{
 			ID:         ev.TaskID,
@@ -168,6 +173,79 @@ func (s *Scheduler) buildAgentProxyRecord(ev *types.TaskEvent) *upstream.Backend
 	}
 }
 
+// the proxy configs of the app are converted to the janitor upstream configs.
+func upstreamRules(rules []*types.ProxyRule) []*upstream.Rule {
+	if len(rules) == 0 {
+		return nil
+	}
+
+	ret := make([]*upstream.Rule, 0, len(rules))
+	for _, r := range rules {
+		ret = append(ret, &upstream.Rule{
+			Host:       r.Host,
+			PathPrefix: r.PathPrefix,
+			PathRegex:  r.PathRegex,
+			Headers:    r.Headers,
+			Methods:    r.Methods,
+			Rewrite:    r.Rewrite,
+			Priority:   r.Priority,
+		})
+	}
+	return ret
+}
+
+func upstreamHealthCheck(hc *types.ProxyHealthCheck) *upstream.HealthCheck {
+	if hc == nil {
+		return nil
+	}
+
+	return &upstream.HealthCheck{
+		Protocol:    hc.Protocol,
+		Path:        hc.Path,
+		Interval:    hc.Interval,
+		Timeout:     hc.Timeout,
+		MaxFailures: hc.MaxFailures,
+		CoolDown:    hc.CoolDown,
+		Passive:     hc.Passive,
+	}
+}
+
+func upstreamRetry(r *types.ProxyRetry) *upstream.Retry {
+	if r == nil {
+		return nil
+	}
+
+	return &upstream.Retry{
+		Retries:        r.Retries,
+		ConnectTimeout: r.ConnectTimeout,
+		OnStatus:       r.OnStatus,
+	}
+}
+
+func upstreamBalance(bl *types.ProxyBalance) *upstream.Balance {
+	if bl == nil {
+		return nil
+	}
+
+	return &upstream.Balance{
+		Algorithm: bl.Algorithm,
+		HashOn:    bl.HashOn,
+		HashKey:   bl.HashKey,
+	}
+}
+
+func upstreamStickiness(st *types.ProxyStickiness) *upstream.Stickiness {
+	if st == nil {
+		return nil
+	}
+
+	return &upstream.Stickiness{
+		Mode:    st.Mode,
+		Name:    st.Name,
+		Timeout: st.Timeout,
+	}
+}
+
 func (s *Scheduler) buildAgentProxyReq(ev *types.TaskEvent) (*http.Request, error) {
 	body := s.buildAgentProxyRecord(ev)
 
@@ -0,0 +1,57 @@
+package mesos
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/Dataman-Cloud/swan/types"
+)
+
+// the converted upstream configs should be the same as the app's proxy
+// configs, so no fields are lost on the way to the agents.
+func TestBuildAgentProxyRecord(t *testing.T) {
+	ev := &types.TaskEvent{
+		AppID:     "app1",
+		AppSticky: true,
+		AppRules: []*types.ProxyRule{{
+			Host:       "a.com",
+			PathPrefix: "/api",
+			Headers:    map[string]string{"X-Canary": "1"},
+			Methods:    []string{"GET"},
+			Rewrite:    "/",
+			Priority:   1,
+		}},
+		AppHealthCheck: &types.ProxyHealthCheck{Protocol: "http", Path: "/health", Interval: 1, Timeout: 1, MaxFailures: 2, CoolDown: 10, Passive: true},
+		AppRetry:       &types.ProxyRetry{Retries: 2, ConnectTimeout: 5, OnStatus: true},
+		AppBalance:     &types.ProxyBalance{Algorithm: "hash", HashOn: "header", HashKey: "X-User"},
+		AppStickiness:  &types.ProxyStickiness{Mode: "cookie", Name: "sid", Timeout: 60},
+	}
+
+	u := (&Scheduler{}).buildAgentProxyRecord(ev).Upstream
+
+	tests := []struct {
+		name      string
+		got, want interface{}
+	}{
+		{"rules", u.Rules, ev.AppRules},
+		{"health check", u.HealthCheck, ev.AppHealthCheck},
+		{"retry", u.Retry, ev.AppRetry},
+		{"balance", u.Balance, ev.AppBalance},
+		{"stickiness", u.Stickiness, ev.AppStickiness},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _ := json.Marshal(tt.got)
+			want, _ := json.Marshal(tt.want)
+			if string(got) != string(want) {
+				t.Errorf("converted = %s, want %s", got, want)
+			}
+		})
+	}
+
+	empty := (&Scheduler{}).buildAgentProxyRecord(&types.TaskEvent{AppID: "app1"}).Upstream
+	if empty.Rules != nil || empty.HealthCheck != nil || empty.Retry != nil || empty.Balance != nil || empty.Stickiness != nil {
+		t.Errorf("converted the absent configs: %+v", empty)
+	}
+}
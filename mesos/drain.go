@@ -0,0 +1,26 @@
+package mesos
+
+import (
+	log "github.com/Sirupsen/logrus"
+)
+
+// unschedulableAgents returns the agents being drained or drained, which are
+// kept in the store so it survives the manager failover. the agent is back
+// once its maintenance window passed.
+func (s *Scheduler) unschedulableAgents() map[string]bool {
+	m := make(map[string]bool)
+
+	drains, err := s.db.ListDrains()
+	if err != nil {
+		log.Errorf("list drains error: %v", err)
+		return m
+	}
+
+	for _, d := range drains {
+		if !d.Expired() {
+			m[d.AgentID] = true
+		}
+	}
+
+	return m
+}
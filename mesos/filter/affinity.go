@@ -0,0 +1,53 @@
+package filter
+
+import (
+	"errors"
+
+	magent "github.com/Dataman-Cloud/swan/mesos/agent"
+	"github.com/Dataman-Cloud/swan/types"
+)
+
+var (
+	errNoAffinityAgent = errors.New("no agent satisfied the affinity rules")
+)
+
+// AffinityAgents is the agents running the tasks of the apps selected by the rule.
+type AffinityAgents struct {
+	Rule   *types.AffinityRule
+	Agents map[string]bool // agent id
+}
+
+type affinityFilter struct{}
+
+func NewAffinityFilter() *affinityFilter {
+	return &affinityFilter{}
+}
+
+func (f *affinityFilter) Filter(opts *FilterOptions, agents []*magent.Agent) ([]*magent.Agent, error) {
+	if len(opts.Affinities) == 0 {
+		return agents, nil
+	}
+
+	candidates := make([]*magent.Agent, 0)
+
+	for _, agent := range agents {
+		match := true
+		for _, aff := range opts.Affinities {
+			running := aff.Agents[agent.ID()]
+
+			if (aff.Rule.Type == types.Affinity && !running) || (aff.Rule.Type == types.AntiAffinity && running) {
+				match = false
+				break
+			}
+		}
+
+		if match {
+			candidates = append(candidates, agent)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return nil, errNoAffinityAgent
+	}
+	return candidates, nil
+}
@@ -2,8 +2,10 @@ package filter
 
 import (
 	"errors"
+	"strconv"
 
 	magent "github.com/Dataman-Cloud/swan/mesos/agent"
+	"github.com/Dataman-Cloud/swan/types"
 )
 
 var (
@@ -20,12 +22,15 @@ func (f *constraintsFilter) Filter(opts *FilterOptions, agents []*magent.Agent)
 	var (
 		constraints = opts.Constraints
 		candidates  = make([]*magent.Agent, 0)
+		attrs       = make(map[string]map[string]string) // agent id -> attributes
 	)
 
 	for _, agent := range agents {
+		attrs[agent.ID()] = agent.Attributes()
+
 		match := true
 		for _, constraint := range constraints {
-			if constraint.Match(agent.Attributes()) {
+			if constraint.Match(attrs[agent.ID()]) {
 				continue
 			}
 			match = false
@@ -37,8 +42,75 @@ func (f *constraintsFilter) Filter(opts *FilterOptions, agents []*magent.Agent)
 		}
 	}
 
+	for _, constraint := range constraints {
+		if constraint.IsPlacement() {
+			candidates = filterPlacement(constraint, opts, candidates, attrs)
+		}
+	}
+
 	if len(candidates) == 0 {
 		return nil, errNoSatisfiedAgent
 	}
 	return candidates, nil
 }
+
+// filterPlacement filters the agents by the number of the app's tasks already
+// running on each attribute value, with the replicas going to be placed.
+func filterPlacement(c *types.Constraint, opts *FilterOptions, agents []*magent.Agent, attrs map[string]map[string]string) []*magent.Agent {
+	var (
+		counts     = make(map[string]int) // attribute value -> nb of tasks
+		candidates = make([]*magent.Agent, 0)
+	)
+
+	for _, placed := range opts.Placement {
+		if v, ok := placed[c.Attribute]; ok {
+			counts[v]++
+		}
+	}
+
+	switch c.Operator {
+	case types.ConstraintUnique, types.ConstraintMaxPer:
+		max := 1
+		if c.Operator == types.ConstraintMaxPer {
+			max, _ = strconv.Atoi(c.Value)
+		}
+
+		for _, agent := range agents {
+			if counts[attrs[agent.ID()][c.Attribute]]+opts.Replicas <= max {
+				candidates = append(candidates, agent)
+			}
+		}
+
+	case types.ConstraintGroupBy:
+		// spread to the least used attribute values among the agents, the
+		// values not seen yet are counted as zero by the expected number.
+		var (
+			min  = -1
+			seen = make(map[string]bool)
+		)
+
+		for v := range counts {
+			seen[v] = true
+		}
+
+		for _, agent := range agents {
+			v := attrs[agent.ID()][c.Attribute]
+			seen[v] = true
+			if n := counts[v]; min < 0 || n < min {
+				min = n
+			}
+		}
+
+		if groups, _ := strconv.Atoi(c.Value); groups > len(seen) {
+			min = 0
+		}
+
+		for _, agent := range agents {
+			if counts[attrs[agent.ID()][c.Attribute]] == min {
+				candidates = append(candidates, agent)
+			}
+		}
+	}
+
+	return candidates
+}
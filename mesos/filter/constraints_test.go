@@ -0,0 +1,149 @@
+package filter
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+
+	magent "github.com/Dataman-Cloud/swan/mesos/agent"
+	"github.com/Dataman-Cloud/swan/mesosproto"
+	"github.com/Dataman-Cloud/swan/types"
+)
+
+func newTestAgent(id string, attrs map[string]string) *magent.Agent {
+	offer := &mesosproto.Offer{
+		Id:          &mesosproto.OfferID{Value: proto.String(id + "-offer")},
+		AgentId:     &mesosproto.AgentID{Value: proto.String(id)},
+		FrameworkId: &mesosproto.FrameworkID{Value: proto.String("swan")},
+		Hostname:    proto.String(id),
+	}
+
+	for k, v := range attrs {
+		offer.Attributes = append(offer.Attributes, &mesosproto.Attribute{
+			Name: proto.String(k),
+			Type: mesosproto.Value_TEXT.Enum(),
+			Text: &mesosproto.Value_Text{Value: proto.String(v)},
+		})
+	}
+
+	a := magent.NewAgent(id, id, offer.Attributes)
+	a.AddOffer(magent.NewOffer(offer))
+	return a
+}
+
+func agentIds(agents []*magent.Agent) []string {
+	ids := make([]string, 0, len(agents))
+	for _, a := range agents {
+		ids = append(ids, a.ID())
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func TestConstraintsFilter(t *testing.T) {
+	agents := []*magent.Agent{
+		newTestAgent("a1", map[string]string{"rack": "r1", "zone": "a"}),
+		newTestAgent("a2", map[string]string{"rack": "r1", "zone": "b"}),
+		newTestAgent("a3", map[string]string{"rack": "r2", "zone": "c"}),
+		newTestAgent("a4", map[string]string{"zone": "a"}),
+	}
+
+	tests := []struct {
+		name       string
+		constraint *types.Constraint
+		placement  []map[string]string
+		replicas   int
+		want       []string
+	}{
+		{
+			name:       "equal",
+			constraint: &types.Constraint{Attribute: "zone", Operator: "==", Value: "a"},
+			want:       []string{"a1", "a4"},
+		},
+		{
+			name:       "in",
+			constraint: &types.Constraint{Attribute: "zone", Operator: types.ConstraintIn, Value: "a, c"},
+			want:       []string{"a1", "a3", "a4"},
+		},
+		{
+			name:       "not in rejects the agents without the attribute",
+			constraint: &types.Constraint{Attribute: "rack", Operator: types.ConstraintNotIn, Value: "r2"},
+			want:       []string{"a1", "a2"},
+		},
+		{
+			name:       "unique",
+			constraint: &types.Constraint{Attribute: "rack", Operator: types.ConstraintUnique},
+			placement:  []map[string]string{{"rack": "r1"}},
+			want:       []string{"a3"},
+		},
+		{
+			name:       "max per",
+			constraint: &types.Constraint{Attribute: "zone", Operator: types.ConstraintMaxPer, Value: "2"},
+			placement:  []map[string]string{{"zone": "a"}, {"zone": "b"}, {"zone": "b"}},
+			want:       []string{"a1", "a3", "a4"},
+		},
+		{
+			name:       "max per with replicas",
+			constraint: &types.Constraint{Attribute: "zone", Operator: types.ConstraintMaxPer, Value: "2"},
+			placement:  []map[string]string{{"zone": "a"}},
+			replicas:   2,
+			want:       []string{"a2", "a3"},
+		},
+		{
+			name:       "group by the least used value",
+			constraint: &types.Constraint{Attribute: "rack", Operator: types.ConstraintGroupBy},
+			placement:  []map[string]string{{"rack": "r1"}, {"rack": "r2"}, {"rack": "r2"}},
+			want:       []string{"a1", "a2"},
+		},
+		{
+			name:       "group by the seen values",
+			constraint: &types.Constraint{Attribute: "rack", Operator: types.ConstraintGroupBy},
+			placement:  []map[string]string{{"rack": "r1"}, {"rack": "r2"}, {"rack": "r3"}},
+			want:       []string{"a1", "a2", "a3"},
+		},
+		{
+			name:       "group by the expected number of values",
+			constraint: &types.Constraint{Attribute: "rack", Operator: types.ConstraintGroupBy, Value: "3"},
+			placement:  []map[string]string{{"rack": "r1"}, {"rack": "r2"}},
+			want:       []string{},
+		},
+		{
+			name:       "group by all of the expected values used",
+			constraint: &types.Constraint{Attribute: "rack", Operator: types.ConstraintGroupBy, Value: "3"},
+			placement:  []map[string]string{{"rack": "r1"}, {"rack": "r2"}, {"rack": "r3"}, {"rack": "r3"}},
+			want:       []string{"a1", "a2", "a3"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			replicas := tt.replicas
+			if replicas == 0 {
+				replicas = 1
+			}
+
+			opts := &FilterOptions{
+				Replicas:    replicas,
+				Constraints: []*types.Constraint{tt.constraint},
+				Placement:   tt.placement,
+			}
+
+			got, err := NewConstraintsFilter().Filter(opts, agents)
+			if len(tt.want) == 0 {
+				if err != errNoSatisfiedAgent {
+					t.Errorf("Filter() = %v, want error %v", agentIds(got), errNoSatisfiedAgent)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Filter() error = %v", err)
+			}
+			if ids := agentIds(got); !reflect.DeepEqual(ids, tt.want) {
+				t.Errorf("Filter() = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
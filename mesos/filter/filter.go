@@ -2,6 +2,7 @@ package filter
 
 import (
 	magent "github.com/Dataman-Cloud/swan/mesos/agent"
+	"github.com/Dataman-Cloud/swan/mesosproto"
 	"github.com/Dataman-Cloud/swan/types"
 )
 
@@ -14,8 +15,25 @@ type FilterOptions struct {
 	ResRequired types.ResourcesRequired
 	Replicas    int
 
+	// the offered resources could be used by the app, according by the
+	// roles & reservations, all of the resources are usable if nil.
+	Usable func(*mesosproto.Resource) bool
+
 	// constraints
 	Constraints []*types.Constraint
+
+	// attributes of the agents the app's tasks already running on, one
+	// entry for each task, used by the placement constraints.
+	Placement []map[string]string
+
+	// agents resolved from the app affinity rules
+	Affinities []*AffinityAgents
+
+	// persistent volumes of the task
+	Volumes *VolumeOptions
+
+	// agent ids which shouldn't run new tasks, eg: draining
+	Unschedulable map[string]bool
 }
 
 // the returned agents contains at least one proper agent
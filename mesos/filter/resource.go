@@ -16,13 +16,18 @@ func NewResourceFilter() *resourceFilter {
 	return &resourceFilter{}
 }
 
+// Filter picks the agents with enough resources, the tasks without gpus
+// are placed onto the agents with gpus only if no others could fit, to
+// keep the gpus avaliable for the tasks really need them.
 func (f *resourceFilter) Filter(opts *FilterOptions, agents []*magent.Agent) ([]*magent.Agent, error) {
 	var (
 		candidates = make([]*magent.Agent, 0)
+		gpuAgents  = make([]*magent.Agent, 0)
 		replicas   = opts.Replicas
 
 		// multiplicate with replicas to calculate total resource requirments
 		cpuReq   = opts.ResRequired.CPUs * float64(replicas)
+		gpuReq   = opts.ResRequired.GPUs * float64(replicas)
 		memReq   = opts.ResRequired.Mem * float64(replicas)
 		diskReq  = opts.ResRequired.Disk * float64(replicas)
 		portsReq = opts.ResRequired.NumPort * replicas
@@ -30,11 +35,31 @@ func (f *resourceFilter) Filter(opts *FilterOptions, agents []*magent.Agent) ([]
 
 	for _, agent := range agents {
 		var (
-			cpus, mem, disk, ports = agent.Resources() // avaliable agent resources
+			cpus, gpus, mem, disk float64
+			ports                 []uint64
 		)
-		if cpus >= cpuReq && mem >= memReq && disk >= diskReq && len(ports) >= portsReq {
-			candidates = append(candidates, agent)
+
+		// avaliable agent resources
+		if opts.Usable != nil {
+			cpus, gpus, mem, disk, ports = agent.UsableResources(opts.Usable)
+		} else {
+			cpus, mem, disk, ports = agent.Resources()
+			gpus = agent.GPUs()
+		}
+		if cpus < cpuReq || gpus < gpuReq || mem < memReq || disk < diskReq || len(ports) < portsReq {
+			continue
+		}
+
+		if gpuReq == 0 && gpus > 0 {
+			gpuAgents = append(gpuAgents, agent)
+			continue
 		}
+
+		candidates = append(candidates, agent)
+	}
+
+	if len(candidates) == 0 {
+		candidates = gpuAgents
 	}
 
 	if len(candidates) == 0 {
@@ -0,0 +1,34 @@
+package filter
+
+import (
+	"errors"
+
+	magent "github.com/Dataman-Cloud/swan/mesos/agent"
+)
+
+var errAllUnschedulable = errors.New("all of the agents are unschedulable")
+
+// unschedulableFilter excludes the agents marked unschedulable, eg: draining.
+type unschedulableFilter struct{}
+
+func NewUnschedulableFilter() *unschedulableFilter {
+	return &unschedulableFilter{}
+}
+
+func (f *unschedulableFilter) Filter(opts *FilterOptions, agents []*magent.Agent) ([]*magent.Agent, error) {
+	if len(opts.Unschedulable) == 0 {
+		return agents, nil
+	}
+
+	candidates := make([]*magent.Agent, 0)
+	for _, agent := range agents {
+		if !opts.Unschedulable[agent.ID()] {
+			candidates = append(candidates, agent)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return nil, errAllUnschedulable
+	}
+	return candidates, nil
+}
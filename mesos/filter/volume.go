@@ -0,0 +1,104 @@
+package filter
+
+import (
+	"errors"
+
+	magent "github.com/Dataman-Cloud/swan/mesos/agent"
+	"github.com/Dataman-Cloud/swan/mesosproto"
+)
+
+var (
+	errVolumeNotOffered  = errors.New("persistent volumes not offered")
+	errVolumeDiskNoSpace = errors.New("no disk to create persistent volumes")
+)
+
+// VolumeOptions describes the persistent volumes of the task, the task is
+// pinned to the agent holding its bound volumes, or the agent should have
+// the disk to create the new ones.
+type VolumeOptions struct {
+	AgentID      string
+	Persistences []string  // persistence ids of the bound volumes
+	Sizes        []float64 // sizes of the volumes to be created
+	Usable       func(*mesosproto.Resource) bool
+}
+
+type volumeFilter struct{}
+
+func NewVolumeFilter() *volumeFilter {
+	return &volumeFilter{}
+}
+
+func (f *volumeFilter) Filter(opts *FilterOptions, agents []*magent.Agent) ([]*magent.Agent, error) {
+	vopts := opts.Volumes
+	if vopts == nil {
+		return agents, nil
+	}
+
+	if vopts.AgentID != "" {
+		for _, agent := range agents {
+			if agent.ID() == vopts.AgentID && offersVolumes(agent, vopts.Persistences) && fitsVolumes(agent, vopts.Sizes, vopts.Usable) {
+				return []*magent.Agent{agent}, nil
+			}
+		}
+		return nil, errVolumeNotOffered
+	}
+
+	candidates := make([]*magent.Agent, 0)
+	for _, agent := range agents {
+		if fitsVolumes(agent, vopts.Sizes, vopts.Usable) {
+			candidates = append(candidates, agent)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return nil, errVolumeDiskNoSpace
+	}
+	return candidates, nil
+}
+
+func offersVolumes(agent *magent.Agent, persistences []string) bool {
+	offered := make(map[string]bool)
+	for _, offer := range agent.GetOffers() {
+		for _, r := range offer.GetResources() {
+			if id := r.GetDisk().GetPersistence().GetId(); id != "" {
+				offered[id] = true
+			}
+		}
+	}
+
+	for _, id := range persistences {
+		if !offered[id] {
+			return false
+		}
+	}
+	return true
+}
+
+// fitsVolumes checks each of the volumes could be created on one of the disk
+// resources, as a volume can't be splitted among the resources.
+func fitsVolumes(agent *magent.Agent, sizes []float64, usable func(*mesosproto.Resource) bool) bool {
+	disks := make([]float64, 0)
+	for _, offer := range agent.GetOffers() {
+		for _, r := range offer.GetResources() {
+			if r.GetName() == "disk" && usable(r) {
+				disks = append(disks, r.GetScalar().GetValue())
+			}
+		}
+	}
+
+	for _, size := range sizes {
+		fit := false
+		for i, disk := range disks {
+			if disk >= size {
+				disks[i] -= size
+				fit = true
+				break
+			}
+		}
+
+		if !fit {
+			return false
+		}
+	}
+	return true
+}
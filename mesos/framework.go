@@ -29,6 +29,7 @@ func (s *Scheduler) buildFramework() *mesosproto.FrameworkInfo {
 		User:            proto.String(defaultFrameworkUser),
 		Name:            proto.String(defaultFrameworkName),
 		Principal:       proto.String(defaultFrameworkPrincipal),
+		Role:            proto.String(s.cfg.Role),
 		FailoverTimeout: proto.Float64(defaultFrameworkFailoverTimeout),
 		Checkpoint:      proto.Bool(s.cfg.EnableCheckPoint),
 		Hostname:        proto.String(hostName),
@@ -49,6 +50,7 @@ func (s *Scheduler) buildFramework() *mesosproto.FrameworkInfo {
 
 func (s *Scheduler) FrameworkInfo() *types.FrameworkInfo {
 	return &types.FrameworkInfo{
-		ID: s.framework.Id.GetValue(),
+		ID:   s.framework.Id.GetValue(),
+		Role: s.framework.GetRole(),
 	}
 }
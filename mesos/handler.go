@@ -3,6 +3,7 @@ package mesos
 import (
 	"encoding/json"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	magent "github.com/Dataman-Cloud/swan/mesos/agent"
@@ -33,7 +34,11 @@ func (s *Scheduler) subscribedHandler(event *mesosproto.Event) {
 		log.Errorf("update frameworkid got error:%s", err)
 	}
 
+	s.resetDemand()
+
 	s.startReconcileLoop()
+
+	go s.resumeRelaunches()
 }
 
 func (s *Scheduler) offersHandler(event *mesosproto.Event) {
@@ -43,11 +48,20 @@ func (s *Scheduler) offersHandler(event *mesosproto.Event) {
 
 	log.Debugf("Receiving %d offer(s) from mesos", len(offers))
 
+	atomic.AddInt64(&s.counters.received, int64(len(offers)))
+
 	for _, offer := range offers {
 		agentId := offer.AgentId.GetValue()
 		attrs := offer.GetAttributes()
 		hostname := offer.GetHostname()
 
+		s.attrs.update(offer)
+
+		// the offer is consumed by releasing the stale reservations
+		if s.unreserveStale(offer) {
+			continue
+		}
+
 		a := s.getAgent(agentId)
 		if a == nil {
 			a = magent.NewAgent(agentId, hostname, attrs)
@@ -56,6 +70,12 @@ func (s *Scheduler) offersHandler(event *mesosproto.Event) {
 
 		s.addOffer(offer)
 	}
+
+	s.notifyOffers()
+
+	if inverses := event.Offers.GetInverseOffers(); len(inverses) > 0 {
+		s.inverseOffersHandler(inverses)
+	}
 }
 
 func (s *Scheduler) rescindedHandler(event *mesosproto.Event) {
@@ -65,6 +85,12 @@ func (s *Scheduler) rescindedHandler(event *mesosproto.Event) {
 
 	log.Debugln("Receiving rescind msg for offer ", offerId)
 
+	if s.rescindInverseOffer(offerId) {
+		return
+	}
+
+	atomic.AddInt64(&s.counters.rescinded, 1)
+
 	for _, agent := range s.getAgents() {
 		if offer := agent.GetOffer(offerId); offer != nil {
 			s.removeOffer(offer)
@@ -98,7 +124,7 @@ func (s *Scheduler) updateHandler(event *mesosproto.Event) {
 	var (
 		status  = event.GetUpdate().GetStatus()
 		state   = status.GetState()
-		taskId  = status.TaskId.GetValue()
+		taskId  = types.PodTaskId(status.TaskId.GetValue())
 		healthy = status.GetHealthy()
 		data    = status.GetData()
 	)
@@ -179,6 +205,10 @@ func (s *Scheduler) updateHandler(event *mesosproto.Event) {
 		}
 	}
 
+	if task.IP == "" && state == mesosproto.TaskState_TASK_RUNNING {
+		task.IP = containerIP(status)
+	}
+
 	var (
 		previousHealthy = task.Healthy // previous healthy
 		previousStatus  = task.Status  // previous status
@@ -198,9 +228,18 @@ func (s *Scheduler) updateHandler(event *mesosproto.Event) {
 		task.ErrMsg = status.GetReason().String() + ":" + status.GetMessage()
 	}
 
-	// reset task failed retry time to zero
+	// memo the time the task became running, its failed retry time is reset
+	// to zero only if it has stayed running long enough.
 	if state == mesosproto.TaskState_TASK_RUNNING {
-		task.Retries = 0
+		if task.RunningSince == nil {
+			now := time.Now()
+			task.RunningSince = &now
+		}
+	} else if since := task.RunningSince; since != nil {
+		if restart := s.taskRestartPolicy(appId, task); time.Since(*since) >= restart.ResetDuration() {
+			task.Retries = 0
+		}
+		task.RunningSince = nil
 	}
 
 	// memo db update db task
@@ -253,7 +292,7 @@ func (s *Scheduler) updateHandler(event *mesosproto.Event) {
 		if ver.Proxy != nil {
 			proxies := ver.Proxy.Proxies
 			proxyEnabled := ver.Proxy.Enabled
-			mappings := ver.Container.Docker.PortMappings
+			mappings := ver.Container.PortMappings()
 			for i, proxy := range proxies {
 				var (
 					alias      = proxy.Alias
@@ -274,6 +313,11 @@ func (s *Scheduler) updateHandler(event *mesosproto.Event) {
 					AppAlias:       alias,
 					AppListen:      listen,
 					AppSticky:      sticky,
+					AppRules:       proxy.Rules,
+					AppHealthCheck: proxy.HealthCheck,
+					AppRetry:       proxy.Retry,
+					AppBalance:     proxy.Balance,
+					AppStickiness:  proxy.Stickiness,
 					TaskID:         taskId,
 					IP:             task.IP,
 					Port:           taskPort,
@@ -307,6 +351,11 @@ func (s *Scheduler) updateHandler(event *mesosproto.Event) {
 	if state != mesosproto.TaskState_TASK_STAGING &&
 		state != mesosproto.TaskState_TASK_STARTING &&
 		state != mesosproto.TaskState_TASK_RUNNING {
+		if !ver.RestartPolicy.Restart(task.Status) {
+			log.Debugln("task", taskId, "terminated as", task.Status, "not restarted by policy")
+			return
+		}
+
 		if task.Retries >= task.MaxRetries {
 			// no more retry
 			log.Debugln("task", taskId, "maxRetries:", task.MaxRetries, "retries:", task.Retries)
@@ -62,6 +62,11 @@ func (s *Scheduler) FullTaskEventsAndRecords() []*types.CombinedEvents {
 					taskEv.AppAlias = proxy.Alias
 					taskEv.AppListen = proxy.Listen
 					taskEv.AppSticky = proxy.Sticky
+					taskEv.AppRules = proxy.Rules
+					taskEv.AppHealthCheck = proxy.HealthCheck
+					taskEv.AppRetry = proxy.Retry
+					taskEv.AppBalance = proxy.Balance
+					taskEv.AppStickiness = proxy.Stickiness
 					if len(task.Ports) > 0 {
 						taskEv.Port = task.Ports[i] // currently only support the first port within proxy & events
 					}
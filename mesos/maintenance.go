@@ -0,0 +1,239 @@
+package mesos
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/Dataman-Cloud/swan/mesosproto"
+	"github.com/Dataman-Cloud/swan/types"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// the interval to check the drain of the agent under maintenance
+const inverseOfferCheckInterval = time.Second * 5
+
+// MaintenanceSchedule returns the maintenance windows of the machines from
+// the mesos master.
+func (s *Scheduler) MaintenanceSchedule() ([]*types.MaintenanceWindow, error) {
+	resp, err := http.Get("http://" + s.leader + "/maintenance/schedule")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if code := resp.StatusCode; code != http.StatusOK {
+		return nil, fmt.Errorf("get mesos maintenance schedule with unexpected response [%d]", code)
+	}
+
+	var schedule struct {
+		Windows []struct {
+			MachineIDs []struct {
+				Hostname string `json:"hostname"`
+				IP       string `json:"ip"`
+			} `json:"machine_ids"`
+			Unavailability struct {
+				Start struct {
+					Nanoseconds int64 `json:"nanoseconds"`
+				} `json:"start"`
+				Duration *struct {
+					Nanoseconds int64 `json:"nanoseconds"`
+				} `json:"duration"`
+			} `json:"unavailability"`
+		} `json:"windows"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&schedule); err != nil {
+		return nil, err
+	}
+
+	ret := make([]*types.MaintenanceWindow, 0)
+	for _, w := range schedule.Windows {
+		var (
+			start = time.Unix(0, w.Unavailability.Start.Nanoseconds)
+			end   *time.Time
+		)
+
+		if d := w.Unavailability.Duration; d != nil {
+			t := start.Add(time.Duration(d.Nanoseconds))
+			end = &t
+		}
+
+		for _, m := range w.MachineIDs {
+			ret = append(ret, &types.MaintenanceWindow{
+				Hostname: m.Hostname,
+				IP:       m.IP,
+				Start:    start,
+				End:      end,
+			})
+		}
+	}
+
+	return ret, nil
+}
+
+// inverseOffersHandler drains the agents requested back by the inverse offers,
+// each inverse offer is accepted once its agent has been drained.
+func (s *Scheduler) inverseOffersHandler(offers []*mesosproto.InverseOffer) {
+	for _, offer := range offers {
+		var (
+			id      = offer.GetId().GetValue()
+			agentId = offer.GetAgentId().GetValue()
+		)
+
+		if agentId == "" {
+			log.Warnf("inverse offer %s for all of the agents not supported, ignored", id)
+			continue
+		}
+
+		s.inverseMu.Lock()
+		_, handling := s.inverses[id]
+		s.inverses[id] = true
+		s.inverseMu.Unlock()
+
+		if !handling {
+			go s.handleInverseOffer(offer)
+		}
+	}
+}
+
+func (s *Scheduler) handleInverseOffer(offer *mesosproto.InverseOffer) {
+	var (
+		id      = offer.GetId().GetValue()
+		agentId = offer.GetAgentId().GetValue()
+		start   = time.Unix(0, offer.GetUnavailability().GetStart().GetNanoseconds())
+		until   *time.Time
+	)
+
+	defer s.forgetInverseOffer(id)
+
+	if d := offer.GetUnavailability().GetDuration(); d != nil {
+		t := start.Add(time.Duration(d.GetNanoseconds()))
+		until = &t
+	}
+
+	log.Printf("Received inverse offer %s, agent %s under maintenance from %s", id, agentId, start.Format(time.RFC3339))
+
+	if err := s.drainForMaintenance(agentId, until); err != nil {
+		log.Errorf("drain agent %s for maintenance error: %v", agentId, err)
+		return
+	}
+
+	ticker := time.NewTicker(inverseOfferCheckInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if !s.inverseOutstanding(id) {
+			log.Printf("inverse offer %s rescinded", id)
+			return
+		}
+
+		d, err := s.db.GetDrain(agentId)
+		if err != nil {
+			if s.db.IsErrNotFound(err) {
+				log.Printf("agent %s drain canceled, declining inverse offer %s", agentId, id)
+				s.respondInverseOffer(id, mesosproto.Call_DECLINE)
+			} else {
+				log.Errorf("get agent %s drain error: %v", agentId, err)
+			}
+			return
+		}
+
+		switch d.Status {
+		case types.DrainStatusDrained:
+			log.Printf("agent %s drained, accepting inverse offer %s", agentId, id)
+			s.respondInverseOffer(id, mesosproto.Call_ACCEPT)
+			return
+		case types.DrainStatusFailed:
+			log.Errorf("agent %s drain failed: %s, declining inverse offer %s", agentId, d.ErrMsg, id)
+			s.respondInverseOffer(id, mesosproto.Call_DECLINE)
+			return
+		}
+
+		if time.Now().After(start) {
+			log.Warnf("agent %s maintenance started before drained", agentId)
+			return
+		}
+	}
+}
+
+// drainForMaintenance marks the agent draining, the api server moves its tasks.
+// the drain of the passed maintenance is replaced.
+func (s *Scheduler) drainForMaintenance(agentId string, until *time.Time) error {
+	d, err := s.db.GetDrain(agentId)
+	if err == nil && !d.Expired() {
+		return nil // drained already
+	}
+
+	if err != nil && !s.db.IsErrNotFound(err) {
+		return err
+	}
+
+	if err == nil {
+		if err := s.db.DeleteDrain(agentId); err != nil {
+			return err
+		}
+	}
+
+	return s.db.CreateDrain(&types.Drain{
+		AgentID: agentId,
+		Status:  types.DrainStatusDraining,
+		Reason:  types.DrainReasonMaintenance,
+		Until:   until,
+		Created: time.Now(),
+		Updated: time.Now(),
+	})
+}
+
+// respondInverseOffer accepts or declines the inverse offer, the inverse offer
+// ids are taken by the ACCEPT & DECLINE calls of the mesos scheduler api.
+func (s *Scheduler) respondInverseOffer(id string, typ mesosproto.Call_Type) {
+	var (
+		offerIds = []*mesosproto.OfferID{{Value: proto.String(id)}}
+		call     = &mesosproto.Call{
+			FrameworkId: s.FrameworkId(),
+			Type:        typ.Enum(),
+		}
+	)
+
+	if typ == mesosproto.Call_ACCEPT {
+		call.Accept = &mesosproto.Call_Accept{OfferIds: offerIds}
+	} else {
+		call.Decline = &mesosproto.Call_Decline{OfferIds: offerIds}
+	}
+
+	if _, err := s.SendCall(call, http.StatusAccepted); err != nil {
+		log.Errorf("respond inverse offer %s by %s error: %v", id, typ, err)
+	}
+}
+
+func (s *Scheduler) inverseOutstanding(id string) bool {
+	s.inverseMu.Lock()
+	defer s.inverseMu.Unlock()
+
+	return s.inverses[id]
+}
+
+// rescindInverseOffer tells whether the rescinded offer is an inverse offer.
+func (s *Scheduler) rescindInverseOffer(id string) bool {
+	s.inverseMu.Lock()
+	defer s.inverseMu.Unlock()
+
+	if _, ok := s.inverses[id]; !ok {
+		return false
+	}
+
+	s.inverses[id] = false
+	return true
+}
+
+func (s *Scheduler) forgetInverseOffer(id string) {
+	s.inverseMu.Lock()
+	defer s.inverseMu.Unlock()
+
+	delete(s.inverses, id)
+}
@@ -40,6 +40,53 @@ func (s *Scheduler) FrameworkState() (*megos.Framework, error) {
 	return nil, fmt.Errorf("no such framework: %s", fwName)
 }
 
+// reconcileState is the framework state with the unreachable tasks & agents,
+// which are not parsed by megos, available since mesos 1.1.
+type reconcileState struct {
+	Frameworks []struct {
+		megos.Framework
+		UnreachableTasks []megos.Task `json:"unreachable_tasks"`
+	} `json:"frameworks"`
+	UnreachableSlaves []struct {
+		ID string `json:"id"`
+	} `json:"unreachable_slaves"`
+}
+
+// frameworkReconcileState obtains the framework state, with the tasks & the
+// ids of the agents unreachable to the mesos master.
+func (s *Scheduler) frameworkReconcileState() (*megos.Framework, []megos.Task, map[string]bool, error) {
+	client, err := s.megosClient()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	resp, err := client.GetHTTPResponseFromCluster(client.GetURLForStateFile)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	var state reconcileState
+	if err := json.NewDecoder(resp.Body).Decode(&state); err != nil {
+		return nil, nil, nil, err
+	}
+
+	unreachable := make(map[string]bool)
+	for _, agent := range state.UnreachableSlaves {
+		unreachable[agent.ID] = true
+	}
+
+	fwName := s.framework.GetName()
+	for _, fw := range state.Frameworks {
+		if fw.Name == fwName {
+			nfw := fw.Framework
+			return &nfw, fw.UnreachableTasks, unreachable, nil
+		}
+	}
+
+	return nil, nil, nil, fmt.Errorf("no such framework: %s", fwName)
+}
+
 // megosClient is just a helper mesos http client via vendor `andygrunwald/megos` which
 // only `GET` on mesos http endpoints, we only use it to obtain cluster's states quickly.
 func (s *Scheduler) megosClient() (*megos.Client, error) {
@@ -0,0 +1,238 @@
+package mesos
+
+import (
+	"sync"
+
+	"github.com/Dataman-Cloud/swan/mesos/filter"
+	"github.com/Dataman-Cloud/swan/mesosproto"
+	"github.com/Dataman-Cloud/swan/types"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// attrsCache memo the text attributes & hostname of the agents seen in offers,
+// unlike the scheduler agents, the agent is kept even without any offers, as
+// the placement constraints need the attributes of the agents running tasks.
+type attrsCache struct {
+	sync.RWMutex
+	m map[string]map[string]string
+}
+
+func newAttrsCache() *attrsCache {
+	return &attrsCache{
+		m: make(map[string]map[string]string),
+	}
+}
+
+func (c *attrsCache) update(offer *mesosproto.Offer) {
+	attrs := make(map[string]string)
+	for _, attr := range offer.GetAttributes() {
+		if attr.GetType() == mesosproto.Value_TEXT {
+			attrs[attr.GetName()] = attr.GetText().GetValue()
+		}
+	}
+	attrs["hostname"] = offer.GetHostname()
+
+	c.Lock()
+	c.m[offer.GetAgentId().GetValue()] = attrs
+	c.Unlock()
+}
+
+func (c *attrsCache) get(agentId string) (map[string]string, bool) {
+	c.RLock()
+	defer c.RUnlock()
+
+	attrs, ok := c.m[agentId]
+	return attrs, ok
+}
+
+// agentAttributes returns the attributes of the agent, the cache is refreshed
+// by mesos state if the agent hasn't been seen in offers, eg: after failover.
+func (s *Scheduler) agentAttributes(agentId string) map[string]string {
+	if attrs, ok := s.attrs.get(agentId); ok {
+		return attrs
+	}
+
+	state, err := s.MesosState()
+	if err != nil {
+		log.Errorf("get mesos state to refresh agent attributes error: %v", err)
+		return nil
+	}
+
+	s.attrs.Lock()
+	for _, slave := range state.Slaves {
+		if _, ok := s.attrs.m[slave.ID]; ok {
+			continue
+		}
+
+		attrs := make(map[string]string)
+		for k, v := range slave.Attributes {
+			if text, ok := v.(string); ok {
+				attrs[k] = text
+			}
+		}
+		attrs["hostname"] = slave.Hostname
+
+		s.attrs.m[slave.ID] = attrs
+	}
+	s.attrs.Unlock()
+
+	attrs, _ := s.attrs.get(agentId)
+	return attrs
+}
+
+// placement returns the attributes of the agents for each of the app's tasks
+// that already placed and not terminated.
+func (s *Scheduler) placement(appId string) []map[string]string {
+	placement := make([]map[string]string, 0)
+
+	tasks, err := s.db.ListTasks(appId)
+	if err != nil {
+		log.Errorf("list app %s tasks for placement error: %v", appId, err)
+		return placement
+	}
+
+	for _, task := range tasks {
+		if task.AgentId == "" || isTaskTerminated(task) {
+			continue
+		}
+
+		if attrs := s.agentAttributes(task.AgentId); attrs != nil {
+			placement = append(placement, attrs)
+		}
+	}
+
+	return placement
+}
+
+// appsSnapshot is the apps with their tasks & versions loaded once for each
+// launching, so the placement of the tasks doesn't scan the store each time.
+type appsSnapshot struct {
+	s    *Scheduler
+	once sync.Once
+	apps []*appTasks
+}
+
+type appTasks struct {
+	app      *types.Application
+	tasks    []*types.Task
+	versions map[string]*types.Version // version id -> version, of the current & the tasks
+}
+
+func (s *Scheduler) newAppsSnapshot() *appsSnapshot {
+	return &appsSnapshot{s: s}
+}
+
+// load lists the apps at the first call, which are shared by the later calls.
+func (ss *appsSnapshot) load() []*appTasks {
+	ss.once.Do(func() {
+		db := ss.s.db
+
+		apps, err := db.ListApps()
+		if err != nil {
+			log.Errorf("list apps for placement error: %v", err)
+			return
+		}
+
+		for _, app := range apps {
+			tasks, err := db.ListTasks(app.ID)
+			if err != nil {
+				log.Errorf("list app %s tasks for placement error: %v", app.ID, err)
+				continue
+			}
+
+			at := &appTasks{
+				app:      app,
+				tasks:    tasks,
+				versions: make(map[string]*types.Version),
+			}
+
+			verIds := make([]string, 0, len(tasks)+1)
+			if len(app.Version) > 0 {
+				verIds = append(verIds, app.Version[0])
+			}
+			for _, task := range tasks {
+				verIds = append(verIds, task.Version)
+			}
+
+			for _, id := range verIds {
+				if _, ok := at.versions[id]; ok {
+					continue
+				}
+
+				ver, err := db.GetVersion(app.ID, id)
+				if err != nil {
+					log.Errorf("get app %s version %s for placement error: %v", app.ID, id, err)
+				}
+				at.versions[id] = ver // nil if error
+			}
+
+			ss.apps = append(ss.apps, at)
+		}
+	})
+
+	return ss.apps
+}
+
+// current returns the current version of the app, nil if not found.
+func (at *appTasks) current() *types.Version {
+	if len(at.app.Version) == 0 {
+		return nil
+	}
+	return at.versions[at.app.Version[0]]
+}
+
+// affinityAgents resolves the agents running the tasks of the apps selected
+// by each of the affinity rules, the app itself is never selected.
+func (s *Scheduler) affinityAgents(ss *appsSnapshot, appId string, rules []*types.AffinityRule) []*filter.AffinityAgents {
+	ret := make([]*filter.AffinityAgents, 0, len(rules))
+	if len(rules) == 0 {
+		return ret
+	}
+
+	apps := ss.load()
+
+	for _, rule := range rules {
+		aff := &filter.AffinityAgents{
+			Rule:   rule,
+			Agents: make(map[string]bool),
+		}
+
+		for _, at := range apps {
+			if at.app.ID == appId {
+				continue
+			}
+
+			var appLabels map[string]string
+			if rule.Selector != "" {
+				ver := at.current()
+				if ver == nil {
+					continue
+				}
+				appLabels = ver.Labels
+			}
+
+			if !rule.Matches(at.app.ID, appLabels) {
+				continue
+			}
+
+			for _, task := range at.tasks {
+				if task.AgentId != "" && !isTaskTerminated(task) {
+					aff.Agents[task.AgentId] = true
+				}
+			}
+		}
+
+		ret = append(ret, aff)
+	}
+
+	return ret
+}
+
+func isTaskTerminated(task *types.Task) bool {
+	switch task.Status {
+	case "failed", "TASK_FINISHED", "TASK_FAILED", "TASK_KILLED", "TASK_LOST", "TASK_ERROR", "TASK_DROPPED", "TASK_GONE":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+package mesos
+
+import (
+	"github.com/Dataman-Cloud/swan/mesosproto"
+	"github.com/Dataman-Cloud/swan/types"
+)
+
+// allocateTask allocates the offered resources for the task, the pod instance
+// takes the resources of each of its containers & the executor.
+func allocateTask(pool *resourcePool, task *Task) error {
+	if !task.IsPod() {
+		resources, err := pool.allocate(task.Resources)
+		if err != nil {
+			return err
+		}
+		task.Resources = resources
+		return nil
+	}
+
+	resources, err := pool.allocate(task.executor.Resources)
+	if err != nil {
+		return err
+	}
+	task.executor.Resources = resources
+
+	for _, t := range task.group {
+		resources, err := pool.allocate(t.Resources)
+		if err != nil {
+			return err
+		}
+		t.Resources = resources
+	}
+
+	return nil
+}
+
+func (s *Scheduler) launchGroupOperation(task *Task) *mesosproto.Offer_Operation {
+	task.executor.FrameworkId = s.FrameworkId()
+	for _, t := range task.group {
+		t.AgentId = task.AgentId
+	}
+
+	return &mesosproto.Offer_Operation{
+		Type: mesosproto.Offer_Operation_LAUNCH_GROUP.Enum(),
+		LaunchGroup: &mesosproto.Offer_Operation_LaunchGroup{
+			Executor:  task.executor,
+			TaskGroup: &mesosproto.TaskGroupInfo{Tasks: task.group},
+		},
+	}
+}
+
+// podStatusIgnored tells whether the status of the pod container is ignored,
+// the pod instance follows the status of its first container, and the failure
+// of any other container, which fails the whole task group.
+func podStatusIgnored(status *mesosproto.TaskStatus) bool {
+	id := status.GetTaskId().GetValue()
+	if types.PodTaskId(id) == id {
+		return false
+	}
+
+	return DetectTaskError(status) == nil
+}
+
+// containerIP returns the ip of the container joined the CNI network.
+func containerIP(status *mesosproto.TaskStatus) string {
+	for _, info := range status.GetContainerStatus().GetNetworkInfos() {
+		for _, addr := range info.GetIpAddresses() {
+			if ip := addr.GetIpAddress(); ip != "" {
+				return ip
+			}
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,289 @@
+package mesos
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Dataman-Cloud/swan/mesos/filter"
+	"github.com/Dataman-Cloud/swan/types"
+	"github.com/Dataman-Cloud/swan/utils"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// the app with priority waits for offers for a while before preempting
+const preemptDelay = time.Second * 30
+
+// victim is a running task of a lower priority app, which could be evicted.
+type victim struct {
+	appId    string
+	task     *types.Task
+	ver      *types.Version
+	priority int
+}
+
+type victims []*victim
+
+func (v victims) Len() int      { return len(v) }
+func (v victims) Swap(i, j int) { v[i], v[j] = v[j], v[i] }
+
+// the lowest priority and the newest first
+func (v victims) Less(i, j int) bool {
+	if v[i].priority == v[j].priority {
+		return v[i].task.Created.After(v[j].task.Created)
+	}
+	return v[i].priority < v[j].priority
+}
+
+// preempt evicts the lower priority tasks on one agent to make room for the
+// app's tasks, the evicted tasks are returned to be requeued.
+func (s *Scheduler) preempt(appId string, opts *filter.FilterOptions, priority int) []*victim {
+	agentId, evicting := s.findVictims(appId, opts, priority)
+	if len(evicting) == 0 {
+		s.sendAppEvent(types.EventTypeAppPreemption, appId, "no lower priority tasks could be preempted", nil)
+		return nil
+	}
+
+	taskIds := make([]string, 0, len(evicting))
+	for _, v := range evicting {
+		taskIds = append(taskIds, v.task.ID)
+	}
+
+	log.Printf("app %s preempting %d tasks on agent %s: %v", appId, len(evicting), agentId, taskIds)
+	s.sendAppEvent(types.EventTypeAppPreemption, appId, fmt.Sprintf("preempt %d tasks on agent %s", len(evicting), agentId), map[string]interface{}{
+		"agent_id": agentId,
+		"tasks":    taskIds,
+		"priority": priority,
+	})
+
+	var (
+		wg      sync.WaitGroup
+		evicted = make([]*victim, 0, len(evicting))
+		mu      sync.Mutex
+	)
+
+	for _, v := range evicting {
+		wg.Add(1)
+
+		go func(v *victim) {
+			defer wg.Done()
+
+			var gracePeriod int64
+			if v.ver.KillPolicy != nil {
+				gracePeriod = v.ver.KillPolicy.Duration
+			}
+
+			if err := s.KillTask(v.task.ID, v.task.AgentId, gracePeriod); err != nil {
+				log.Errorf("preempt task %s error: %v", v.task.ID, err)
+				s.sendAppEvent(types.EventTypeTaskPreempted, v.appId, fmt.Sprintf("preempt task %s by app %s error: %v", v.task.ID, appId, err), nil)
+				return
+			}
+
+			if err := s.db.DeleteTask(v.task.ID); err != nil {
+				log.Errorf("delete preempted task %s error: %v", v.task.ID, err)
+			}
+
+			s.sendAppEvent(types.EventTypeTaskPreempted, v.appId, fmt.Sprintf("task %s preempted by app %s", v.task.ID, appId), map[string]interface{}{
+				"task_id":  v.task.ID,
+				"agent_id": v.task.AgentId,
+				"by":       appId,
+			})
+
+			mu.Lock()
+			evicted = append(evicted, v)
+			mu.Unlock()
+		}(v)
+	}
+
+	wg.Wait()
+
+	return evicted
+}
+
+// findVictims picks the agent with the fewest lower priority tasks to evict,
+// whose resources plus the agent's available resources fit the requirements.
+func (s *Scheduler) findVictims(appId string, opts *filter.FilterOptions, priority int) (string, []*victim) {
+	apps, err := s.db.ListApps()
+	if err != nil {
+		log.Errorf("list apps for preemption error: %v", err)
+		return "", nil
+	}
+
+	byAgent := make(map[string][]*victim)
+
+	for _, app := range apps {
+		// the apps in operation are left alone, the operation owns their tasks
+		if app.ID == appId || app.OpStatus != types.OpStatusNoop {
+			continue
+		}
+
+		tasks, err := s.db.ListTasks(app.ID)
+		if err != nil {
+			log.Errorf("list app %s tasks for preemption error: %v", app.ID, err)
+			continue
+		}
+
+		vers := make(map[string]*types.Version)
+		for _, task := range tasks {
+			if task.AgentId == "" || task.Status != "TASK_RUNNING" {
+				continue
+			}
+
+			ver, ok := vers[task.Version]
+			if !ok {
+				if ver, err = s.db.GetVersion(app.ID, task.Version); err != nil {
+					log.Errorf("get app %s version %s for preemption error: %v", app.ID, task.Version, err)
+				}
+				vers[task.Version] = ver
+			}
+
+			if ver == nil || ver.Priority >= priority {
+				continue
+			}
+
+			byAgent[task.AgentId] = append(byAgent[task.AgentId], &victim{
+				appId:    app.ID,
+				task:     task,
+				ver:      ver,
+				priority: ver.Priority,
+			})
+		}
+	}
+
+	var (
+		replicas = float64(opts.Replicas)
+		cpusReq  = opts.ResRequired.CPUs * replicas
+		gpusReq  = opts.ResRequired.GPUs * replicas
+		memReq   = opts.ResRequired.Mem * replicas
+		diskReq  = opts.ResRequired.Disk * replicas
+		portsReq = opts.ResRequired.NumPort * opts.Replicas
+
+		picked    string
+		pickedVcs []*victim
+	)
+
+	for agentId, vcs := range byAgent {
+		if !s.agentAcceptable(agentId, opts) {
+			continue
+		}
+
+		var (
+			cpus, gpus, mem, disk float64
+			ports                 int
+		)
+
+		if a := s.getAgent(agentId); a != nil {
+			var p []uint64
+			if opts.Usable != nil {
+				cpus, gpus, mem, disk, p = a.UsableResources(opts.Usable)
+			} else {
+				cpus, mem, disk, p = a.Resources()
+				gpus = a.GPUs()
+			}
+			ports = len(p)
+		}
+
+		sort.Sort(victims(vcs))
+
+		for n, v := range vcs {
+			cpus += v.ver.CPUs
+			gpus += v.ver.GPUs
+			mem += v.ver.Mem
+			disk += v.ver.Disk
+			ports += len(v.task.Ports)
+
+			if cpus < cpusReq || gpus < gpusReq || mem < memReq || disk < diskReq || ports < portsReq {
+				continue
+			}
+
+			if pickedVcs == nil || n+1 < len(pickedVcs) {
+				picked, pickedVcs = agentId, vcs[:n+1]
+			}
+			break
+		}
+	}
+
+	return picked, pickedVcs
+}
+
+// agentAcceptable checks the agent's attributes against the constraints & affinities,
+// the placement constraints are skipped as the evicted tasks change the placement.
+func (s *Scheduler) agentAcceptable(agentId string, opts *filter.FilterOptions) bool {
+	if opts.Unschedulable[agentId] {
+		return false
+	}
+
+	attrs := s.agentAttributes(agentId)
+
+	for _, c := range opts.Constraints {
+		if !c.Match(attrs) {
+			return false
+		}
+	}
+
+	for _, aff := range opts.Affinities {
+		running := aff.Agents[agentId]
+		if (aff.Rule.Type == types.Affinity && !running) || (aff.Rule.Type == types.AntiAffinity && running) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// requeue relaunches the evicted tasks with the same names, each of them
+// waits for offers independently.
+func (s *Scheduler) requeue(by string, evicted []*victim) {
+	for _, v := range evicted {
+		go func(v *victim) {
+			var (
+				name   = v.task.Name
+				id     = fmt.Sprintf("%s.%s", utils.RandomString(12), name)
+				idx, _ = strconv.Atoi(strings.SplitN(name, ".", 2)[0])
+			)
+
+			// the app may have been removed or taken by an operation since evicted
+			app, err := s.db.GetApp(v.appId)
+			if err == nil && app.OpStatus != types.OpStatusNoop {
+				err = fmt.Errorf("app status is %s", app.OpStatus)
+			}
+			if err != nil {
+				log.Warnf("skip requeuing preempted task %s: %v", v.task.ID, err)
+				s.sendAppEvent(types.EventTypeTaskRequeued, v.appId, fmt.Sprintf("task %s preempted by app %s not requeued: %v", v.task.ID, by, err), nil)
+				return
+			}
+
+			cfg := types.NewTaskConfig(v.ver, idx)
+			err = s.LaunchTasks([]*Task{NewTask(cfg, id, name)})
+
+			msg := fmt.Sprintf("task %s preempted by app %s requeued as %s", v.task.ID, by, id)
+			if err != nil {
+				msg = fmt.Sprintf("%s, launch error: %v", msg, err)
+				log.Errorf("requeue preempted task %s error: %v", v.task.ID, err)
+			}
+
+			s.sendAppEvent(types.EventTypeTaskRequeued, v.appId, msg, map[string]interface{}{
+				"task_id":  id,
+				"previous": v.task.ID,
+			})
+		}(v)
+	}
+}
+
+func (s *Scheduler) sendAppEvent(typ, appId, msg string, data interface{}) {
+	ev := &types.AppEvent{
+		Type:    typ,
+		AppID:   appId,
+		Message: msg,
+		Data:    data,
+		Time:    time.Now(),
+	}
+
+	if err := s.SendAppEvent(ev); err != nil {
+		log.Errorf("send app %s event %s error: %v", appId, typ, err)
+	}
+}
@@ -0,0 +1,156 @@
+package mesos
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Dataman-Cloud/swan/mesos/filter"
+	"github.com/Dataman-Cloud/swan/mesos/strategy"
+	"github.com/Dataman-Cloud/swan/types"
+)
+
+// ranking is the latest agents ranking of the app by its strategy.
+type ranking struct {
+	Strategy string                 `json:"strategy"`
+	Picked   string                 `json:"picked"` // agent id
+	Scores   []*strategy.AgentScore `json:"scores"`
+	Time     time.Time              `json:"time"`
+}
+
+type rankings struct {
+	sync.RWMutex
+	m map[string]*ranking // app id -> latest ranking
+}
+
+func (r *rankings) set(appId string, rk *ranking) {
+	r.Lock()
+	r.m[appId] = rk
+	r.Unlock()
+}
+
+func (r *rankings) get(appId string) *ranking {
+	r.RLock()
+	defer r.RUnlock()
+
+	return r.m[appId]
+}
+
+// newStrategy builds the manager default strategy by name.
+func newStrategy(name string) strategy.Strategy {
+	switch name {
+	case types.StrategyRandom:
+		return strategy.NewRandomStrategy()
+	case types.StrategySpread:
+		return strategy.NewSpreadStrategy()
+	case types.StrategyWeighted:
+		return strategy.NewWeightedStrategy([]*strategy.WeightedScorer{
+			{Scorer: strategy.NewLeastAllocatedScorer(), Weight: 1},
+		})
+	}
+
+	return strategy.NewBinPackStrategy()
+}
+
+// strategyFor returns the strategy specified by the task config and its name,
+// or the manager default strategy if not specified.
+func (s *Scheduler) strategyFor(ss *appsSnapshot, cfg *types.TaskConfig) (strategy.Strategy, string) {
+	spec := cfg.Strategy
+	if spec == nil {
+		return s.strategy, s.cfg.Strategy
+	}
+
+	if spec.Name != types.StrategyWeighted {
+		return newStrategy(spec.Name), spec.Name
+	}
+
+	scorers := make([]*strategy.WeightedScorer, 0, len(spec.Scorers))
+	for _, sc := range spec.Scorers {
+		var scorer strategy.Scorer
+
+		switch sc.Name {
+		case types.ScorerLeastAllocated:
+			scorer = strategy.NewLeastAllocatedScorer()
+		case types.ScorerMostAllocated:
+			scorer = strategy.NewMostAllocatedScorer()
+		case types.ScorerAttribute:
+			scorer = strategy.NewAttributeScorer(sc.Attribute, sc.Value)
+		case types.ScorerImageLocality:
+			scorer = strategy.NewImageLocalityScorer(s.imageAgents(ss, cfg.Image))
+		default:
+			continue
+		}
+
+		scorers = append(scorers, &strategy.WeightedScorer{Scorer: scorer, Weight: sc.Weight})
+	}
+
+	return strategy.NewWeightedStrategy(scorers), spec.Name
+}
+
+// imageAgents returns the agents have run the tasks of the image.
+func (s *Scheduler) imageAgents(ss *appsSnapshot, image string) map[string]bool {
+	ret := make(map[string]bool)
+
+	for _, at := range ss.load() {
+		for _, task := range at.tasks {
+			if task.AgentId == "" {
+				continue
+			}
+
+			ver := at.versions[task.Version]
+			if ver != nil && ver.Container != nil && ver.Container.Docker != nil && ver.Container.Docker.Image == image {
+				ret[task.AgentId] = true
+			}
+		}
+	}
+
+	return ret
+}
+
+// filterOptions builds the filter options to launch replicas tasks of the app,
+// the affinities are resolved by the apps snapshot of the launching.
+func (s *Scheduler) filterOptions(ss *appsSnapshot, appId string, cfg *types.TaskConfig, replicas int) *filter.FilterOptions {
+	return &filter.FilterOptions{
+		ResRequired: cfg.ResourcesRequired(),
+		Replicas:    replicas,
+		Usable:      s.usable(appId, cfg),
+		Constraints: cfg.Constraints,
+		Placement:   s.placement(appId),
+		Affinities:  s.affinityAgents(ss, appId, cfg.Affinities),
+
+		Unschedulable: s.unschedulableAgents(),
+	}
+}
+
+// OfferScores explains the current agents ranking for the app, by the
+// strategy of the app's current version.
+func (s *Scheduler) OfferScores(appId string) (interface{}, error) {
+	app, err := s.db.GetApp(appId)
+	if err != nil {
+		return nil, err
+	}
+
+	ver, err := s.db.GetVersion(appId, app.Version[0])
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		cfg        = types.NewTaskConfig(ver, 0)
+		snapshot   = s.newAppsSnapshot()
+		strat, typ = s.strategyFor(snapshot, cfg)
+		ret        = map[string]interface{}{
+			"strategy": typ,
+			"latest":   s.rankings.get(appId),
+		}
+	)
+
+	agents, err := filter.ApplyFilters(s.filters, s.filterOptions(snapshot, appId, cfg, 1), s.getAgents())
+	if err != nil {
+		ret["error"] = err.Error()
+		return ret, nil
+	}
+
+	ret["scores"] = strategy.Scores(strat, agents)
+
+	return ret, nil
+}
@@ -0,0 +1,153 @@
+package mesos
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Dataman-Cloud/swan/types"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// Reconciliation compares the tasks in the store with the active tasks of the
+// framework on the mesos master. the pending tasks not launched yet and the
+// terminated tasks are skipped. the tasks on the unreachable agents are not
+// missing, as they may come back once the agents reregistered.
+func (s *Scheduler) Reconciliation() (*types.ReconcileReport, error) {
+	fw, unreachableTasks, unreachableAgents, err := s.frameworkReconcileState()
+	if err != nil {
+		return nil, fmt.Errorf("get framework state error: %v", err)
+	}
+
+	apps, err := s.db.ListApps()
+	if err != nil {
+		return nil, fmt.Errorf("list apps error: %v", err)
+	}
+
+	var (
+		active      = make(map[string]bool)
+		stored      = make(map[string]bool)
+		unreachable = make(map[string]bool)
+		report      = &types.ReconcileReport{
+			Time:        time.Now(),
+			Missing:     make([]*types.ReconcileTask, 0),
+			Orphans:     make([]*types.ReconcileTask, 0),
+			Unreachable: make([]*types.ReconcileTask, 0),
+		}
+	)
+
+	for _, t := range fw.Tasks {
+		active[t.ID] = true
+	}
+
+	for _, t := range unreachableTasks {
+		unreachable[t.ID] = true
+	}
+
+	for _, app := range apps {
+		tasks, err := s.db.ListTasks(app.ID)
+		if err != nil {
+			return nil, fmt.Errorf("list app %s tasks error: %v", app.ID, err)
+		}
+
+		for _, task := range tasks {
+			stored[task.ID] = true
+
+			if active[task.ID] || task.AgentId == "" || isTaskTerminated(task) || s.getPendingTask(task.ID) != nil {
+				continue
+			}
+
+			t := &types.ReconcileTask{
+				ID:      task.ID,
+				Name:    task.Name,
+				AppID:   app.ID,
+				AgentID: task.AgentId,
+				State:   task.Status,
+			}
+
+			if unreachable[task.ID] || unreachableAgents[task.AgentId] || task.Status == "TASK_UNREACHABLE" {
+				report.Unreachable = append(report.Unreachable, t)
+				continue
+			}
+
+			report.Missing = append(report.Missing, t)
+		}
+	}
+
+	for _, t := range fw.Tasks {
+		if stored[types.PodTaskId(t.ID)] {
+			continue
+		}
+
+		report.Orphans = append(report.Orphans, &types.ReconcileTask{
+			ID:      t.ID,
+			Name:    t.Name,
+			AppID:   appIdOf(t.ID),
+			AgentID: t.SlaveID,
+			State:   t.State,
+		})
+	}
+
+	return report, nil
+}
+
+// RepairTask takes the repair action on the task of the current report, the
+// missing task could be relaunched or dropped, the orphan task could be killed.
+func (s *Scheduler) RepairTask(repair *types.ReconcileRepair) error {
+	report, err := s.Reconciliation()
+	if err != nil {
+		return err
+	}
+
+	if repair.Action == types.RepairKill {
+		t := findReconcileTask(report.Orphans, repair.TaskID)
+		if t == nil {
+			return fmt.Errorf("task %s is not an orphan task", repair.TaskID)
+		}
+
+		log.Printf("Killing orphan task %s on agent %s", t.ID, t.AgentID)
+
+		return s.KillTask(t.ID, t.AgentID, 0)
+	}
+
+	t := findReconcileTask(report.Missing, repair.TaskID)
+	if t == nil {
+		return fmt.Errorf("task %s is not missing on mesos", repair.TaskID)
+	}
+
+	task, err := s.db.GetTask(t.AppID, t.ID)
+	if err != nil {
+		return fmt.Errorf("get task %s error: %v", t.ID, err)
+	}
+
+	log.Printf("Repairing missing task %s by %s", t.ID, repair.Action)
+
+	if err := s.db.DeleteTask(t.ID); err != nil {
+		return fmt.Errorf("delete task %s error: %v", t.ID, err)
+	}
+
+	if repair.Action == types.RepairRelaunch {
+		go s.rescheduleTask(t.AppID, task)
+	}
+
+	return nil
+}
+
+func findReconcileTask(tasks []*types.ReconcileTask, id string) *types.ReconcileTask {
+	for _, t := range tasks {
+		if t.ID == id {
+			return t
+		}
+	}
+	return nil
+}
+
+// appIdOf extracts the app id from the task id formatted as: {random}.{index}.{app id}
+func appIdOf(taskId string) string {
+	parts := strings.SplitN(taskId, ".", 3)
+	if len(parts) < 3 {
+		return ""
+	}
+	return parts[2]
+}
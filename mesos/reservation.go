@@ -0,0 +1,496 @@
+package mesos
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync/atomic"
+
+	"github.com/golang/protobuf/proto"
+
+	magent "github.com/Dataman-Cloud/swan/mesos/agent"
+	"github.com/Dataman-Cloud/swan/mesosproto"
+	"github.com/Dataman-Cloud/swan/types"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+const (
+	defaultRole = "*"
+
+	// label of the dynamically reserved resources, to mark the app they're reserved for
+	reservationLabel = "SWAN_APP_ID"
+)
+
+// checkRole verifies the role of the task config against the framework role.
+func (s *Scheduler) checkRole(cfg *types.TaskConfig) error {
+	role := s.framework.GetRole()
+
+	if cfg.Role != "" && cfg.Role != defaultRole && cfg.Role != role {
+		return fmt.Errorf("role %s mismatched with the framework role %s", cfg.Role, role)
+	}
+
+	if cfg.Reserve && role == defaultRole {
+		return errors.New("can't reserve resources without the framework role")
+	}
+
+	if len(cfg.PersistentVolumes()) > 0 && role == defaultRole {
+		return errors.New("can't create persistent volumes without the framework role")
+	}
+
+	return nil
+}
+
+// usable returns the func to tell whether the offered resource could be used
+// by the app's tasks:
+// - the resources reserved for other apps are never used.
+// - the unreserved resources are used if the role not specified or `*`, or to be reserved.
+// - the resources reserved for the framework role are used if the role not specified or the same.
+func (s *Scheduler) usable(appId string, cfg *types.TaskConfig) func(*mesosproto.Resource) bool {
+	return func(r *mesosproto.Resource) bool {
+		if r.GetRevocable() != nil || r.GetDisk() != nil {
+			return false
+		}
+
+		if app := reservedApp(r); app != "" && app != appId {
+			return false
+		}
+
+		if r.GetRole() == defaultRole {
+			return cfg.Role == "" || cfg.Role == defaultRole || cfg.Reserve
+		}
+
+		return cfg.Role == "" || cfg.Role == r.GetRole()
+	}
+}
+
+// reservedApp returns the app which the resource reserved for dynamically.
+func reservedApp(r *mesosproto.Resource) string {
+	for _, label := range r.GetReservation().GetLabels().GetLabels() {
+		if label.GetKey() == reservationLabel {
+			return label.GetValue()
+		}
+	}
+
+	return ""
+}
+
+// resourcePool holds the usable resources of the offers, allocates them to
+// the tasks with proper roles & reservations.
+type resourcePool struct {
+	scalars []*scalarResource
+	ports   []*portResource
+	disks   []*scalarResource               // to create persistent volumes
+	volumes map[string]*mesosproto.Resource // persistence id -> offered volume
+
+	// to reserve the allocated unreserved resources
+	reserve     bool
+	role        string
+	reservation *mesosproto.Resource_ReservationInfo
+
+	reserving []*mesosproto.Resource // to be reserved before launching
+	creating  []*mesosproto.Resource // persistent volumes to be created before launching
+}
+
+type scalarResource struct {
+	offered *mesosproto.Resource
+	remain  float64
+}
+
+type portResource struct {
+	offered *mesosproto.Resource
+	port    uint64
+}
+
+func (s *Scheduler) newResourcePool(appId string, cfg *types.TaskConfig, offers []*magent.Offer) *resourcePool {
+	var (
+		usable       = s.usable(appId, cfg)
+		volumeUsable = s.volumeDiskUsable(appId)
+		pool         = &resourcePool{
+			volumes: make(map[string]*mesosproto.Resource),
+			reserve: cfg.Reserve,
+			role:    s.framework.GetRole(),
+			reservation: &mesosproto.Resource_ReservationInfo{
+				Principal: proto.String(s.framework.GetPrincipal()),
+				Labels: &mesosproto.Labels{
+					Labels: []*mesosproto.Label{
+						{Key: proto.String(reservationLabel), Value: proto.String(appId)},
+					},
+				},
+			},
+		}
+	)
+
+	for _, offer := range offers {
+		for _, r := range offer.GetResources() {
+			if id := r.GetDisk().GetPersistence().GetId(); id != "" && reservedApp(r) == appId {
+				pool.volumes[id] = r
+				continue
+			}
+
+			if volumeUsable(r) {
+				sr := &scalarResource{r, r.GetScalar().GetValue()}
+				pool.disks = append(pool.disks, sr)
+
+				// shared with the scalars, to be allocated once
+				if usable(r) {
+					pool.scalars = append(pool.scalars, sr)
+				}
+				continue
+			}
+
+			if !usable(r) {
+				continue
+			}
+
+			if r.GetName() == "ports" {
+				for _, rg := range r.GetRanges().GetRange() {
+					for i := rg.GetBegin(); i <= rg.GetEnd(); i++ {
+						pool.ports = append(pool.ports, &portResource{r, i})
+					}
+				}
+				continue
+			}
+
+			if r.GetScalar() != nil {
+				pool.scalars = append(pool.scalars, &scalarResource{r, r.GetScalar().GetValue()})
+			}
+		}
+	}
+
+	// the reserved resources are allocated first
+	sort.Stable(scalarResources(pool.scalars))
+	sort.Stable(scalarResources(pool.disks))
+	sort.Stable(portResources(pool.ports))
+
+	return pool
+}
+
+type scalarResources []*scalarResource
+
+func (s scalarResources) Len() int      { return len(s) }
+func (s scalarResources) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s scalarResources) Less(i, j int) bool {
+	return reservedRank(s[i].offered) < reservedRank(s[j].offered)
+}
+
+type portResources []*portResource
+
+func (s portResources) Len() int      { return len(s) }
+func (s portResources) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s portResources) Less(i, j int) bool {
+	return reservedRank(s[i].offered) < reservedRank(s[j].offered)
+}
+
+// the resources reserved for the app, then the role, finally the unreserved
+func reservedRank(r *mesosproto.Resource) int {
+	switch {
+	case reservedApp(r) != "":
+		return 0
+	case r.GetRole() != defaultRole:
+		return 1
+	}
+	return 2
+}
+
+// Ports returns the usable ports, the reserved ones first.
+func (p *resourcePool) Ports() []uint64 {
+	ports := make([]uint64, 0, len(p.ports))
+	for _, pr := range p.ports {
+		ports = append(ports, pr.port)
+	}
+	return ports
+}
+
+// allocate splits the required resources of the task into the offered ones
+// with the roles & reservations, the allocated unreserved resources are to be
+// reserved before launching if the pool is reserving.
+func (p *resourcePool) allocate(required []*mesosproto.Resource) ([]*mesosproto.Resource, error) {
+	allocated := make([]*mesosproto.Resource, 0, len(required))
+
+	add := func(offered *mesosproto.Resource, r *mesosproto.Resource) {
+		r.Role = proto.String(offered.GetRole())
+		r.Reservation = offered.GetReservation()
+
+		if p.reserve && offered.GetRole() == defaultRole {
+			r.Role = proto.String(p.role)
+			r.Reservation = p.reservation
+			p.reserving = append(p.reserving, r)
+		}
+
+		allocated = append(allocated, r)
+	}
+
+	for _, req := range required {
+		if req.GetName() == "ports" {
+			for _, rg := range req.GetRanges().GetRange() {
+				for i := rg.GetBegin(); i <= rg.GetEnd(); i++ {
+					pr := p.port(i)
+					if pr == nil {
+						return nil, fmt.Errorf("port %d not offered", i)
+					}
+
+					add(pr.offered, &mesosproto.Resource{
+						Name: proto.String("ports"),
+						Type: mesosproto.Value_RANGES.Enum(),
+						Ranges: &mesosproto.Value_Ranges{
+							Range: []*mesosproto.Value_Range{{Begin: proto.Uint64(i), End: proto.Uint64(i)}},
+						},
+					})
+				}
+			}
+			continue
+		}
+
+		need := req.GetScalar().GetValue()
+		for _, sr := range p.scalars {
+			if need <= 0 {
+				break
+			}
+
+			if sr.offered.GetName() != req.GetName() || sr.remain <= 0 {
+				continue
+			}
+
+			v := need
+			if sr.remain < v {
+				v = sr.remain
+			}
+			sr.remain -= v
+			need -= v
+
+			add(sr.offered, &mesosproto.Resource{
+				Name:   proto.String(req.GetName()),
+				Type:   mesosproto.Value_SCALAR.Enum(),
+				Scalar: &mesosproto.Value_Scalar{Value: proto.Float64(v)},
+			})
+		}
+
+		if need > 0.0001 {
+			return nil, fmt.Errorf("%s not enough", req.GetName())
+		}
+	}
+
+	return allocated, nil
+}
+
+// port takes the offered port out of the pool.
+func (p *resourcePool) port(port uint64) *portResource {
+	for i, pr := range p.ports {
+		if pr.port == port {
+			p.ports = append(p.ports[:i], p.ports[i+1:]...)
+			return pr
+		}
+	}
+	return nil
+}
+
+// unreserveStale releases the resources in the offer reserved for the apps
+// which have gone or no longer reserve, the persistent volumes are destroyed
+// before unreserving. the offer is consumed if so.
+func (s *Scheduler) unreserveStale(offer *mesosproto.Offer) bool {
+	var (
+		destroying = make([]*mesosproto.Resource, 0)
+		stale      = make([]*mesosproto.Resource, 0)
+		apps       = make(map[string]*appReservation)
+	)
+
+	for _, r := range offer.GetResources() {
+		appId := reservedApp(r)
+		if appId == "" {
+			continue
+		}
+
+		ar, ok := apps[appId]
+		if !ok {
+			ar = s.appReservation(appId)
+			apps[appId] = ar
+		}
+
+		if ar.reserve || (ar.volumes && r.GetName() == "disk") {
+			continue
+		}
+
+		if r.GetDisk().GetPersistence() != nil {
+			destroying = append(destroying, r)
+
+			// the disk is still reserved after the volume destroyed
+			r = proto.Clone(r).(*mesosproto.Resource)
+			r.Disk = nil
+		}
+
+		stale = append(stale, r)
+	}
+
+	if len(stale) == 0 {
+		return false
+	}
+
+	for appId, ar := range apps {
+		if !ar.reserve {
+			s.markStale(appId)
+		}
+	}
+
+	operations := make([]*mesosproto.Offer_Operation, 0)
+	if len(destroying) > 0 {
+		operations = append(operations, &mesosproto.Offer_Operation{
+			Type:    mesosproto.Offer_Operation_DESTROY.Enum(),
+			Destroy: &mesosproto.Offer_Operation_Destroy{Volumes: destroying},
+		})
+	}
+
+	operations = append(operations, &mesosproto.Offer_Operation{
+		Type:      mesosproto.Offer_Operation_UNRESERVE.Enum(),
+		Unreserve: &mesosproto.Offer_Operation_Unreserve{Resources: stale},
+	})
+
+	call := &mesosproto.Call{
+		FrameworkId: s.FrameworkId(),
+		Type:        mesosproto.Call_ACCEPT.Enum(),
+		Accept: &mesosproto.Call_Accept{
+			OfferIds:   []*mesosproto.OfferID{offer.GetId()},
+			Operations: operations,
+			Filters:    &mesosproto.Filters{RefuseSeconds: proto.Float64(1)},
+		},
+	}
+
+	log.Printf("Destroying %d volume(s) & unreserving %d resource(s) on agent %s", len(destroying), len(stale), offer.GetHostname())
+
+	if _, err := s.SendCall(call, http.StatusAccepted); err != nil {
+		log.Errorf("unreserve resources on agent %s error: %v", offer.GetHostname(), err)
+		return false
+	}
+
+	atomic.AddInt64(&s.counters.used, 1)
+
+	for _, r := range destroying {
+		s.destroyVolume(r)
+	}
+
+	return true
+}
+
+type appReservation struct {
+	reserve bool // keep all of the reserved resources
+	volumes bool // keep the reserved disk & persistent volumes
+}
+
+// appReservation tells whether the app's current version still reserve the
+// resources or has persistent volumes, it's treated as reserving unless the
+// app is surely gone.
+func (s *Scheduler) appReservation(appId string) *appReservation {
+	keep := &appReservation{true, true}
+
+	app, err := s.db.GetApp(appId)
+	if err != nil {
+		if s.db.IsErrNotFound(err) {
+			return &appReservation{}
+		}
+		return keep
+	}
+
+	if len(app.Version) == 0 {
+		return keep
+	}
+
+	ver, err := s.db.GetVersion(appId, app.Version[0])
+	if err != nil {
+		return keep
+	}
+
+	return &appReservation{
+		reserve: ver.Reserve,
+		volumes: len(ver.PersistentVolumes()) > 0,
+	}
+}
+
+// Quota shows the framework role with the quota guarantee of the role and
+// the reserved resources in the holding offers.
+func (s *Scheduler) Quota() (interface{}, error) {
+	var (
+		role     = s.framework.GetRole()
+		reserved = make(map[string]float64)
+		apps     = make(map[string]map[string]float64) // reserved for each app
+	)
+
+	for _, a := range s.getAgents() {
+		for _, offer := range a.GetOffers() {
+			for _, r := range offer.GetResources() {
+				if r.GetRole() == defaultRole || r.GetScalar() == nil {
+					continue
+				}
+
+				reserved[r.GetName()] += r.GetScalar().GetValue()
+
+				if app := reservedApp(r); app != "" {
+					if _, ok := apps[app]; !ok {
+						apps[app] = make(map[string]float64)
+					}
+					apps[app][r.GetName()] += r.GetScalar().GetValue()
+				}
+			}
+		}
+	}
+
+	ret := map[string]interface{}{
+		"role":     role,
+		"reserved": reserved,
+		"apps":     apps,
+	}
+
+	if role == defaultRole {
+		return ret, nil
+	}
+
+	guarantee, err := s.roleQuota(role)
+	if err != nil {
+		return nil, err
+	}
+	ret["guarantee"] = guarantee
+
+	return ret, nil
+}
+
+// roleQuota query the quota guarantee of the role on mesos master.
+func (s *Scheduler) roleQuota(role string) (map[string]float64, error) {
+	resp, err := http.Get("http://" + s.leader + "/quota")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if code := resp.StatusCode; code != http.StatusOK {
+		return nil, fmt.Errorf("get mesos quota with unexpected response [%d]", code)
+	}
+
+	var status struct {
+		Infos []struct {
+			Role      string `json:"role"`
+			Guarantee []struct {
+				Name   string `json:"name"`
+				Scalar struct {
+					Value float64 `json:"value"`
+				} `json:"scalar"`
+			} `json:"guarantee"`
+		} `json:"infos"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return nil, err
+	}
+
+	ret := make(map[string]float64)
+	for _, info := range status.Infos {
+		if info.Role != role {
+			continue
+		}
+		for _, g := range info.Guarantee {
+			ret[g.Name] += g.Scalar.Value
+		}
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,170 @@
+package mesos
+
+import (
+	"time"
+
+	"github.com/Dataman-Cloud/swan/types"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// taskRestartPolicy returns the restart policy of the task's version, nil for
+// the default policy.
+func (s *Scheduler) taskRestartPolicy(appId string, task *types.Task) *types.RestartPolicy {
+	ver, err := s.db.GetVersion(appId, task.Version)
+	if err != nil {
+		log.Errorf("get task %s version for restart policy error: %v", task.ID, err)
+		return nil
+	}
+
+	return ver.RestartPolicy
+}
+
+// waitRelaunch waits until the scheduled launch of the retrying task, and
+// returns the task's version reloaded by then. false if the task has gone
+// meanwhile, eg: the app was deleted or scaled down, or killed by the stop.
+func (s *Scheduler) waitRelaunch(appId string, task *types.Task, stop <-chan struct{}) (*types.Version, bool) {
+	defer s.unwatchRelaunch(task.ID)
+
+	var d time.Duration
+	if task.NextLaunch != nil {
+		d = task.NextLaunch.Sub(time.Now())
+	}
+
+	if d > 0 {
+		log.Printf("Task %s will be relaunched in %s", task.Name, d)
+
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+		case <-stop:
+			return nil, false
+		}
+	}
+
+	// killed right before the overdue relaunch
+	select {
+	case <-stop:
+		return nil, false
+	default:
+	}
+
+	t, err := s.db.GetTask(appId, task.ID)
+	if err != nil {
+		if !s.db.IsErrNotFound(err) {
+			log.Errorf("get retrying task %s error: %v", task.ID, err)
+		}
+		return nil, false
+	}
+
+	ver, err := s.db.GetVersion(appId, t.Version)
+	if err != nil {
+		log.Errorf("get retrying task %s version %s error: %v", task.ID, t.Version, err)
+		return nil, false
+	}
+
+	// not retrying any more, so it won't be relaunched again on resume
+	t.Status = "pending"
+	t.NextLaunch = nil
+	t.Updated = time.Now()
+	if err := s.db.UpdateTask(appId, t); err != nil {
+		log.Errorf("update retrying task %s error: %v", task.ID, err)
+		return nil, false
+	}
+
+	return ver, true
+}
+
+// retryingTasks returns the retrying tasks of all apps by app id, excluding
+// the ones already waiting for relaunch.
+func (s *Scheduler) retryingTasks() (map[string][]*types.Task, error) {
+	apps, err := s.db.ListApps()
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make(map[string][]*types.Task)
+	for _, app := range apps {
+		tasks, err := s.db.ListTasks(app.ID)
+		if err != nil {
+			log.Errorf("list app %s tasks for relaunch error: %v", app.ID, err)
+			continue
+		}
+
+		for _, t := range tasks {
+			if t.Status == "retrying" && !s.isRelaunching(t.ID) {
+				ret[app.ID] = append(ret[app.ID], t)
+			}
+		}
+	}
+
+	return ret, nil
+}
+
+// resumeRelaunches re-arms the relaunch of the retrying tasks, whose backoff
+// was lost by the scheduler restart or the leader failover. the tasks overdue
+// are relaunched right away.
+func (s *Scheduler) resumeRelaunches() {
+	retrying, err := s.retryingTasks()
+	if err != nil {
+		log.Errorf("list retrying tasks for relaunch error: %v", err)
+		return
+	}
+
+	for appId, tasks := range retrying {
+		for _, t := range tasks {
+			stop, ok := s.watchRelaunch(t.ID)
+			if !ok {
+				continue
+			}
+
+			log.Printf("Resume the relaunch of retrying task %s", t.ID)
+			go s.relaunchTask(appId, t, stop)
+		}
+	}
+}
+
+// watchRelaunch returns the channel closed once the retrying task is killed,
+// false if the task is already waiting for relaunch.
+func (s *Scheduler) watchRelaunch(taskId string) (<-chan struct{}, bool) {
+	s.relaunchMu.Lock()
+	defer s.relaunchMu.Unlock()
+
+	if _, ok := s.relaunching[taskId]; ok {
+		return nil, false
+	}
+
+	stop := make(chan struct{})
+	s.relaunching[taskId] = stop
+	return stop, true
+}
+
+func (s *Scheduler) isRelaunching(taskId string) bool {
+	s.relaunchMu.Lock()
+	defer s.relaunchMu.Unlock()
+
+	_, ok := s.relaunching[taskId]
+	return ok
+}
+
+func (s *Scheduler) unwatchRelaunch(taskId string) {
+	s.relaunchMu.Lock()
+	delete(s.relaunching, taskId)
+	s.relaunchMu.Unlock()
+}
+
+// cancelRelaunch stops the relaunch of the retrying task, false if the task is
+// not waiting for relaunch.
+func (s *Scheduler) cancelRelaunch(taskId string) bool {
+	s.relaunchMu.Lock()
+	defer s.relaunchMu.Unlock()
+
+	stop, ok := s.relaunching[taskId]
+	if ok {
+		close(stop)
+		delete(s.relaunching, taskId)
+	}
+	return ok
+}
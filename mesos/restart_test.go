@@ -0,0 +1,224 @@
+package mesos
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Dataman-Cloud/swan/store"
+	"github.com/Dataman-Cloud/swan/types"
+)
+
+var errNotFound = errors.New("not found")
+
+// fakeStore keeps the apps, tasks & versions in memory, the other methods
+// are not implemented.
+type fakeStore struct {
+	store.Store
+
+	sync.Mutex
+	tasks    map[string][]*types.Task // app id -> tasks
+	versions map[string]*types.Version
+}
+
+func (fs *fakeStore) ListApps() ([]*types.Application, error) {
+	fs.Lock()
+	defer fs.Unlock()
+
+	apps := make([]*types.Application, 0, len(fs.tasks))
+	for id := range fs.tasks {
+		apps = append(apps, &types.Application{ID: id})
+	}
+	return apps, nil
+}
+
+func (fs *fakeStore) ListTasks(aid string) ([]*types.Task, error) {
+	fs.Lock()
+	defer fs.Unlock()
+
+	ret := make([]*types.Task, 0, len(fs.tasks[aid]))
+	for _, t := range fs.tasks[aid] {
+		cp := *t
+		ret = append(ret, &cp)
+	}
+	return ret, nil
+}
+
+func (fs *fakeStore) GetTask(aid, tid string) (*types.Task, error) {
+	fs.Lock()
+	defer fs.Unlock()
+
+	for _, t := range fs.tasks[aid] {
+		if t.ID == tid {
+			cp := *t
+			return &cp, nil
+		}
+	}
+	return nil, errNotFound
+}
+
+func (fs *fakeStore) UpdateTask(aid string, task *types.Task) error {
+	fs.Lock()
+	defer fs.Unlock()
+
+	for i, t := range fs.tasks[aid] {
+		if t.ID == task.ID {
+			cp := *task
+			fs.tasks[aid][i] = &cp
+			return nil
+		}
+	}
+	return errNotFound
+}
+
+func (fs *fakeStore) GetVersion(aid, vid string) (*types.Version, error) {
+	fs.Lock()
+	defer fs.Unlock()
+
+	if v, ok := fs.versions[vid]; ok {
+		return v, nil
+	}
+	return nil, errNotFound
+}
+
+func (fs *fakeStore) IsErrNotFound(err error) bool {
+	return err == errNotFound
+}
+
+func newRestartScheduler(tasks map[string][]*types.Task) (*Scheduler, *fakeStore) {
+	fs := &fakeStore{
+		tasks:    tasks,
+		versions: map[string]*types.Version{"v1": {ID: "v1"}},
+	}
+
+	return &Scheduler{
+		db:          fs,
+		relaunching: make(map[string]chan struct{}),
+	}, fs
+}
+
+func TestWaitRelaunch(t *testing.T) {
+	tests := []struct {
+		name     string
+		task     *types.Task
+		delay    time.Duration // of the next launch
+		stop     bool
+		want     bool
+		minDelay time.Duration
+	}{
+		{
+			name:  "overdue relaunched at once",
+			task:  &types.Task{ID: "t1", Status: "retrying", Version: "v1"},
+			delay: -time.Hour,
+			want:  true,
+		},
+		{
+			name:     "wait until the next launch",
+			task:     &types.Task{ID: "t1", Status: "retrying", Version: "v1"},
+			delay:    time.Millisecond * 50,
+			want:     true,
+			minDelay: time.Millisecond * 40,
+		},
+		{
+			name:  "stopped",
+			task:  &types.Task{ID: "t1", Status: "retrying", Version: "v1"},
+			delay: time.Hour,
+			stop:  true,
+		},
+		{
+			name:  "stopped when overdue",
+			task:  &types.Task{ID: "t1", Status: "retrying", Version: "v1"},
+			delay: -time.Hour,
+			stop:  true,
+		},
+		{
+			name:  "version gone",
+			task:  &types.Task{ID: "t1", Status: "retrying", Version: "v2"},
+			delay: -time.Hour,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := time.Now().Add(tt.delay)
+			tt.task.NextLaunch = &next
+
+			s, fs := newRestartScheduler(map[string][]*types.Task{"app1": {tt.task}})
+
+			stop, _ := s.watchRelaunch(tt.task.ID)
+			if tt.stop {
+				s.cancelRelaunch(tt.task.ID)
+			}
+
+			start := time.Now()
+			ver, ok := s.waitRelaunch("app1", tt.task, stop)
+			if ok != tt.want {
+				t.Fatalf("waitRelaunch() = %v, want %v", ok, tt.want)
+			}
+			if s.isRelaunching(tt.task.ID) {
+				t.Errorf("task %s still watched after the wait", tt.task.ID)
+			}
+			if !ok {
+				return
+			}
+
+			if elapsed := time.Since(start); elapsed < tt.minDelay {
+				t.Errorf("waited %s, want at least %s", elapsed, tt.minDelay)
+			}
+			if ver == nil || ver.ID != "v1" {
+				t.Errorf("waitRelaunch() version = %v, want v1", ver)
+			}
+
+			got, _ := fs.GetTask("app1", tt.task.ID)
+			if got.Status != "pending" || got.NextLaunch != nil {
+				t.Errorf("task status = %s, next launch = %v, want pending without next launch", got.Status, got.NextLaunch)
+			}
+		})
+	}
+}
+
+func TestResumeRelaunches(t *testing.T) {
+	later := time.Now().Add(time.Hour)
+
+	s, _ := newRestartScheduler(map[string][]*types.Task{
+		"app1": {
+			{ID: "t1", Status: "retrying", Version: "v1", NextLaunch: &later},
+			{ID: "t2", Status: "TASK_RUNNING", Version: "v1"},
+		},
+		"app2": {
+			{ID: "t3", Status: "retrying", Version: "v1", NextLaunch: &later},
+		},
+	})
+
+	retrying, err := s.retryingTasks()
+	if err != nil {
+		t.Fatalf("retryingTasks() error = %v", err)
+	}
+	if len(retrying["app1"]) != 1 || retrying["app1"][0].ID != "t1" || len(retrying["app2"]) != 1 {
+		t.Fatalf("retryingTasks() = %v, want t1 of app1 & t3 of app2", retrying)
+	}
+
+	s.resumeRelaunches()
+
+	for _, id := range []string{"t1", "t3"} {
+		if !s.isRelaunching(id) {
+			t.Errorf("relaunch of task %s not resumed", id)
+		}
+	}
+	if s.isRelaunching("t2") {
+		t.Errorf("running task t2 shouldn't be relaunched")
+	}
+
+	// the tasks waiting for relaunch are not resumed twice
+	if retrying, _ := s.retryingTasks(); len(retrying) != 0 {
+		t.Errorf("retryingTasks() = %v, want none after resumed", retrying)
+	}
+
+	// the resumed wait is stopped by the kill
+	for _, id := range []string{"t1", "t3"} {
+		if !s.cancelRelaunch(id) {
+			t.Errorf("cancelRelaunch(%s) = false, want true", id)
+		}
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	log "github.com/Sirupsen/logrus"
@@ -37,6 +38,9 @@ type SchedulerConfig struct {
 
 	HeartbeatTimeout        float64
 	MaxTasksPerOffer        int
+	LaunchTimeout           time.Duration
+	RefuseSeconds           float64
+	Role                    string
 	EnableCapabilityKilling bool
 	EnableCheckPoint        bool
 }
@@ -60,9 +64,12 @@ type Scheduler struct {
 	agents       map[string]*magent.Agent // holding offers (agents)
 	pendingTasks map[string]*Task
 
+	attrs *attrsCache // agent id -> attributes, kept even the agent without offers
+
 	reconcileTimer *time.Ticker
 
-	strategy strategy.Strategy
+	strategy strategy.Strategy // default strategy
+	rankings *rankings
 	filters  []filter.Filter
 
 	eventmgr *eventManager
@@ -70,6 +77,20 @@ type Scheduler struct {
 	clusterMaster *mole.Master
 
 	sem chan struct{} // to order the mesos offer acquirement by multi app launching
+
+	offerMu      sync.Mutex
+	offerArrived chan struct{} // closed & renewed once offers arrived
+
+	counters offerCounters
+
+	demandMu sync.Mutex
+	demand   offerDemand // suppress & revive offers by the launchers
+
+	inverseMu sync.Mutex
+	inverses  map[string]bool // inverse offer id -> outstanding, false once rescinded
+
+	relaunchMu  sync.Mutex
+	relaunching map[string]chan struct{} // retrying task id -> closed on killed
 }
 
 // NewScheduler...
@@ -79,21 +100,17 @@ func NewScheduler(cfg *SchedulerConfig, db store.Store, clusterMaster *mole.Mast
 		quit:          make(chan struct{}),
 		agents:        make(map[string]*magent.Agent),
 		pendingTasks:  make(map[string]*Task),
+		attrs:         newAttrsCache(),
 		db:            db,
-		strategy:      strategy.NewBinPackStrategy(), // default strategy
-		filters:       []filter.Filter{filter.NewConstraintsFilter(), filter.NewResourceFilter()},
+		strategy:      newStrategy(cfg.Strategy),
+		rankings:      &rankings{m: make(map[string]*ranking)},
+		filters:       []filter.Filter{filter.NewUnschedulableFilter(), filter.NewConstraintsFilter(), filter.NewAffinityFilter(), filter.NewVolumeFilter(), filter.NewResourceFilter()},
 		eventmgr:      NewEventManager(),
 		clusterMaster: clusterMaster,
 		sem:           make(chan struct{}, 1), // allow only one offer acquirement at one time
-	}
-
-	switch cfg.Strategy {
-	case "random":
-		s.strategy = strategy.NewRandomStrategy()
-	case "binpack", "binpacking":
-		s.strategy = strategy.NewBinPackStrategy()
-	case "spread":
-		s.strategy = strategy.NewSpreadStrategy()
+		offerArrived:  make(chan struct{}),
+		inverses:      make(map[string]bool),
+		relaunching:   make(map[string]chan struct{}),
 	}
 
 	if err := s.init(); err != nil {
@@ -297,6 +314,14 @@ func (s *Scheduler) handleEvent(ev *mesosproto.Event) {
 			}
 		}()
 
+		if podStatusIgnored(status) {
+			log.Debugf("Ignored status %s of pod container task %s", state.String(), taskId)
+			return
+		}
+
+		// the status of the pod container goes to the pod instance
+		taskId = types.PodTaskId(taskId)
+
 		// emit event status to pending task
 		log.Debugf("Finding task %s to send status %s", taskId, state.String())
 		s.sendTaskStatus(taskId, status)
@@ -316,8 +341,8 @@ func (s *Scheduler) addOffer(offer *mesosproto.Offer) {
 
 	f := magent.NewOffer(offer)
 
-	log.Debugf("Received offer %s with resource cpus:[%.2f] mem:[%.2fG] disk:[%.2fG] ports:%v from agent %s",
-		f.GetId(), f.GetCpus(), f.GetMem()/1024, f.GetDisk()/1024, f.GetPortRange(), f.GetHostname())
+	log.Debugf("Received offer %s with resource cpus:[%.2f] gpus:[%.0f] mem:[%.2fG] disk:[%.2fG] ports:%v from agent %s",
+		f.GetId(), f.GetCpus(), f.GetGpus(), f.GetMem()/1024, f.GetDisk()/1024, f.GetPortRange(), f.GetHostname())
 
 	a.AddOffer(f)
 	time.AfterFunc(time.Second*5, func() { // release the offer later
@@ -350,7 +375,7 @@ func (s *Scheduler) declineOffers(offers []*magent.Offer) error {
 		Decline: &mesosproto.Call_Decline{
 			OfferIds: []*mesosproto.OfferID{},
 			Filters: &mesosproto.Filters{
-				RefuseSeconds: proto.Float64(1),
+				RefuseSeconds: proto.Float64(s.refuseSeconds()),
 			},
 		},
 	}
@@ -370,6 +395,8 @@ func (s *Scheduler) declineOffers(offers []*magent.Offer) error {
 		return err
 	}
 
+	atomic.AddInt64(&s.counters.declined, int64(len(offers)))
+
 	return nil
 }
 
@@ -464,6 +491,12 @@ func (s *Scheduler) sendTaskStatus(taskID string, status *mesosproto.TaskStatus)
 func (s *Scheduler) KillTask(taskId, agentId string, gracePeriod int64) error {
 	log.Printf("Killing task %s with agentId %s", taskId, agentId)
 
+	// the retrying task isn't launched yet
+	if s.cancelRelaunch(taskId) {
+		log.Printf("Relaunch of task %s canceled", taskId)
+		return nil
+	}
+
 	if agentId == "" {
 		log.Warnf("agentId of task %s is empty, ignore", taskId)
 		return nil
@@ -689,75 +722,41 @@ func (s *Scheduler) Offers() interface{} {
 	return offers
 }
 
-// wait proper offers according by grouped-task's constraints & resources requirments
-func (s *Scheduler) waitOffers(filterOpts *filter.FilterOptions) ([]*magent.Offer, error) {
-	log.Debugln("Finding suitable agent to run tasks")
+func (s *Scheduler) LaunchTasks(tasks []*Task) error {
 
 	var (
-		offers         = make([]*magent.Offer, 0, 0)
-		maxWait        = time.Second * 86400
-		waitTimeout    = time.After(maxWait)
-		err            error // global final error
-		filteredAgents []*magent.Agent
+		wg     sync.WaitGroup
+		groups = [][]*Task{}
+		count  = len(tasks)
+		step   = s.cfg.MaxTasksPerOffer
+		cfg    = tasks[0].cfg
+		appId  = strings.SplitN(tasks[0].GetName(), ".", 2)[1]
 	)
 
-	for {
-		// make the offer exclusively
-		s.lockOffer()
-
-		select {
-		case <-waitTimeout:
-			s.unlockOffer() // make other launchers avaliable to mesos offers
-			if err != nil {
-				return nil, fmt.Errorf("without proper agents: %s", err.Error())
-			} else {
-				return nil, fmt.Errorf("wait offer timeout in %s", maxWait)
-			}
-
-		default:
-			// ensure we have at least one agent avaliable
-			agents := s.getAgents()
-			if len(agents) == 0 {
-				log.Warnf("without proper offers, no agents avaliable, retrying ...")
-				goto RETRY
-			}
+	if err := s.checkRole(cfg); err != nil {
+		return err
+	}
 
-			// filter agents by resources & constraints
-			filteredAgents, err = filter.ApplyFilters(s.filters, filterOpts, agents)
-			if err != nil {
-				log.Warnf("without proper offers: [%v], retrying ...", err)
-				goto RETRY
-			}
+	s.acquireOffers()
+	defer s.releaseOffers()
 
-			offers = filteredAgents[0].GetOffers()
-			log.Debugf("Found %d agents with %d offers avaliable", len(filteredAgents), len(offers))
+	// the apps are loaded once for the placement of all the tasks
+	snapshot := s.newAppsSnapshot()
 
-			// now we got proper offers
-			if len(offers) > 0 {
-				for _, offer := range offers {
-					s.removeOffer(offer)
-				}
-				s.unlockOffer()
-				return offers, nil
-			}
+	strat, typ := s.strategyFor(snapshot, cfg)
 
-		RETRY:
-			// no proper offers, delay for a while and try again ...
-			s.unlockOffer()
-			time.Sleep(time.Millisecond * 500)
+	// the placement constraints should see each placed task of the app
+	for _, c := range cfg.Constraints {
+		if c.IsPlacement() {
+			step = 1
+			break
 		}
 	}
-}
-
-func (s *Scheduler) LaunchTasks(tasks []*Task) error {
 
-	var (
-		wg     sync.WaitGroup
-		groups = [][]*Task{}
-		count  = len(tasks)
-		step   = s.cfg.MaxTasksPerOffer
-		cfg    = tasks[0].cfg
-	)
+	// each task is bound to its own persistent volumes
+	if len(cfg.PersistentVolumes()) > 0 {
+		step = 1
+	}
 
 	var errs struct {
 		m []error
@@ -821,74 +820,94 @@ func (s *Scheduler) LaunchTasks(tasks []*Task) error {
 			}
 		}
 
-		// try to use filter options to obtain proper offers
-		filterOpts := &filter.FilterOptions{
-			ResRequired: cfg.ResourcesRequired(),
-			Replicas:    len(group),
-			Constraints: cfg.Constraints,
-		}
-
-		// try obtain proper offers
-		offers, err := s.waitOffers(filterOpts)
-		if err != nil {
-			for _, task := range group {
-				if err := s.updateTask(task.ID(), err.Error(), "failed"); err != nil {
-					log.Errorf("update task errmsg error: %v", err)
-				}
-			}
-			return err
-		}
-
-		// add penging tasks before actually launching the mesos tasks
-		for _, task := range group {
-			s.addPendingTask(task)
-		}
+		// place the tasks across the agents, the tasks not placed yet keep
+		// waiting for the offers until the deadline.
+		var (
+			deadline = s.launchDeadline(group)
+			pending  = group
+		)
 
-		// launch group tasks with specified offers
-		if err := s.launchGroupTasksWithOffers(offers, group); err != nil {
-			// NOTE: required to prevent memory leaks if tasks not emited to mesos master.
-			for _, task := range group {
-				s.removePendingTask(task.ID())
+		for len(pending) > 0 {
+			filterOpts := s.filterOptions(snapshot, appId, cfg, len(pending))
+			filterOpts.Volumes = s.volumeOptions(appId, pending[0].GetName(), cfg)
 
-				if err := s.updateTask(task.ID(), err.Error(), "failed"); err != nil {
-					log.Errorf("update task errmsg error: %v", err)
+			assigned, left, err := s.waitOffers(appId, pending, filterOpts, strat, typ, cfg.Priority, deadline)
+			if err != nil {
+				for _, task := range pending {
+					if err := s.updateTask(task.ID(), err.Error(), "failed"); err != nil {
+						log.Errorf("update task errmsg error: %v", err)
+					}
 				}
-
+				return err
 			}
-			return err
-		}
-
-		// wait grouped-tasks status in background
-		for _, task := range group {
-			wg.Add(1)
-			go func(task *Task) {
-				defer wg.Done()
-				log.Debugf("Waiting for task %s to be running", task.ID())
+			pending = left
 
-				for status := range task.GetStatus() {
-					log.Debugf("Receiving status %s for task %s", status.GetState().String(), task.ID())
+			for i, p := range assigned {
+				// add penging tasks before actually launching the mesos tasks
+				for _, task := range p.tasks {
+					s.addPendingTask(task)
+				}
 
-					if !IsTaskDone(status) {
-						continue
+				// launch placed tasks with the agent's offers, which are
+				// returned to mesos if not launched.
+				if err := s.launchGroupTasksWithOffers(p.offers, p.tasks); err != nil {
+					// the offers of the following assignments have been taken
+					// out by the placement as well, return all of them.
+					for _, rest := range assigned[i:] {
+						s.declineOffers(rest.offers)
+
+						// NOTE: required to prevent memory leaks if tasks not emited to mesos master.
+						for _, task := range rest.tasks {
+							s.removePendingTask(task.ID())
+
+							if err := s.updateTask(task.ID(), err.Error(), "failed"); err != nil {
+								log.Errorf("update task errmsg error: %v", err)
+							}
+						}
 					}
 
-					if err := DetectTaskError(status); err != nil {
-						log.Errorf("Launch task %s failed: %v", task.ID(), err)
-
+					// nor the tasks not placed yet will be launched
+					for _, task := range pending {
 						if err := s.updateTask(task.ID(), err.Error(), "failed"); err != nil {
 							log.Errorf("update task errmsg error: %v", err)
 						}
-
-						errs.Lock()
-						errs.m = append(errs.m, err)
-						errs.Unlock()
-					} else {
-						log.Printf("Launch task %s succeed", task.ID())
 					}
-					s.removePendingTask(task.ID())
-					return
+					return err
+				}
+
+				// wait placed tasks status in background
+				for _, task := range p.tasks {
+					wg.Add(1)
+					go func(task *Task) {
+						defer wg.Done()
+						log.Debugf("Waiting for task %s to be running", task.ID())
+
+						for status := range task.GetStatus() {
+							log.Debugf("Receiving status %s for task %s", status.GetState().String(), task.ID())
+
+							if !IsTaskDone(status) {
+								continue
+							}
+
+							if err := DetectTaskError(status); err != nil {
+								log.Errorf("Launch task %s failed: %v", task.ID(), err)
+
+								if err := s.updateTask(task.ID(), err.Error(), "failed"); err != nil {
+									log.Errorf("update task errmsg error: %v", err)
+								}
+
+								errs.Lock()
+								errs.m = append(errs.m, err)
+								errs.Unlock()
+							} else {
+								log.Printf("Launch task %s succeed", task.ID())
+							}
+							s.removePendingTask(task.ID())
+							return
+						}
+					}(task)
 				}
-			}(task)
+			}
 		}
 	}
 
@@ -902,10 +921,12 @@ func (s *Scheduler) LaunchTasks(tasks []*Task) error {
 
 // launch grouped runtime tasks with specified mesos offers
 func (s *Scheduler) launchGroupTasksWithOffers(offers []*magent.Offer, tasks []*Task) error {
-	ports := make([]uint64, 0)
-	for _, offer := range offers {
-		ports = append(ports, offer.GetPorts()...)
-	}
+	var (
+		appId   = strings.SplitN(tasks[0].GetName(), ".", 2)[1]
+		pool    = s.newResourcePool(appId, tasks[0].cfg, offers)
+		ports   = pool.Ports()
+		volumes = make(map[string][]*types.PersistentVolume) // task id -> persistent volumes
+	)
 
 	var idx int
 	for _, task := range tasks {
@@ -930,9 +951,18 @@ func (s *Scheduler) launchGroupTasksWithOffers(offers []*magent.Offer, tasks []*
 		}
 
 		task.Build()
-	}
 
-	appId := strings.SplitN(tasks[0].GetName(), ".", 2)[1]
+		// allocate the offered resources with the roles & reservations
+		if err := allocateTask(pool, task); err != nil {
+			return fmt.Errorf("allocate resources for task %s error: %v", task.ID(), err)
+		}
+
+		vols, err := s.allocateVolumes(pool, appId, task, task.AgentId, offers[0].GetHostname())
+		if err != nil {
+			return fmt.Errorf("allocate persistent volumes for task %s error: %v", task.ID(), err)
+		}
+		volumes[task.ID()] = vols
+	}
 
 	// memo update each db tasks' AgentID, IP, Port ...
 	for _, t := range tasks {
@@ -945,6 +975,7 @@ func (s *Scheduler) launchGroupTasksWithOffers(offers []*magent.Offer, tasks []*
 		dbtask.AgentId = t.AgentId.GetValue()
 		dbtask.IP = t.cfg.IP
 		dbtask.Ports = t.cfg.Ports
+		dbtask.Volumes = volumes[t.ID()]
 		if t.cfg.Network == "host" || t.cfg.Network == "bridge" {
 			dbtask.IP = offers[0].GetHostname()
 		}
@@ -956,8 +987,9 @@ func (s *Scheduler) launchGroupTasksWithOffers(offers []*magent.Offer, tasks []*
 	}
 
 	var (
-		offerIds  = []*mesosproto.OfferID{}
-		taskInfos = []*mesosproto.TaskInfo{}
+		offerIds   = []*mesosproto.OfferID{}
+		taskInfos  = []*mesosproto.TaskInfo{}
+		operations = []*mesosproto.Offer_Operation{}
 	)
 
 	for _, offer := range offers {
@@ -967,23 +999,54 @@ func (s *Scheduler) launchGroupTasksWithOffers(offers []*magent.Offer, tasks []*
 	}
 
 	for _, task := range tasks {
-		taskInfos = append(taskInfos, &task.TaskInfo)
+		if !task.IsPod() {
+			taskInfos = append(taskInfos, &task.TaskInfo)
+		}
+	}
+
+	// reserve the unreserved resources before launching, the operations are
+	// applied in order by mesos master.
+	if len(pool.reserving) > 0 {
+		log.Printf("Reserving %d resource(s) on agent %s for app %s", len(pool.reserving), offers[0].GetHostname(), appId)
+
+		operations = append(operations, &mesosproto.Offer_Operation{
+			Type:    mesosproto.Offer_Operation_RESERVE.Enum(),
+			Reserve: &mesosproto.Offer_Operation_Reserve{Resources: pool.reserving},
+		})
+	}
+
+	if len(pool.creating) > 0 {
+		log.Printf("Creating %d persistent volume(s) on agent %s for app %s", len(pool.creating), offers[0].GetHostname(), appId)
+
+		operations = append(operations, &mesosproto.Offer_Operation{
+			Type:   mesosproto.Offer_Operation_CREATE.Enum(),
+			Create: &mesosproto.Offer_Operation_Create{Volumes: pool.creating},
+		})
+	}
+
+	if len(taskInfos) > 0 {
+		operations = append(operations, &mesosproto.Offer_Operation{
+			Type: mesosproto.Offer_Operation_LAUNCH.Enum(),
+			Launch: &mesosproto.Offer_Operation_Launch{
+				TaskInfos: taskInfos,
+			},
+		})
+	}
+
+	// each pod instance is launched by its own executor
+	for _, task := range tasks {
+		if task.IsPod() {
+			operations = append(operations, s.launchGroupOperation(task))
+		}
 	}
 
 	call := &mesosproto.Call{
 		FrameworkId: s.FrameworkId(),
 		Type:        mesosproto.Call_ACCEPT.Enum(),
 		Accept: &mesosproto.Call_Accept{
-			OfferIds: offerIds,
-			Operations: []*mesosproto.Offer_Operation{
-				&mesosproto.Offer_Operation{
-					Type: mesosproto.Offer_Operation_LAUNCH.Enum(),
-					Launch: &mesosproto.Offer_Operation_Launch{
-						TaskInfos: taskInfos,
-					},
-				},
-			},
-			Filters: &mesosproto.Filters{RefuseSeconds: proto.Float64(1)},
+			OfferIds:   offerIds,
+			Operations: operations,
+			Filters:    &mesosproto.Filters{RefuseSeconds: proto.Float64(1)},
 		},
 	}
 
@@ -995,6 +1058,19 @@ func (s *Scheduler) launchGroupTasksWithOffers(offers []*magent.Offer, tasks []*
 		return fmt.Errorf("send launch call got error: %v", err)
 	}
 
+	atomic.AddInt64(&s.counters.used, int64(len(offers)))
+
+	// memo the created volumes binding
+	for _, vols := range volumes {
+		for _, vol := range vols {
+			if pool.created(vol.ID) {
+				if err := s.db.CreateVolume(appId, vol); err != nil {
+					log.Errorf("create app %s volume %s error: %v", appId, vol.ID, err)
+				}
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -1034,10 +1110,6 @@ func (s *Scheduler) rescheduleTask(appId string, task *types.Task) {
 		return
 	}
 
-	seq := strings.SplitN(task.Name, ".", 2)[0]
-	idx, _ := strconv.Atoi(seq)
-	cfg := types.NewTaskConfig(ver, idx)
-
 	var (
 		name       = taskName
 		id         = fmt.Sprintf("%s.%s", utils.RandomString(12), name)
@@ -1050,6 +1122,9 @@ func (s *Scheduler) rescheduleTask(appId string, task *types.Task) {
 		maxRetries = restart.Retries
 	}
 
+	// back off the relaunch of the task failed in a row
+	nextLaunch := time.Now().Add(restart.Delay(retries))
+
 	dbtask := &types.Task{
 		ID:         id,
 		Name:       name,
@@ -1059,6 +1134,7 @@ func (s *Scheduler) rescheduleTask(appId string, task *types.Task) {
 		Healthy:    types.TaskHealthyUnset,
 		Retries:    retries + 1,
 		MaxRetries: maxRetries,
+		NextLaunch: &nextLaunch,
 		Created:    time.Now(),
 		Updated:    time.Now(),
 	}
@@ -1081,14 +1157,36 @@ func (s *Scheduler) rescheduleTask(appId string, task *types.Task) {
 
 	dbtask.Histories = append(dbtask.Histories, task)
 
+	// watched before saved, so the resume won't relaunch it twice
+	stop, _ := s.watchRelaunch(dbtask.ID)
+
 	if err := s.db.CreateTask(appId, dbtask); err != nil {
+		s.unwatchRelaunch(dbtask.ID)
 		log.Errorf("rescheduleTask(): create dbtask %s error: %v", dbtask.ID, err)
 		return
 	}
 
+	s.relaunchTask(appId, dbtask, stop)
+}
+
+// relaunchTask launches the retrying task once its backoff passed.
+func (s *Scheduler) relaunchTask(appId string, dbtask *types.Task, stop <-chan struct{}) {
+	// the config is built by the version when relaunching, which may be
+	// changed during the wait.
+	ver, ok := s.waitRelaunch(appId, dbtask, stop)
+	if !ok {
+		log.Printf("Retrying task %s has gone, reschedule canceled", dbtask.ID)
+		return
+	}
+
+	seq := strings.SplitN(dbtask.Name, ".", 2)[0]
+	idx, _ := strconv.Atoi(seq)
+	cfg := types.NewTaskConfig(ver, idx)
+
 	m := NewTask(cfg, dbtask.ID, dbtask.Name)
 
 	if err := s.LaunchTasks([]*Task{m}); err != nil {
+		dbtask.NextLaunch = nil
 		log.Errorf("rescheduleTask(): launch task %s error: %v", dbtask.ID, err)
 
 		dbtask.Status = "Failed"
@@ -1101,7 +1199,7 @@ func (s *Scheduler) rescheduleTask(appId string, task *types.Task) {
 		return
 	}
 
-	log.Printf("Reschedule task %s succeed", task.Name)
+	log.Printf("Reschedule task %s succeed", dbtask.Name)
 }
 
 func (s *Scheduler) SendEvent(appId string, task *types.Task) error {
@@ -1135,6 +1233,11 @@ func (s *Scheduler) SendEvent(appId string, task *types.Task) error {
 			taskEv.AppAlias = proxy.Alias
 			taskEv.AppListen = proxy.Listen
 			taskEv.AppSticky = proxy.Sticky
+			taskEv.AppRules = proxy.Rules
+			taskEv.AppHealthCheck = proxy.HealthCheck
+			taskEv.AppRetry = proxy.Retry
+			taskEv.AppBalance = proxy.Balance
+			taskEv.AppStickiness = proxy.Stickiness
 
 			if len(task.Ports) > 0 {
 				taskEv.Port = task.Ports[i] // currently only support the first port within proxy & events
@@ -1155,6 +1258,11 @@ func (s *Scheduler) SendEvent(appId string, task *types.Task) error {
 	return nil
 }
 
+// SendAppEvent broadcast the app level event to the event clients.
+func (s *Scheduler) SendAppEvent(ev *types.AppEvent) error {
+	return s.eventmgr.broadcast(ev)
+}
+
 func (s *Scheduler) updateTask(taskId, errmsg, status string) error {
 	parts := strings.SplitN(taskId, ".", 3)
 	if len(parts) < 3 {
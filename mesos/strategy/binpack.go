@@ -25,3 +25,11 @@ func (b *binpackStrategy) RankAndSort(agents []*magent.Agent) []*magent.Agent {
 
 	return candidates
 }
+
+func (b *binpackStrategy) Score(agents []*magent.Agent) []*AgentScore {
+	weightedList := weight(agents)
+
+	sort.Sort(weightedList)
+
+	return weightedList.scores()
+}
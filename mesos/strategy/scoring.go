@@ -0,0 +1,213 @@
+package strategy
+
+import (
+	"sort"
+
+	magent "github.com/Dataman-Cloud/swan/mesos/agent"
+)
+
+// Scorer scores each of the agents in [0, 1].
+type Scorer interface {
+	Name() string
+	Score(agents []*magent.Agent) map[string]float64 // agent id -> score
+}
+
+type WeightedScorer struct {
+	Scorer Scorer
+	Weight float64
+}
+
+type weightedStrategy struct {
+	scorers []*WeightedScorer
+}
+
+// NewWeightedStrategy ranks the agents by the weighted sum of the scorers, highest first.
+func NewWeightedStrategy(scorers []*WeightedScorer) *weightedStrategy {
+	return &weightedStrategy{
+		scorers: scorers,
+	}
+}
+
+func (w *weightedStrategy) RankAndSort(agents []*magent.Agent) []*magent.Agent {
+	candidates := make([]*magent.Agent, 0)
+
+	byId := make(map[string]*magent.Agent)
+	for _, agent := range agents {
+		byId[agent.ID()] = agent
+	}
+
+	for _, score := range w.Score(agents) {
+		candidates = append(candidates, byId[score.AgentID])
+	}
+
+	return candidates
+}
+
+func (w *weightedStrategy) Score(agents []*magent.Agent) []*AgentScore {
+	ret := make([]*AgentScore, 0, len(agents))
+	for _, agent := range agents {
+		ret = append(ret, &AgentScore{
+			AgentID:  agent.ID(),
+			Hostname: agent.Hostname(),
+			Scores:   make(map[string]float64),
+		})
+	}
+
+	for _, ws := range w.scorers {
+		scores := ws.Scorer.Score(agents)
+
+		for _, as := range ret {
+			v := scores[as.AgentID] * ws.Weight
+			as.Scores[ws.Scorer.Name()] += v
+			as.Score += v
+		}
+	}
+
+	sort.Sort(agentScores(ret))
+
+	return ret
+}
+
+// agentScores sorts by score desc, the hostname is compared for stable ranking.
+type agentScores []*AgentScore
+
+func (s agentScores) Len() int      { return len(s) }
+func (s agentScores) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s agentScores) Less(i, j int) bool {
+	if s[i].Score == s[j].Score {
+		return s[i].Hostname < s[j].Hostname
+	}
+	return s[i].Score > s[j].Score
+}
+
+// leastAllocatedScorer prefers the agents with more available cpus & mem,
+// relative to the max of the agents.
+type leastAllocatedScorer struct{}
+
+func NewLeastAllocatedScorer() *leastAllocatedScorer {
+	return &leastAllocatedScorer{}
+}
+
+func (s *leastAllocatedScorer) Name() string {
+	return "least-allocated"
+}
+
+func (s *leastAllocatedScorer) Score(agents []*magent.Agent) map[string]float64 {
+	return availableRatio(agents)
+}
+
+// mostAllocatedScorer prefers the agents with less available cpus & mem, to pack
+// the tasks and leave the other agents free.
+type mostAllocatedScorer struct{}
+
+func NewMostAllocatedScorer() *mostAllocatedScorer {
+	return &mostAllocatedScorer{}
+}
+
+func (s *mostAllocatedScorer) Name() string {
+	return "most-allocated"
+}
+
+func (s *mostAllocatedScorer) Score(agents []*magent.Agent) map[string]float64 {
+	ret := availableRatio(agents)
+	for id, v := range ret {
+		ret[id] = 1 - v
+	}
+	return ret
+}
+
+func availableRatio(agents []*magent.Agent) map[string]float64 {
+	type avail struct {
+		cpus, mem float64
+	}
+
+	var (
+		ret              = make(map[string]float64)
+		avails           = make(map[string]avail)
+		maxCpus, maxMems float64
+	)
+
+	for _, agent := range agents {
+		cpus, mem, _, _ := agent.Resources()
+		avails[agent.ID()] = avail{cpus, mem}
+
+		if cpus > maxCpus {
+			maxCpus = cpus
+		}
+		if mem > maxMems {
+			maxMems = mem
+		}
+	}
+
+	for id, a := range avails {
+		var v float64
+		if maxCpus > 0 {
+			v += a.cpus / maxCpus
+		}
+		if maxMems > 0 {
+			v += a.mem / maxMems
+		}
+		ret[id] = v / 2
+	}
+
+	return ret
+}
+
+// attributeScorer prefers the agents with the attribute, and the value if specified.
+type attributeScorer struct {
+	attr, value string
+}
+
+func NewAttributeScorer(attr, value string) *attributeScorer {
+	return &attributeScorer{
+		attr:  attr,
+		value: value,
+	}
+}
+
+func (s *attributeScorer) Name() string {
+	if s.value == "" {
+		return "attribute:" + s.attr
+	}
+	return "attribute:" + s.attr + "=" + s.value
+}
+
+func (s *attributeScorer) Score(agents []*magent.Agent) map[string]float64 {
+	ret := make(map[string]float64)
+
+	for _, agent := range agents {
+		if v, ok := agent.Attributes()[s.attr]; ok && (s.value == "" || s.value == v) {
+			ret[agent.ID()] = 1
+		}
+	}
+
+	return ret
+}
+
+// imageLocalityScorer prefers the agents have run the tasks of the same image,
+// which is likely to be cached there.
+type imageLocalityScorer struct {
+	agents map[string]bool // agent id
+}
+
+func NewImageLocalityScorer(agents map[string]bool) *imageLocalityScorer {
+	return &imageLocalityScorer{
+		agents: agents,
+	}
+}
+
+func (s *imageLocalityScorer) Name() string {
+	return "image-locality"
+}
+
+func (s *imageLocalityScorer) Score(agents []*magent.Agent) map[string]float64 {
+	ret := make(map[string]float64)
+
+	for _, agent := range agents {
+		if s.agents[agent.ID()] {
+			ret[agent.ID()] = 1
+		}
+	}
+
+	return ret
+}
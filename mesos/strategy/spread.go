@@ -25,3 +25,11 @@ func (s *spreadStrategy) RankAndSort(agents []*magent.Agent) []*magent.Agent {
 
 	return candidates
 }
+
+func (s *spreadStrategy) Score(agents []*magent.Agent) []*AgentScore {
+	weightedList := weight(agents)
+
+	sort.Sort(sort.Reverse(weightedList))
+
+	return weightedList.scores()
+}
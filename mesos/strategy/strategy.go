@@ -7,3 +7,34 @@ import (
 type Strategy interface {
 	RankAndSort(agents []*magent.Agent) []*magent.Agent
 }
+
+// Scorable is implemented by the strategy which could explain its ranking.
+type Scorable interface {
+	Score(agents []*magent.Agent) []*AgentScore // sorted by rank
+}
+
+// AgentScore is the score breakdown of the agent ranked by the strategy.
+type AgentScore struct {
+	AgentID  string             `json:"agent_id"`
+	Hostname string             `json:"hostname"`
+	Score    float64            `json:"score"`
+	Scores   map[string]float64 `json:"scores,omitempty"` // name -> weighted score
+}
+
+// Scores returns the score breakdown of the agents by rank, the agents are
+// only ranked without scores if the strategy isn't Scorable, eg: random.
+func Scores(s Strategy, agents []*magent.Agent) []*AgentScore {
+	if scorable, ok := s.(Scorable); ok {
+		return scorable.Score(agents)
+	}
+
+	ret := make([]*AgentScore, 0)
+	for _, agent := range s.RankAndSort(agents) {
+		ret = append(ret, &AgentScore{
+			AgentID:  agent.ID(),
+			Hostname: agent.Hostname(),
+		})
+	}
+
+	return ret
+}
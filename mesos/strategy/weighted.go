@@ -38,3 +38,19 @@ func weight(agents []*magent.Agent) weightedAgents {
 
 	return weightedList
 }
+
+// scores of the weighted agents, the weight is the sum of available resources.
+func (w weightedAgents) scores() []*AgentScore {
+	ret := make([]*AgentScore, 0, len(w))
+
+	for _, weighted := range w {
+		ret = append(ret, &AgentScore{
+			AgentID:  weighted.agent.ID(),
+			Hostname: weighted.agent.Hostname(),
+			Score:    weighted.weight,
+			Scores:   map[string]float64{"resources": weighted.weight},
+		})
+	}
+
+	return ret
+}
@@ -0,0 +1,216 @@
+package mesos
+
+import (
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/Dataman-Cloud/swan/mesosproto"
+	"github.com/Dataman-Cloud/swan/types"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+const (
+	// the default seconds mesos won't re-offer the declined resources
+	defaultRefuseSeconds = 5
+
+	// the scheduler keeps receiving offers for a while after the last launch,
+	// so the launches in a row, eg: scaling up one by one, don't flap.
+	suppressDelay = time.Second * 10
+
+	// the stale reservations of an app are treated as cleaned up if none of
+	// them seen for a while, longer than the offers held & refused by default.
+	staleGrace = time.Second * 30
+)
+
+// offerCounters counts the offers, updated atomically.
+type offerCounters struct {
+	received  int64
+	used      int64
+	declined  int64
+	rescinded int64
+}
+
+// offerDemand tracks the launchers requiring offers and the apps with the
+// stale reservations pending for cleanup, the offers are suppressed while
+// none of them.
+type offerDemand struct {
+	launching  int
+	stale      map[string]time.Time // app id -> last time its stale reservations seen
+	suppressed bool
+	timer      *time.Timer
+}
+
+func (s *Scheduler) refuseSeconds() float64 {
+	if s.cfg.RefuseSeconds > 0 {
+		return s.cfg.RefuseSeconds
+	}
+	return defaultRefuseSeconds
+}
+
+// OfferStats returns the offer counters & whether the offers are suppressed.
+func (s *Scheduler) OfferStats() *types.OfferStats {
+	s.demandMu.Lock()
+	suppressed := s.demand.suppressed
+	s.demandMu.Unlock()
+
+	return &types.OfferStats{
+		Received:   atomic.LoadInt64(&s.counters.received),
+		Used:       atomic.LoadInt64(&s.counters.used),
+		Declined:   atomic.LoadInt64(&s.counters.declined),
+		Rescinded:  atomic.LoadInt64(&s.counters.rescinded),
+		Suppressed: suppressed,
+	}
+}
+
+// acquireOffers registers a launcher, the first one revives the offers, which
+// also clears the filters of the previously declined offers.
+func (s *Scheduler) acquireOffers() {
+	s.demandMu.Lock()
+	defer s.demandMu.Unlock()
+
+	s.demand.launching++
+	if s.demand.timer != nil {
+		s.demand.timer.Stop()
+		s.demand.timer = nil
+	}
+
+	if s.demand.launching > 1 {
+		return
+	}
+
+	if err := s.reviveOffers(); err != nil {
+		log.Errorf("revive offers error: %v", err)
+		return
+	}
+	s.demand.suppressed = false
+}
+
+// releaseOffers unregisters the launcher, the offers are suppressed later if
+// no more launchers by then.
+func (s *Scheduler) releaseOffers() {
+	s.demandMu.Lock()
+	defer s.demandMu.Unlock()
+
+	s.demand.launching--
+	if s.demand.launching > 0 {
+		return
+	}
+
+	s.suppressLater()
+}
+
+// suppressLater suppresses the offers after the delay if still idle,
+// the caller must hold the demandMu.
+func (s *Scheduler) suppressLater() {
+	if s.demand.timer != nil {
+		s.demand.timer.Stop()
+	}
+
+	s.demand.timer = time.AfterFunc(suppressDelay, func() {
+		s.demandMu.Lock()
+		defer s.demandMu.Unlock()
+
+		if s.demand.launching > 0 || s.demand.suppressed {
+			return
+		}
+
+		// keep receiving offers to release the stale reservations
+		if s.stalePending() {
+			s.suppressLater()
+			return
+		}
+
+		if err := s.suppressOffers(); err != nil {
+			log.Errorf("suppress offers error: %v", err)
+			return
+		}
+		s.demand.suppressed = true
+	})
+}
+
+// CleanupReservations revives the offers to release the reservations & the
+// persistent volumes of the removed app, which are only seen in the offers.
+func (s *Scheduler) CleanupReservations(appId string) {
+	s.markStale(appId)
+}
+
+// markStale records the app with the stale reservations, the offers are
+// revived if suppressed, and won't be suppressed until the cleanup completes.
+func (s *Scheduler) markStale(appId string) {
+	s.demandMu.Lock()
+	defer s.demandMu.Unlock()
+
+	if s.demand.stale == nil {
+		s.demand.stale = make(map[string]time.Time)
+	}
+	s.demand.stale[appId] = time.Now()
+
+	if !s.demand.suppressed {
+		return
+	}
+
+	if err := s.reviveOffers(); err != nil {
+		log.Errorf("revive offers error: %v", err)
+		return
+	}
+	s.demand.suppressed = false
+
+	if s.demand.launching == 0 {
+		s.suppressLater()
+	}
+}
+
+// stalePending tells whether any app's stale reservations still pending for
+// cleanup, the caller must hold the demandMu.
+func (s *Scheduler) stalePending() bool {
+	for appId, seen := range s.demand.stale {
+		if time.Since(seen) > staleGrace {
+			delete(s.demand.stale, appId)
+		}
+	}
+	return len(s.demand.stale) > 0
+}
+
+// resetDemand is called on subscribed, as mesos revives the offers for the
+// (re)subscribed framework.
+func (s *Scheduler) resetDemand() {
+	s.demandMu.Lock()
+	defer s.demandMu.Unlock()
+
+	s.demand.suppressed = false
+	if s.demand.launching == 0 {
+		s.suppressLater()
+	}
+}
+
+func (s *Scheduler) reviveOffers() error {
+	call := &mesosproto.Call{
+		FrameworkId: s.FrameworkId(),
+		Type:        mesosproto.Call_REVIVE.Enum(),
+	}
+
+	log.Println("Reviving offers")
+
+	if _, err := s.SendCall(call, http.StatusAccepted); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Scheduler) suppressOffers() error {
+	call := &mesosproto.Call{
+		FrameworkId: s.FrameworkId(),
+		Type:        mesosproto.Call_SUPPRESS.Enum(),
+	}
+
+	log.Println("Suppressing offers as no tasks pending")
+
+	if _, err := s.SendCall(call, http.StatusAccepted); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -3,6 +3,7 @@ package mesos
 import (
 	"encoding/json"
 	"errors"
+	"time"
 
 	"github.com/Dataman-Cloud/swan/mesosproto"
 	"github.com/Dataman-Cloud/swan/types"
@@ -15,6 +16,12 @@ type Task struct {
 	updates chan *mesosproto.TaskStatus
 
 	cfg *types.TaskConfig
+
+	deadline time.Time // launch deadline, the scheduler's default if zero
+
+	// the pod instance is launched as a task group by the default executor
+	executor *mesosproto.ExecutorInfo
+	group    []*mesosproto.TaskInfo
 }
 
 func NewTask(cfg *types.TaskConfig, id, name string) *Task {
@@ -29,11 +36,22 @@ func NewTask(cfg *types.TaskConfig, id, name string) *Task {
 	return task
 }
 
+// SetDeadline sets the deadline of waiting offers for the task.
+func (t *Task) SetDeadline(deadline time.Time) {
+	t.deadline = deadline
+}
+
 func (t *Task) ID() string {
 	return t.TaskId.GetValue()
 }
 
 func (t *Task) Build() {
+	if t.cfg.IsPod() {
+		t.executor = t.cfg.BuildExecutor(t.ID())
+		t.group = t.cfg.BuildTaskGroup(t.ID(), t.GetName())
+		return
+	}
+
 	t.Resources = t.cfg.BuildResources()
 	t.Command = t.cfg.BuildCommand()
 	t.Container = t.cfg.BuildContainer(t.ID(), t.GetName())
@@ -41,6 +59,16 @@ func (t *Task) Build() {
 		t.HealthCheck = t.cfg.BuildHealthCheck()
 	}
 	t.Labels = t.cfg.BuildLabels(t.ID(), t.GetName())
+
+	// the mesos containerizer takes the environment by the command
+	if t.cfg.Containerizer == types.ContainerizerMesos {
+		t.Command.Environment = t.cfg.BuildEnvironment(t.ID(), t.GetName())
+	}
+}
+
+// IsPod tells whether the task is a pod instance.
+func (t *Task) IsPod() bool {
+	return t.cfg.IsPod()
 }
 
 // GetStatus method reads the task status on the updates channel
@@ -0,0 +1,214 @@
+package mesos
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+
+	"github.com/Dataman-Cloud/swan/mesos/filter"
+	"github.com/Dataman-Cloud/swan/mesosproto"
+	"github.com/Dataman-Cloud/swan/types"
+	"github.com/Dataman-Cloud/swan/utils"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// volumeDiskUsable returns the func to tell whether the offered disk could be
+// used to create the app's persistent volumes, only the unreserved disk or the
+// disk reserved for the app, so the volumes could be tracked by the app label.
+func (s *Scheduler) volumeDiskUsable(appId string) func(*mesosproto.Resource) bool {
+	return func(r *mesosproto.Resource) bool {
+		if r.GetName() != "disk" || r.GetDisk() != nil || r.GetRevocable() != nil {
+			return false
+		}
+
+		return r.GetRole() == defaultRole || reservedApp(r) == appId
+	}
+}
+
+// boundVolumes returns the persistent volumes bound to the task name.
+func (s *Scheduler) boundVolumes(appId, name string) []*types.PersistentVolume {
+	bound := make([]*types.PersistentVolume, 0)
+
+	vols, err := s.db.ListVolumes(appId)
+	if err != nil {
+		log.Errorf("list app %s volumes error: %v", appId, err)
+		return bound
+	}
+
+	for _, vol := range vols {
+		if vol.TaskName == name {
+			bound = append(bound, vol)
+		}
+	}
+
+	return bound
+}
+
+// volumeOptions builds the filter options for the persistent volumes of the
+// task, the task is pinned to the agent of the bound volumes, and the volumes
+// not bound yet are to be created.
+func (s *Scheduler) volumeOptions(appId, name string, cfg *types.TaskConfig) *filter.VolumeOptions {
+	vols := cfg.PersistentVolumes()
+	if len(vols) == 0 {
+		return nil
+	}
+
+	var (
+		opts = &filter.VolumeOptions{
+			Usable: s.volumeDiskUsable(appId),
+		}
+		bound = s.boundVolumes(appId, name)
+	)
+
+	for _, vol := range vols {
+		if pv := findVolume(bound, vol.ContainerPath); pv != nil {
+			opts.AgentID = pv.AgentID
+			opts.Persistences = append(opts.Persistences, pv.ID)
+			continue
+		}
+
+		opts.Sizes = append(opts.Sizes, vol.Persistent.Size)
+	}
+
+	return opts
+}
+
+func findVolume(vols []*types.PersistentVolume, containerPath string) *types.PersistentVolume {
+	for _, vol := range vols {
+		if vol.ContainerPath == containerPath {
+			return vol
+		}
+	}
+	return nil
+}
+
+// allocateVolumes allocates the persistent volumes of the task from the pool,
+// the bound volumes are used directly, the others are reserved & created.
+func (s *Scheduler) allocateVolumes(pool *resourcePool, appId string, task *Task, agentId *mesosproto.AgentID, hostname string) ([]*types.PersistentVolume, error) {
+	var (
+		name  = task.GetName()
+		bound = s.boundVolumes(appId, name)
+		ret   = make([]*types.PersistentVolume, 0)
+	)
+
+	for _, vol := range task.cfg.PersistentVolumes() {
+		if pv := findVolume(bound, vol.ContainerPath); pv != nil {
+			r := pool.volume(pv.ID)
+			if r == nil {
+				return nil, fmt.Errorf("persistent volume %s not offered", pv.ID)
+			}
+
+			task.Resources = append(task.Resources, r)
+			ret = append(ret, pv)
+			continue
+		}
+
+		pv := &types.PersistentVolume{
+			ID:            fmt.Sprintf("%s.%s", utils.RandomString(12), name),
+			AppID:         appId,
+			TaskName:      name,
+			AgentID:       agentId.GetValue(),
+			Hostname:      hostname,
+			ContainerPath: vol.ContainerPath,
+			Size:          vol.Persistent.Size,
+			CreatedAt:     time.Now(),
+		}
+
+		r, err := pool.createVolume(pv.ID, vol)
+		if err != nil {
+			return nil, err
+		}
+
+		task.Resources = append(task.Resources, r)
+		ret = append(ret, pv)
+	}
+
+	return ret, nil
+}
+
+// createVolume takes the disk for the volume from the pool, the unreserved
+// disk is reserved for the app before creating.
+func (p *resourcePool) createVolume(id string, vol *types.Volume) (*mesosproto.Resource, error) {
+	size := vol.Persistent.Size
+
+	for _, sr := range p.disks {
+		if sr.remain < size {
+			continue
+		}
+		sr.remain -= size
+
+		var (
+			role        = sr.offered.GetRole()
+			reservation = sr.offered.GetReservation()
+		)
+
+		if role == defaultRole {
+			role, reservation = p.role, p.reservation
+
+			p.reserving = append(p.reserving, &mesosproto.Resource{
+				Name:        proto.String("disk"),
+				Type:        mesosproto.Value_SCALAR.Enum(),
+				Scalar:      &mesosproto.Value_Scalar{Value: proto.Float64(size)},
+				Role:        proto.String(role),
+				Reservation: reservation,
+			})
+		}
+
+		r := &mesosproto.Resource{
+			Name:        proto.String("disk"),
+			Type:        mesosproto.Value_SCALAR.Enum(),
+			Scalar:      &mesosproto.Value_Scalar{Value: proto.Float64(size)},
+			Role:        proto.String(role),
+			Reservation: reservation,
+			Disk: &mesosproto.Resource_DiskInfo{
+				Persistence: &mesosproto.Resource_DiskInfo_Persistence{
+					Id:        proto.String(id),
+					Principal: reservation.Principal,
+				},
+				Volume: &mesosproto.Volume{
+					ContainerPath: proto.String(vol.PersistentPath()),
+					Mode:          mesosproto.Volume_RW.Enum(),
+				},
+			},
+		}
+
+		p.creating = append(p.creating, r)
+
+		return r, nil
+	}
+
+	return nil, fmt.Errorf("no disk to create persistent volume of %.2f MB", size)
+}
+
+// volume takes the offered persistent volume out of the pool.
+func (p *resourcePool) volume(id string) *mesosproto.Resource {
+	r, ok := p.volumes[id]
+	if ok {
+		delete(p.volumes, id)
+	}
+	return r
+}
+
+// destroyVolume removes the db record of the destroyed persistent volume.
+func (s *Scheduler) destroyVolume(r *mesosproto.Resource) {
+	var (
+		appId = reservedApp(r)
+		id    = r.GetDisk().GetPersistence().GetId()
+	)
+
+	if err := s.db.DeleteVolume(appId, id); err != nil && !s.db.IsErrNotFound(err) {
+		log.Errorf("delete app %s volume %s error: %v", appId, id, err)
+	}
+}
+
+// created tells whether the volume is created by the pool.
+func (p *resourcePool) created(id string) bool {
+	for _, r := range p.creating {
+		if r.GetDisk().GetPersistence().GetId() == id {
+			return true
+		}
+	}
+	return false
+}
@@ -16,7 +16,7 @@ func (s *EtcdStore) CreateApp(app *types.Application) error {
 		pval = path.Join(p, "value")
 	)
 
-	for _, sub := range []string{keyTasks, keyVersions} {
+	for _, sub := range []string{keyTasks, keyVersions, keyDeployments, keySchedules, keyVolumes} {
 		subp := path.Join(p, sub)
 		if err := s.ensureDir(subp); err != nil {
 			return err
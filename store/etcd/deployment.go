@@ -0,0 +1,86 @@
+package etcd
+
+import (
+	"path"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/Dataman-Cloud/swan/types"
+)
+
+func (s *EtcdStore) CreateDeployment(aid string, d *types.Deployment) error {
+	bs, err := encode(d)
+	if err != nil {
+		return err
+	}
+
+	p := path.Join(keyApp, aid, keyDeployments, d.ID)
+
+	if err := s.create(p, bs); err != nil {
+		return err
+	}
+
+	s.pruneDeployments(aid)
+	return nil
+}
+
+// pruneDeployments removes the oldest deployments beyond the history limit.
+func (s *EtcdStore) pruneDeployments(aid string) {
+	p := path.Join(keyApp, aid, keyDeployments)
+
+	children, err := s.list(p)
+	if err != nil {
+		log.Errorf("get app %s children(deployments) error: %v", aid, err)
+		return
+	}
+
+	ids := make([]string, 0, len(children))
+	for id := range children {
+		ids = append(ids, id)
+	}
+
+	for _, id := range types.ExpiredDeployments(ids) {
+		if err := s.del(path.Join(p, id), false); err != nil {
+			log.Errorf("remove app %s expired deployment %s error: %v", aid, id, err)
+		}
+	}
+}
+
+func (s *EtcdStore) UpdateDeployment(aid string, d *types.Deployment) error {
+	bs, err := encode(d)
+	if err != nil {
+		return err
+	}
+
+	p := path.Join(keyApp, aid, keyDeployments, d.ID)
+
+	return s.update(p, bs)
+}
+
+func (s *EtcdStore) ListDeployments(aid string) ([]*types.Deployment, error) {
+	p := path.Join(keyApp, aid, keyDeployments)
+
+	deployments := make([]*types.Deployment, 0)
+
+	children, err := s.list(p)
+	if err != nil {
+		if isEtcdKeyNotFound(err) {
+			return deployments, nil
+		}
+		log.Errorf("get app %s children(deployments) error: %v", aid, err)
+		return nil, err
+	}
+
+	for _, data := range children {
+		var d *types.Deployment
+		if err := decode(data, &d); err != nil {
+			log.Errorf("decode app %s deployment got error: %v", aid, err)
+			return nil, err
+		}
+
+		deployments = append(deployments, d)
+	}
+
+	types.DeploymentList(deployments).Reverse()
+	return deployments, nil
+}
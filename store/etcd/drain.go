@@ -0,0 +1,78 @@
+package etcd
+
+import (
+	"path"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/Dataman-Cloud/swan/types"
+)
+
+func (s *EtcdStore) CreateDrain(d *types.Drain) error {
+	bs, err := encode(d)
+	if err != nil {
+		return err
+	}
+
+	p := path.Join(keyDrains, d.AgentID)
+
+	return s.create(p, bs)
+}
+
+func (s *EtcdStore) UpdateDrain(d *types.Drain) error {
+	bs, err := encode(d)
+	if err != nil {
+		return err
+	}
+
+	p := path.Join(keyDrains, d.AgentID)
+
+	return s.update(p, bs)
+}
+
+func (s *EtcdStore) GetDrain(agentId string) (*types.Drain, error) {
+	p := path.Join(keyDrains, agentId)
+
+	bs, err := s.get(p)
+	if err != nil {
+		return nil, err
+	}
+
+	var d *types.Drain
+	if err := decode(bs, &d); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+func (s *EtcdStore) DeleteDrain(agentId string) error {
+	p := path.Join(keyDrains, agentId)
+
+	return s.del(p, false)
+}
+
+func (s *EtcdStore) ListDrains() ([]*types.Drain, error) {
+	drains := make([]*types.Drain, 0)
+
+	children, err := s.list(keyDrains)
+	if err != nil {
+		if isEtcdKeyNotFound(err) {
+			return drains, nil
+		}
+		log.Errorf("get drains error: %v", err)
+		return nil, err
+	}
+
+	for _, data := range children {
+		var d *types.Drain
+		if err := decode(data, &d); err != nil {
+			log.Errorf("decode drain got error: %v", err)
+			return nil, err
+		}
+
+		drains = append(drains, d)
+	}
+
+	return drains, nil
+}
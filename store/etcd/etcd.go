@@ -19,9 +19,13 @@ const (
 	keyCompose     = "/composes"    // legacy compose instance (group apps), deprecated
 	keyComposeNG   = "/composes-ng" // compose instance (group apps)
 	keyFrameworkID = "/framework"   // framework id
+	keyDrains      = "/drains"      // draining agents
 
-	keyTasks    = "tasks"    // sub key of keyApp
-	keyVersions = "versions" // sub key of keyApp
+	keyTasks       = "tasks"       // sub key of keyApp
+	keyVersions    = "versions"    // sub key of keyApp
+	keyDeployments = "deployments" // sub key of keyApp
+	keySchedules   = "schedules"   // sub key of keyApp
+	keyVolumes     = "volumes"     // sub key of keyApp
 )
 
 var (
@@ -59,7 +63,7 @@ func NewEtcdStore(addrs []string) (*EtcdStore, error) {
 	}
 
 	// create base keys nodes
-	for _, node := range []string{keyApp, keyCompose, keyComposeNG} {
+	for _, node := range []string{keyApp, keyCompose, keyComposeNG, keyDrains} {
 		store.ensureDir(node)
 	}
 
@@ -0,0 +1,81 @@
+package etcd
+
+import (
+	"path"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/Dataman-Cloud/swan/types"
+)
+
+func (s *EtcdStore) CreateSchedule(aid string, sched *types.Schedule) error {
+	bs, err := encode(sched)
+	if err != nil {
+		return err
+	}
+
+	p := path.Join(keyApp, aid, keySchedules, sched.ID)
+
+	return s.create(p, bs)
+}
+
+func (s *EtcdStore) UpdateSchedule(aid string, sched *types.Schedule) error {
+	bs, err := encode(sched)
+	if err != nil {
+		return err
+	}
+
+	p := path.Join(keyApp, aid, keySchedules, sched.ID)
+
+	return s.update(p, bs)
+}
+
+func (s *EtcdStore) GetSchedule(aid, sid string) (*types.Schedule, error) {
+	p := path.Join(keyApp, aid, keySchedules, sid)
+
+	data, err := s.get(p)
+	if err != nil {
+		log.Errorf("find app %s schedule %s got error: %v", aid, sid, err)
+		return nil, err
+	}
+
+	var sched types.Schedule
+	if err := decode(data, &sched); err != nil {
+		return nil, err
+	}
+
+	return &sched, nil
+}
+
+func (s *EtcdStore) DeleteSchedule(aid, sid string) error {
+	p := path.Join(keyApp, aid, keySchedules, sid)
+
+	return s.del(p, false)
+}
+
+func (s *EtcdStore) ListSchedules(aid string) ([]*types.Schedule, error) {
+	p := path.Join(keyApp, aid, keySchedules)
+
+	schedules := make([]*types.Schedule, 0)
+
+	children, err := s.list(p)
+	if err != nil {
+		if isEtcdKeyNotFound(err) {
+			return schedules, nil
+		}
+		log.Errorf("get app %s children(schedules) error: %v", aid, err)
+		return nil, err
+	}
+
+	for _, data := range children {
+		var sched *types.Schedule
+		if err := decode(data, &sched); err != nil {
+			log.Errorf("decode app %s schedule got error: %v", aid, err)
+			return nil, err
+		}
+
+		schedules = append(schedules, sched)
+	}
+
+	return schedules, nil
+}
@@ -0,0 +1,53 @@
+package etcd
+
+import (
+	"path"
+
+	log "github.com/Sirupsen/logrus"
+
+	"github.com/Dataman-Cloud/swan/types"
+)
+
+func (s *EtcdStore) CreateVolume(aid string, v *types.PersistentVolume) error {
+	bs, err := encode(v)
+	if err != nil {
+		return err
+	}
+
+	p := path.Join(keyApp, aid, keyVolumes, v.ID)
+
+	return s.create(p, bs)
+}
+
+func (s *EtcdStore) DeleteVolume(aid, vid string) error {
+	p := path.Join(keyApp, aid, keyVolumes, vid)
+
+	return s.del(p, false)
+}
+
+func (s *EtcdStore) ListVolumes(aid string) ([]*types.PersistentVolume, error) {
+	p := path.Join(keyApp, aid, keyVolumes)
+
+	volumes := make([]*types.PersistentVolume, 0)
+
+	children, err := s.list(p)
+	if err != nil {
+		if isEtcdKeyNotFound(err) {
+			return volumes, nil
+		}
+		log.Errorf("get app %s children(volumes) error: %v", aid, err)
+		return nil, err
+	}
+
+	for _, data := range children {
+		var v *types.PersistentVolume
+		if err := decode(data, &v); err != nil {
+			log.Errorf("decode app %s volume got error: %v", aid, err)
+			return nil, err
+		}
+
+		volumes = append(volumes, v)
+	}
+
+	return volumes, nil
+}
@@ -28,6 +28,26 @@ type Store interface {
 	ListVersions(string) ([]*types.Version, error)
 	DeleteVersion(string, string) error
 
+	CreateDeployment(string, *types.Deployment) error
+	UpdateDeployment(string, *types.Deployment) error
+	ListDeployments(string) ([]*types.Deployment, error)
+
+	CreateSchedule(string, *types.Schedule) error
+	UpdateSchedule(string, *types.Schedule) error
+	GetSchedule(string, string) (*types.Schedule, error)
+	DeleteSchedule(string, string) error
+	ListSchedules(string) ([]*types.Schedule, error)
+
+	CreateVolume(string, *types.PersistentVolume) error
+	DeleteVolume(string, string) error
+	ListVolumes(string) ([]*types.PersistentVolume, error)
+
+	CreateDrain(*types.Drain) error
+	UpdateDrain(*types.Drain) error
+	GetDrain(string) (*types.Drain, error)
+	DeleteDrain(string) error
+	ListDrains() ([]*types.Drain, error)
+
 	UpdateFrameworkId(frameworkId string) error
 	GetFrameworkId() (string, int64)
 
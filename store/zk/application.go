@@ -147,6 +147,21 @@ func (zk *ZKStore) DeleteApp(id string) error {
 		return err
 	}
 
+	if err := zk.deleteDeployments(id); err != nil {
+		log.Errorf("delete app %s deployments key got error: %v", id, err)
+		return err
+	}
+
+	if err := zk.deleteSchedules(id); err != nil {
+		log.Errorf("delete app %s schedules key got error: %v", id, err)
+		return err
+	}
+
+	if err := zk.deleteVolumes(id); err != nil {
+		log.Errorf("delete app %s volumes key got error: %v", id, err)
+		return err
+	}
+
 	return zk.del(p)
 }
 
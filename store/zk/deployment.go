@@ -0,0 +1,108 @@
+package zk
+
+import (
+	"path"
+
+	"github.com/Dataman-Cloud/swan/types"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+func (zk *ZKStore) CreateDeployment(aid string, d *types.Deployment) error {
+	bs, err := encode(d)
+	if err != nil {
+		return err
+	}
+
+	p := path.Join(keyApp, aid, "deployments", d.ID)
+
+	if err := zk.createAll(p, bs); err != nil {
+		return err
+	}
+
+	zk.pruneDeployments(aid)
+	return nil
+}
+
+// pruneDeployments removes the oldest deployments beyond the history limit.
+func (zk *ZKStore) pruneDeployments(aid string) {
+	p := path.Join(keyApp, aid, "deployments")
+
+	children, err := zk.list(p)
+	if err != nil {
+		log.Errorf("get app %s children(deployments) error: %v", aid, err)
+		return
+	}
+
+	for _, child := range types.ExpiredDeployments(children) {
+		if err := zk.del(path.Join(p, child)); err != nil {
+			log.Errorf("remove app %s expired deployment %s error: %v", aid, child, err)
+		}
+	}
+}
+
+func (zk *ZKStore) UpdateDeployment(aid string, d *types.Deployment) error {
+	bs, err := encode(d)
+	if err != nil {
+		return err
+	}
+
+	p := path.Join(keyApp, aid, "deployments", d.ID)
+
+	return zk.set(p, bs)
+}
+
+func (zk *ZKStore) ListDeployments(aid string) ([]*types.Deployment, error) {
+	p := path.Join(keyApp, aid, "deployments")
+
+	deployments := make([]*types.Deployment, 0)
+
+	children, err := zk.list(p)
+	if err != nil {
+		if err == errNotExists {
+			return deployments, nil
+		}
+		log.Errorf("get app %s children(deployments) error: %v", aid, err)
+		return nil, err
+	}
+
+	for _, child := range children {
+		p := path.Join(keyApp, aid, "deployments", child)
+		data, _, err := zk.get(p)
+		if err != nil {
+			log.Errorf("get %s got error: %v", p, err)
+			return nil, err
+		}
+
+		var d *types.Deployment
+		if err := decode(data, &d); err != nil {
+			log.Errorf("decode deployment %s got error: %v", child, err)
+			return nil, err
+		}
+
+		deployments = append(deployments, d)
+	}
+
+	types.DeploymentList(deployments).Reverse()
+	return deployments, nil
+}
+
+func (zk *ZKStore) deleteDeployments(aid string) error {
+	p := path.Join(keyApp, aid, "deployments")
+
+	children, err := zk.list(p)
+	if err != nil {
+		if err == errNotExists {
+			return nil
+		}
+		return err
+	}
+
+	for _, child := range children {
+		if err := zk.del(path.Join(p, child)); err != nil {
+			return err
+		}
+	}
+
+	return zk.del(p)
+}
@@ -0,0 +1,84 @@
+package zk
+
+import (
+	"path"
+
+	"github.com/Dataman-Cloud/swan/types"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+func (zk *ZKStore) CreateDrain(d *types.Drain) error {
+	bs, err := encode(d)
+	if err != nil {
+		return err
+	}
+
+	p := path.Join(keyDrains, d.AgentID)
+
+	return zk.createAll(p, bs)
+}
+
+func (zk *ZKStore) UpdateDrain(d *types.Drain) error {
+	bs, err := encode(d)
+	if err != nil {
+		return err
+	}
+
+	p := path.Join(keyDrains, d.AgentID)
+
+	return zk.set(p, bs)
+}
+
+func (zk *ZKStore) GetDrain(agentId string) (*types.Drain, error) {
+	p := path.Join(keyDrains, agentId)
+
+	data, _, err := zk.get(p)
+	if err != nil {
+		log.Errorf("find agent %s drain got error: %v", agentId, err)
+		return nil, err
+	}
+
+	var d types.Drain
+	if err := decode(data, &d); err != nil {
+		return nil, err
+	}
+
+	return &d, nil
+}
+
+func (zk *ZKStore) DeleteDrain(agentId string) error {
+	p := path.Join(keyDrains, agentId)
+
+	return zk.del(p)
+}
+
+func (zk *ZKStore) ListDrains() ([]*types.Drain, error) {
+	drains := make([]*types.Drain, 0)
+
+	children, err := zk.list(keyDrains)
+	if err != nil {
+		if err == errNotExists {
+			return drains, nil
+		}
+		log.Errorf("get drains error: %v", err)
+		return nil, err
+	}
+
+	for _, child := range children {
+		data, _, err := zk.get(path.Join(keyDrains, child))
+		if err != nil {
+			log.Errorf("find agent %s drain got error: %v", child, err)
+			return nil, err
+		}
+
+		var d types.Drain
+		if err := decode(data, &d); err != nil {
+			return nil, err
+		}
+
+		drains = append(drains, &d)
+	}
+
+	return drains, nil
+}
@@ -0,0 +1,100 @@
+package zk
+
+import (
+	"path"
+
+	"github.com/Dataman-Cloud/swan/types"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+func (zk *ZKStore) CreateSchedule(aid string, s *types.Schedule) error {
+	bs, err := encode(s)
+	if err != nil {
+		return err
+	}
+
+	p := path.Join(keyApp, aid, "schedules", s.ID)
+
+	return zk.createAll(p, bs)
+}
+
+func (zk *ZKStore) UpdateSchedule(aid string, s *types.Schedule) error {
+	bs, err := encode(s)
+	if err != nil {
+		return err
+	}
+
+	p := path.Join(keyApp, aid, "schedules", s.ID)
+
+	return zk.set(p, bs)
+}
+
+func (zk *ZKStore) GetSchedule(aid, sid string) (*types.Schedule, error) {
+	p := path.Join(keyApp, aid, "schedules", sid)
+
+	data, _, err := zk.get(p)
+	if err != nil {
+		log.Errorf("find app %s schedule %s got error: %v", aid, sid, err)
+		return nil, err
+	}
+
+	var s types.Schedule
+	if err := decode(data, &s); err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+func (zk *ZKStore) DeleteSchedule(aid, sid string) error {
+	p := path.Join(keyApp, aid, "schedules", sid)
+
+	return zk.del(p)
+}
+
+func (zk *ZKStore) ListSchedules(aid string) ([]*types.Schedule, error) {
+	p := path.Join(keyApp, aid, "schedules")
+
+	schedules := make([]*types.Schedule, 0)
+
+	children, err := zk.list(p)
+	if err != nil {
+		if err == errNotExists {
+			return schedules, nil
+		}
+		log.Errorf("get app %s children(schedules) error: %v", aid, err)
+		return nil, err
+	}
+
+	for _, child := range children {
+		s, err := zk.GetSchedule(aid, child)
+		if err != nil {
+			return nil, err
+		}
+
+		schedules = append(schedules, s)
+	}
+
+	return schedules, nil
+}
+
+func (zk *ZKStore) deleteSchedules(aid string) error {
+	p := path.Join(keyApp, aid, "schedules")
+
+	children, err := zk.list(p)
+	if err != nil {
+		if err == errNotExists {
+			return nil
+		}
+		return err
+	}
+
+	for _, child := range children {
+		if err := zk.del(path.Join(p, child)); err != nil {
+			return err
+		}
+	}
+
+	return zk.del(p)
+}
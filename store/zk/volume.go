@@ -0,0 +1,78 @@
+package zk
+
+import (
+	"path"
+
+	"github.com/Dataman-Cloud/swan/types"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+func (zk *ZKStore) CreateVolume(aid string, v *types.PersistentVolume) error {
+	bs, err := encode(v)
+	if err != nil {
+		return err
+	}
+
+	p := path.Join(keyApp, aid, "volumes", v.ID)
+
+	return zk.createAll(p, bs)
+}
+
+func (zk *ZKStore) DeleteVolume(aid, vid string) error {
+	p := path.Join(keyApp, aid, "volumes", vid)
+
+	return zk.del(p)
+}
+
+func (zk *ZKStore) ListVolumes(aid string) ([]*types.PersistentVolume, error) {
+	p := path.Join(keyApp, aid, "volumes")
+
+	volumes := make([]*types.PersistentVolume, 0)
+
+	children, err := zk.list(p)
+	if err != nil {
+		if err == errNotExists {
+			return volumes, nil
+		}
+		log.Errorf("get app %s children(volumes) error: %v", aid, err)
+		return nil, err
+	}
+
+	for _, child := range children {
+		data, _, err := zk.get(path.Join(p, child))
+		if err != nil {
+			log.Errorf("find app %s volume %s got error: %v", aid, child, err)
+			return nil, err
+		}
+
+		var v types.PersistentVolume
+		if err := decode(data, &v); err != nil {
+			return nil, err
+		}
+
+		volumes = append(volumes, &v)
+	}
+
+	return volumes, nil
+}
+
+func (zk *ZKStore) deleteVolumes(aid string) error {
+	p := path.Join(keyApp, aid, "volumes")
+
+	children, err := zk.list(p)
+	if err != nil {
+		if err == errNotExists {
+			return nil
+		}
+		return err
+	}
+
+	for _, child := range children {
+		if err := zk.del(path.Join(p, child)); err != nil {
+			return err
+		}
+	}
+
+	return zk.del(p)
+}
@@ -0,0 +1,55 @@
+package types
+
+import (
+	"errors"
+
+	"github.com/Dataman-Cloud/swan/utils/labels"
+)
+
+const (
+	Affinity     = "affinity"
+	AntiAffinity = "anti-affinity"
+)
+
+// AffinityRule places the tasks on (affinity) or away from (anti-affinity)
+// the agents running the tasks of other apps, the apps are selected by app
+// id or label selector, eg: "tier=cache,env in (prod,staging)".
+type AffinityRule struct {
+	Type     string `json:"type"`
+	AppID    string `json:"appId,omitempty"`
+	Selector string `json:"selector,omitempty"`
+}
+
+func (r *AffinityRule) Valid() error {
+	switch r.Type {
+	case Affinity, AntiAffinity:
+	default:
+		return errors.New("unsupported affinity type: " + r.Type)
+	}
+
+	if (r.AppID == "") == (r.Selector == "") {
+		return errors.New("one of appId and selector required for affinity rule")
+	}
+
+	if r.Selector != "" {
+		if _, err := labels.Parse(r.Selector); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Matches reports whether the app is selected by the rule.
+func (r *AffinityRule) Matches(appId string, appLabels map[string]string) bool {
+	if r.AppID != "" {
+		return r.AppID == appId
+	}
+
+	selector, err := labels.Parse(r.Selector)
+	if err != nil {
+		return false
+	}
+
+	return selector.Matches(labels.Set(appLabels))
+}
@@ -20,6 +20,8 @@ const (
 	OpStatusStopping         = "stopping"
 	OpStatusDeleting         = "deleting"
 	OpStatusRollback         = "rollbacking"
+	OpStatusPaused           = "paused"
+	OpStatusDraining         = "draining"
 )
 
 type Application struct {
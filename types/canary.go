@@ -1,16 +1,79 @@
 package types
 
+import (
+	"errors"
+	"fmt"
+)
+
 const (
 	DefaultCanaryUpdateDelay = 5
+	DefaultCanarySoak        = 60
 
 	CanaryUpdateOnFailureStop     = "stop"
 	CanaryUpdateOnFailureContinue = "continue"
 )
 
 type CanaryUpdateBody struct {
-	Version   *Version `json:"version"`
-	Instances int      `json:"instances"`
-	Value     float64  `json:"value"`
-	OnFailure string   `json:"onFailure"`
-	Delay     float64  `json:"delay"`
+	Version   *Version    `json:"version"`
+	Instances int         `json:"instances"`
+	Value     float64     `json:"value"`
+	OnFailure string      `json:"onFailure"`
+	Delay     float64     `json:"delay"`
+	Plan      *CanaryPlan `json:"plan,omitempty"`
+}
+
+// CanaryPlan describes a progressive canary update which advances step by step
+// automatically. After each step, the new version tasks must stay healthy for
+// `Soak` seconds, and the proxy error rate of them must not exceed `MaxErrorRate`,
+// otherwise the plan is aborted and all of traffics go back to the old version.
+type CanaryPlan struct {
+	Steps        []*CanaryStep `json:"steps"`
+	Soak         float64       `json:"soak"`         // by seconds
+	MaxErrorRate float64       `json:"maxErrorRate"` // 0 means error rate is not checked
+}
+
+type CanaryStep struct {
+	Instances int     `json:"instances"` // total nb of new version tasks after this step, computed by value if 0
+	Value     float64 `json:"value"`     // traffic weight of the new version tasks, (0, 1]
+}
+
+func (p *CanaryPlan) Valid() error {
+	if len(p.Steps) == 0 {
+		return errors.New("canary plan steps required")
+	}
+
+	var (
+		lastValue     float64
+		lastInstances int
+	)
+
+	for i, step := range p.Steps {
+		if step.Value <= 0 || step.Value > 1 {
+			return fmt.Errorf("canary plan step %d: value must between (0, 1]", i+1)
+		}
+		if step.Value <= lastValue {
+			return fmt.Errorf("canary plan step %d: value must be greater than previous step", i+1)
+		}
+		if step.Instances < 0 {
+			return fmt.Errorf("canary plan step %d: instances can't be negative", i+1)
+		}
+		if step.Instances > 0 && step.Instances < lastInstances {
+			return fmt.Errorf("canary plan step %d: instances can't be less than previous step", i+1)
+		}
+
+		lastValue = step.Value
+		if step.Instances > 0 {
+			lastInstances = step.Instances
+		}
+	}
+
+	if p.Soak < 0 {
+		return errors.New("canary plan soak can't be negative")
+	}
+
+	if p.MaxErrorRate < 0 || p.MaxErrorRate > 1 {
+		return errors.New("canary plan maxErrorRate must between [0, 1]")
+	}
+
+	return nil
 }
@@ -0,0 +1,58 @@
+package types
+
+import "testing"
+
+func TestCanaryPlanValid(t *testing.T) {
+	tests := []struct {
+		name    string
+		plan    *CanaryPlan
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			plan: &CanaryPlan{
+				Steps:        []*CanaryStep{{Value: 0.1}, {Instances: 2, Value: 0.5}, {Instances: 4, Value: 1}},
+				Soak:         30,
+				MaxErrorRate: 0.05,
+			},
+		},
+		{
+			name:    "no steps",
+			plan:    &CanaryPlan{},
+			wantErr: true,
+		},
+		{
+			name:    "value out of range",
+			plan:    &CanaryPlan{Steps: []*CanaryStep{{Value: 1.5}}},
+			wantErr: true,
+		},
+		{
+			name:    "value not increasing",
+			plan:    &CanaryPlan{Steps: []*CanaryStep{{Value: 0.5}, {Value: 0.5}}},
+			wantErr: true,
+		},
+		{
+			name:    "instances decreasing",
+			plan:    &CanaryPlan{Steps: []*CanaryStep{{Instances: 3, Value: 0.2}, {Instances: 2, Value: 0.4}}},
+			wantErr: true,
+		},
+		{
+			name:    "negative soak",
+			plan:    &CanaryPlan{Steps: []*CanaryStep{{Value: 1}}, Soak: -1},
+			wantErr: true,
+		},
+		{
+			name:    "error rate out of range",
+			plan:    &CanaryPlan{Steps: []*CanaryStep{{Value: 1}}, MaxErrorRate: 2},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.plan.Valid(); (err != nil) != tt.wantErr {
+				t.Errorf("CanaryPlan.Valid() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -4,9 +4,19 @@ import (
 	"errors"
 	"fmt"
 	"regexp"
+	"strconv"
+	"strings"
 )
 
-var supportedOperator = []string{"==", "!=", "~="}
+const (
+	ConstraintUnique  = "UNIQUE"
+	ConstraintGroupBy = "GROUP_BY"
+	ConstraintMaxPer  = "MAX_PER"
+	ConstraintIn      = "IN"
+	ConstraintNotIn   = "NOT_IN"
+)
+
+var supportedOperator = []string{"==", "!=", "~=", ConstraintUnique, ConstraintGroupBy, ConstraintMaxPer, ConstraintIn, ConstraintNotIn}
 
 type Constraint struct {
 	Attribute string `yaml:"attribute" json:"attribute"`
@@ -18,6 +28,25 @@ func (c *Constraint) validate() error {
 	if c.Attribute == "" {
 		return errors.New("attribute required for constraint")
 	}
+
+	switch c.Operator {
+	case ConstraintMaxPer:
+		if n, err := strconv.Atoi(c.Value); err != nil || n <= 0 {
+			return errors.New("value of MAX_PER constraint should be positive integer")
+		}
+	case ConstraintGroupBy:
+		if c.Value == "" {
+			return nil
+		}
+		if n, err := strconv.Atoi(c.Value); err != nil || n <= 0 {
+			return errors.New("value of GROUP_BY constraint should be positive integer")
+		}
+	case ConstraintIn, ConstraintNotIn:
+		if len(c.values()) == 0 {
+			return fmt.Errorf("value list required for %s constraint", c.Operator)
+		}
+	}
+
 	for _, str := range supportedOperator {
 		if str == c.Operator {
 			return nil
@@ -27,6 +56,19 @@ func (c *Constraint) validate() error {
 	return fmt.Errorf("Operator not supported. supported operators is %v", supportedOperator)
 }
 
+// IsPlacement reports whether the constraint depends on where the app's tasks
+// already run, rather than the agent attributes only.
+func (c *Constraint) IsPlacement() bool {
+	switch c.Operator {
+	case ConstraintUnique, ConstraintGroupBy, ConstraintMaxPer:
+		return true
+	}
+	return false
+}
+
+// Match checks the agent attributes against the constraint, the placement
+// constraints are always matched here, they're checked by the scheduler filter.
+// the agent without the attribute never matches, including NOT_IN.
 func (c *Constraint) Match(attrs map[string]string) bool {
 	for k, v := range attrs {
 		if k == c.Attribute {
@@ -37,6 +79,12 @@ func (c *Constraint) Match(attrs map[string]string) bool {
 				return not(c.Value, v)
 			case "~=":
 				return like(c.Value, v)
+			case ConstraintIn:
+				return in(c.values(), v)
+			case ConstraintNotIn:
+				return !in(c.values(), v)
+			case ConstraintUnique, ConstraintGroupBy, ConstraintMaxPer:
+				return true
 			}
 		}
 	}
@@ -44,6 +92,17 @@ func (c *Constraint) Match(attrs map[string]string) bool {
 	return false
 }
 
+// values split the comma separated value list of IN, NOT_IN constraint.
+func (c *Constraint) values() []string {
+	vals := make([]string, 0)
+	for _, v := range strings.Split(c.Value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			vals = append(vals, v)
+		}
+	}
+	return vals
+}
+
 func equal(n, m string) bool {
 	return n == m
 }
@@ -56,3 +115,12 @@ func like(n, m string) bool {
 	matched, _ := regexp.MatchString(n, m)
 	return matched
 }
+
+func in(list []string, m string) bool {
+	for _, n := range list {
+		if n == m {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,136 @@
+package types
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	DeploymentCreate   = "create"
+	DeploymentScale    = "scale"
+	DeploymentUpdate   = "update"
+	DeploymentCanary   = "canary"
+	DeploymentRollback = "rollback"
+	DeploymentStart    = "start"
+	DeploymentStop     = "stop"
+	DeploymentDrain    = "drain"
+
+	DeploymentRunning = "running"
+	DeploymentSucceed = "succeed"
+	DeploymentFailed  = "failed"
+
+	DeploymentTaskLaunch = "launch"
+	DeploymentTaskKill   = "kill"
+
+	// the deployment records kept for each app, the oldest are removed beyond
+	MaxDeploymentHistory = 50
+)
+
+// Deployment is the history record of an app operation, unlike App.OpStatus
+// it's kept even the operation finished.
+type Deployment struct {
+	ID           string            `json:"id"`
+	AppID        string            `json:"appId"`
+	Type         string            `json:"type"`
+	TriggeredBy  string            `json:"triggeredBy"`
+	FromVersions []string          `json:"fromVersions"`
+	ToVersion    string            `json:"toVersion"`
+	Status       string            `json:"status"`
+	Tasks        []*DeploymentTask `json:"tasks"`
+	ErrMsg       string            `json:"errmsg"`
+	StartedAt    time.Time         `json:"startedAt"`
+	FinishedAt   time.Time         `json:"finishedAt"`
+
+	mu sync.Mutex // protect Tasks
+}
+
+// DeploymentTask is the outcome of one task step within a deployment.
+type DeploymentTask struct {
+	TaskID  string    `json:"taskId"`
+	Name    string    `json:"name"`
+	Version string    `json:"version"`
+	Action  string    `json:"action"`
+	Result  string    `json:"result"`
+	ErrMsg  string    `json:"errmsg,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+func NewDeployment(appId, typ, triggeredBy string, from []string, to string) *Deployment {
+	return &Deployment{
+		ID:           strconv.FormatInt(time.Now().UTC().UnixNano(), 10),
+		AppID:        appId,
+		Type:         typ,
+		TriggeredBy:  triggeredBy,
+		FromVersions: from,
+		ToVersion:    to,
+		Status:       DeploymentRunning,
+		Tasks:        make([]*DeploymentTask, 0),
+		StartedAt:    time.Now(),
+	}
+}
+
+// AddTask memo the outcome of a task step, it's safe for concurrent use.
+func (d *Deployment) AddTask(taskId, name, version, action string, err error) {
+	t := &DeploymentTask{
+		TaskID:  taskId,
+		Name:    name,
+		Version: version,
+		Action:  action,
+		Result:  DeploymentSucceed,
+		Time:    time.Now(),
+	}
+
+	if err != nil {
+		t.Result = DeploymentFailed
+		t.ErrMsg = err.Error()
+	}
+
+	d.mu.Lock()
+	d.Tasks = append(d.Tasks, t)
+	d.mu.Unlock()
+}
+
+// Finish mark the deployment finished with the final error.
+func (d *Deployment) Finish(err error) {
+	d.Status = DeploymentSucceed
+	if err != nil {
+		d.Status = DeploymentFailed
+		d.ErrMsg = err.Error()
+	}
+	d.FinishedAt = time.Now()
+}
+
+type DeploymentList []*Deployment
+
+func (dl DeploymentList) Len() int      { return len(dl) }
+func (dl DeploymentList) Swap(i, j int) { dl[i], dl[j] = dl[j], dl[i] }
+func (dl DeploymentList) Less(i, j int) bool {
+	m, _ := strconv.ParseInt(dl[i].ID, 10, 64)
+	n, _ := strconv.ParseInt(dl[j].ID, 10, 64)
+
+	return m < n
+}
+
+// Reverse sort the deployments from latest to oldest
+func (dl DeploymentList) Reverse() {
+	sort.Sort(sort.Reverse(dl))
+}
+
+// ExpiredDeployments picks the oldest deployment ids beyond the history limit.
+func ExpiredDeployments(ids []string) []string {
+	if len(ids) <= MaxDeploymentHistory {
+		return nil
+	}
+
+	sorted := make([]string, len(ids))
+	copy(sorted, ids)
+	sort.Slice(sorted, func(i, j int) bool {
+		m, _ := strconv.ParseInt(sorted[i], 10, 64)
+		n, _ := strconv.ParseInt(sorted[j], 10, 64)
+		return m < n
+	})
+
+	return sorted[:len(sorted)-MaxDeploymentHistory]
+}
@@ -0,0 +1,30 @@
+package types
+
+import "time"
+
+const (
+	DrainStatusDraining = "draining"
+	DrainStatusDrained  = "drained"
+	DrainStatusFailed   = "failed"
+
+	DrainReasonMaintenance = "maintenance" // drained by the mesos inverse offer
+)
+
+// Drain marks the agent unschedulable, the tasks on it are moved to the
+// other agents app by app.
+type Drain struct {
+	AgentID string     `json:"agent_id"`
+	Status  string     `json:"status"`
+	Moved   int        `json:"moved"`            // tasks moved to the other agents
+	Pinned  []string   `json:"pinned,omitempty"` // tasks bound to the persistent volumes, couldn't be moved
+	ErrMsg  string     `json:"errmsg"`
+	Reason  string     `json:"reason,omitempty"` // empty if drained by the api
+	Until   *time.Time `json:"until,omitempty"`  // the maintenance window end, nil if infinite
+	Created time.Time  `json:"created"`
+	Updated time.Time  `json:"updated"`
+}
+
+// Expired tells whether the maintenance window of the drain has passed.
+func (d *Drain) Expired() bool {
+	return d.Reason == DrainReasonMaintenance && d.Until != nil && time.Now().After(*d.Until)
+}
@@ -3,6 +3,7 @@ package types
 import (
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/Dataman-Cloud/swan/agent/janitor/upstream"
 	"github.com/Dataman-Cloud/swan/agent/resolver"
@@ -12,6 +13,11 @@ const (
 	EventTypeTaskHealthy      = "task_healthy"
 	EventTypeTaskWeightChange = "task_weight_change"
 	EventTypeTaskUnhealthy    = "task_unhealthy"
+
+	EventTypeAppAutoScale  = "app_autoscale"
+	EventTypeAppPreemption = "app_preemption"
+	EventTypeTaskPreempted = "task_preempted"
+	EventTypeTaskRequeued  = "task_requeued"
 )
 
 type CombinedEvents struct {
@@ -21,19 +27,24 @@ type CombinedEvents struct {
 }
 
 type TaskEvent struct {
-	Type           string  `json:"type"`
-	AppID          string  `json:"app_id"`
-	AppAlias       string  `json:"app_alias"`  // for proxy
-	AppListen      string  `json:"app_listen"` // for proxy
-	AppSticky      bool    `json:"app_sticky"` // for proxy
-	VersionID      string  `json:"version_id"`
-	AppVersion     string  `json:"app_version"`
-	TaskID         string  `json:"task_id"`
-	IP             string  `json:"task_ip"`
-	Port           uint64  `json:"task_port"`
-	TargetPort     uint64  `json:"target_port"`
-	Weight         float64 `json:"weihgt"`
-	GatewayEnabled bool    `json:"gateway"` // for proxy
+	Type           string            `json:"type"`
+	AppID          string            `json:"app_id"`
+	AppAlias       string            `json:"app_alias"`                  // for proxy
+	AppListen      string            `json:"app_listen"`                 // for proxy
+	AppSticky      bool              `json:"app_sticky"`                 // for proxy
+	AppRules       []*ProxyRule      `json:"app_rules,omitempty"`        // for proxy
+	AppHealthCheck *ProxyHealthCheck `json:"app_health_check,omitempty"` // for proxy
+	AppRetry       *ProxyRetry       `json:"app_retry,omitempty"`        // for proxy
+	AppBalance     *ProxyBalance     `json:"app_balance,omitempty"`      // for proxy
+	AppStickiness  *ProxyStickiness  `json:"app_stickiness,omitempty"`   // for proxy
+	VersionID      string            `json:"version_id"`
+	AppVersion     string            `json:"app_version"`
+	TaskID         string            `json:"task_id"`
+	IP             string            `json:"task_ip"`
+	Port           uint64            `json:"task_port"`
+	TargetPort     uint64            `json:"target_port"`
+	Weight         float64           `json:"weihgt"`
+	GatewayEnabled bool              `json:"gateway"` // for proxy
 }
 
 // Format format task events to SSE text
@@ -41,3 +52,18 @@ func (e *TaskEvent) Format() []byte {
 	bs, _ := json.Marshal(e)
 	return []byte(fmt.Sprintf("event: %s\ndata: %s\n\n", e.Type, string(bs)))
 }
+
+// AppEvent is the app level event, eg: the autoscale decisions.
+type AppEvent struct {
+	Type    string      `json:"type"`
+	AppID   string      `json:"app_id"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+	Time    time.Time   `json:"time"`
+}
+
+// Format format app events to SSE text
+func (e *AppEvent) Format() []byte {
+	bs, _ := json.Marshal(e)
+	return []byte(fmt.Sprintf("event: %s\ndata: %s\n\n", e.Type, string(bs)))
+}
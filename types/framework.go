@@ -1,5 +1,15 @@
 package types
 
 type FrameworkInfo struct {
-	ID string
+	ID   string
+	Role string
+}
+
+// OfferStats counts the mesos offers handled by the scheduler.
+type OfferStats struct {
+	Received   int64 `json:"received"`
+	Used       int64 `json:"used"`
+	Declined   int64 `json:"declined"`
+	Rescinded  int64 `json:"rescinded"`
+	Suppressed bool  `json:"suppressed"`
 }
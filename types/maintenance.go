@@ -0,0 +1,27 @@
+package types
+
+import "time"
+
+// MaintenanceWindow is the unavailability of the machine in the mesos
+// maintenance schedule.
+type MaintenanceWindow struct {
+	Hostname string     `json:"hostname,omitempty"`
+	IP       string     `json:"ip,omitempty"`
+	Start    time.Time  `json:"start"`
+	End      *time.Time `json:"end,omitempty"` // nil if the unavailability is infinite
+}
+
+// Match tells whether the window is of the machine with the hostname or ips.
+func (w *MaintenanceWindow) Match(hostname string, ips ...string) bool {
+	if w.Hostname != "" && w.Hostname == hostname {
+		return true
+	}
+
+	for _, ip := range ips {
+		if w.IP != "" && w.IP == ip {
+			return true
+		}
+	}
+
+	return false
+}
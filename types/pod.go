@@ -0,0 +1,361 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/Dataman-Cloud/swan/mesosproto"
+	"github.com/gogo/protobuf/proto"
+)
+
+const (
+	ContainerizerDocker = "docker"
+	ContainerizerMesos  = "mesos"
+
+	// image type of the mesos containerizer
+	ImageDocker = "docker"
+	ImageAppc   = "appc"
+
+	// the resources of the mesos default executor running the pod
+	PodExecutorCPUs = 0.1
+	PodExecutorMem  = 32.0
+	PodExecutorDisk = 10.0
+)
+
+var podContainerName = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+
+// MesosContainer describes the container run by the mesos containerizer (UCR),
+// the image is pulled & provisioned by mesos instead of the docker daemon.
+type MesosContainer struct {
+	Image          string         `json:"image,omitempty"` // not required by pod
+	ImageType      string         `json:"imageType,omitempty"`
+	ForcePullImage bool           `json:"forcePullImage,omitempty"`
+	Network        string         `json:"network,omitempty"` // host or the name of the CNI network
+	PortMappings   []*PortMapping `json:"portMappings,omitempty"`
+}
+
+func (m *MesosContainer) Valid() error {
+	switch m.ImageType {
+	case "", ImageDocker, ImageAppc:
+	default:
+		return errors.New("unsupported image type, should be [docker,appc]")
+	}
+
+	switch m.Network {
+	case "":
+		return errors.New("network required")
+	case "bridge", "none":
+		return errors.New("mesos containerizer only support host or CNI network")
+	}
+
+	seen := make(map[string]bool)
+	for _, pm := range m.PortMappings {
+		if err := pm.Valid(); err != nil {
+			return err
+		}
+		if _, ok := seen[pm.Name]; ok {
+			return fmt.Errorf("port name %s conflict", pm.Name)
+		}
+		seen[pm.Name] = true
+	}
+
+	return nil
+}
+
+// PodContainer is one of the containers of the pod, the containers of a pod
+// instance are launched together as a mesos task group by the default executor,
+// sharing the network and the pod volumes.
+type PodContainer struct {
+	Name    string            `json:"name"`
+	Image   string            `json:"image"`
+	Command string            `json:"cmd,omitempty"`
+	CPUs    float64           `json:"cpus"`
+	Mem     float64           `json:"mem"`
+	Disk    float64           `json:"disk,omitempty"`
+	Env     map[string]string `json:"env,omitempty"`
+	Volumes []*Volume         `json:"volumes,omitempty"` // host path volumes
+	Mounts  []*VolumeMount    `json:"mounts,omitempty"`
+}
+
+// VolumeMount mounts the pod volume by name, which is created in the sandbox
+// of the executor and shared among the containers mounting it.
+type VolumeMount struct {
+	Name          string `json:"name"`
+	ContainerPath string `json:"containerPath"`
+	Mode          string `json:"mode,omitempty"` // RO or RW, default RW
+}
+
+func (c *PodContainer) Valid() error {
+	if !podContainerName.MatchString(c.Name) {
+		return fmt.Errorf("invalid pod container name %q, should be lowercase alphanumeric or '-'", c.Name)
+	}
+	if c.Image == "" {
+		return fmt.Errorf("pod container %s image required", c.Name)
+	}
+	if c.CPUs <= 0 || c.Mem <= 0 {
+		return fmt.Errorf("pod container %s cpus & mem must be positive", c.Name)
+	}
+	if c.Disk < 0 {
+		return fmt.Errorf("pod container %s disk can't be negative", c.Name)
+	}
+
+	for _, v := range c.Volumes {
+		if v.Persistent != nil {
+			return errors.New("persistent volume not supported by pod")
+		}
+		if err := v.Valid(); err != nil {
+			return err
+		}
+	}
+
+	for _, m := range c.Mounts {
+		if m.Name == "" || strings.Contains(m.Name, "/") {
+			return fmt.Errorf("invalid pod volume name %q", m.Name)
+		}
+		if !path.IsAbs(m.ContainerPath) {
+			return errors.New("VolumeMount.ContainerPath should be absolute path")
+		}
+		switch m.Mode {
+		case "", "RO", "RW":
+		default:
+			return errors.New("unsupported VolumeMount.Mode, should be [RO,RW]")
+		}
+	}
+
+	return nil
+}
+
+// IsPod tells whether the version runs the pod of multiple containers.
+func (v *Version) IsPod() bool {
+	return len(v.Containers) > 0
+}
+
+func (v *Version) validPod() error {
+	if !v.Container.IsMesos() {
+		return errors.New("pod requires mesos containerizer")
+	}
+
+	if len(v.PersistentVolumes()) > 0 {
+		return errors.New("persistent volume not supported by pod")
+	}
+
+	seen := make(map[string]bool)
+	for _, c := range v.Containers {
+		if err := c.Valid(); err != nil {
+			return err
+		}
+		if seen[c.Name] {
+			return fmt.Errorf("pod container name %s conflict", c.Name)
+		}
+		seen[c.Name] = true
+	}
+
+	return nil
+}
+
+// PodContainerTaskId returns the mesos task id of the pod container, the first
+// container takes the id of the pod instance, so killing the instance kills the
+// whole task group.
+func PodContainerTaskId(id, container string) string {
+	parts := strings.SplitN(id, ".", 2)
+	if len(parts) < 2 {
+		return id
+	}
+	return fmt.Sprintf("%s-%s.%s", parts[0], container, parts[1])
+}
+
+// PodTaskId returns the id of the pod instance by the mesos task id of its
+// container, the others are returned as is.
+func PodTaskId(taskId string) string {
+	parts := strings.SplitN(taskId, ".", 2)
+	if len(parts) < 2 {
+		return taskId
+	}
+
+	if i := strings.Index(parts[0], "-"); i > 0 {
+		return parts[0][:i] + "." + parts[1]
+	}
+	return taskId
+}
+
+func (c *TaskConfig) IsPod() bool {
+	return len(c.Containers) > 0
+}
+
+// BuildExecutor builds the default executor running the pod instance, which
+// holds the network of the pod and the resources of the pod ports.
+func (c *TaskConfig) BuildExecutor(id string) *mesosproto.ExecutorInfo {
+	rs := scalarResources(PodExecutorCPUs, PodExecutorMem, PodExecutorDisk)
+	for _, r := range c.BuildResources() {
+		if r.GetName() == "ports" {
+			rs = append(rs, r)
+		}
+	}
+
+	return &mesosproto.ExecutorInfo{
+		Type:       mesosproto.ExecutorInfo_DEFAULT.Enum(),
+		ExecutorId: &mesosproto.ExecutorID{Value: proto.String("executor." + id)},
+		Resources:  rs,
+		Container: &mesosproto.ContainerInfo{
+			Type:         mesosproto.ContainerInfo_MESOS.Enum(),
+			NetworkInfos: c.networkInfos(),
+		},
+	}
+}
+
+// BuildTaskGroup builds the tasks of the pod containers.
+func (c *TaskConfig) BuildTaskGroup(id, name string) []*mesosproto.TaskInfo {
+	tasks := make([]*mesosproto.TaskInfo, 0, len(c.Containers))
+
+	for i, ct := range c.Containers {
+		taskId := id
+		if i > 0 {
+			taskId = PodContainerTaskId(id, ct.Name)
+		}
+
+		cmd := &mesosproto.CommandInfo{Shell: proto.Bool(false)}
+		if ct.Command != "" {
+			cmd = &mesosproto.CommandInfo{
+				Uris:  c.uris(),
+				Shell: proto.Bool(true),
+				Value: proto.String(ct.Command),
+			}
+		}
+		cmd.Environment = c.environment(id, name, ct.Env)
+
+		volumes := append(c.volumes(), mesosVolumes(ct.Volumes)...)
+		for _, m := range ct.Mounts {
+			mode := mesosproto.Volume_RW
+			if m.Mode == "RO" {
+				mode = mesosproto.Volume_RO
+			}
+
+			volumes = append(volumes, &mesosproto.Volume{
+				ContainerPath: proto.String(m.ContainerPath),
+				Mode:          &mode,
+				Source: &mesosproto.Volume_Source{
+					Type: mesosproto.Volume_Source_SANDBOX_PATH.Enum(),
+					SandboxPath: &mesosproto.Volume_Source_SandboxPath{
+						Type: mesosproto.Volume_Source_SandboxPath_PARENT.Enum(),
+						Path: proto.String(path.Join("volumes", m.Name)),
+					},
+				},
+			})
+		}
+
+		task := &mesosproto.TaskInfo{
+			Name:      proto.String(ct.Name + "." + name),
+			TaskId:    &mesosproto.TaskID{Value: proto.String(taskId)},
+			Resources: scalarResources(ct.CPUs, ct.Mem, ct.Disk),
+			Command:   cmd,
+			Container: &mesosproto.ContainerInfo{
+				Type:    mesosproto.ContainerInfo_MESOS.Enum(),
+				Volumes: volumes,
+				Mesos:   &mesosproto.ContainerInfo_MesosInfo{Image: c.image(ct.Image)},
+			},
+			Labels: c.BuildLabels(id, name),
+		}
+
+		// the health check of the pod goes with the first container
+		if i == 0 && c.HealthCheck != nil {
+			task.HealthCheck = c.BuildHealthCheck()
+		}
+
+		tasks = append(tasks, task)
+	}
+
+	return tasks
+}
+
+func (c *TaskConfig) buildMesosContainer() *mesosproto.ContainerInfo {
+	return &mesosproto.ContainerInfo{
+		Type:         mesosproto.ContainerInfo_MESOS.Enum(),
+		Volumes:      c.volumes(),
+		NetworkInfos: c.networkInfos(),
+		Mesos:        &mesosproto.ContainerInfo_MesosInfo{Image: c.image(c.Image)},
+	}
+}
+
+func (c *TaskConfig) image(name string) *mesosproto.Image {
+	img := &mesosproto.Image{
+		Type:   mesosproto.Image_DOCKER.Enum(),
+		Docker: &mesosproto.Image_Docker{Name: proto.String(name)},
+		Cached: proto.Bool(!c.ForcePullImage),
+	}
+
+	if c.ImageType == ImageAppc {
+		img.Type = mesosproto.Image_APPC.Enum()
+		img.Docker = nil
+		img.Appc = &mesosproto.Image_Appc{Name: proto.String(name)}
+	}
+
+	return img
+}
+
+// networkInfos joins the container to the CNI network, nothing for host network.
+func (c *TaskConfig) networkInfos() []*mesosproto.NetworkInfo {
+	if c.Network == "host" {
+		return nil
+	}
+
+	info := &mesosproto.NetworkInfo{Name: proto.String(c.Network)}
+	if c.IP != "" {
+		info.IpAddresses = []*mesosproto.NetworkInfo_IPAddress{
+			{IpAddress: proto.String(c.IP)},
+		}
+	}
+
+	return []*mesosproto.NetworkInfo{info}
+}
+
+// BuildEnvironment builds the environment of the task run by the mesos containerizer.
+func (c *TaskConfig) BuildEnvironment(id, name string) *mesosproto.Environment {
+	return c.environment(id, name)
+}
+
+// environment is passed by the command as the mesos containerizer doesn't take
+// the docker parameters.
+func (c *TaskConfig) environment(id, name string, envs ...map[string]string) *mesosproto.Environment {
+	vars := make([]*mesosproto.Environment_Variable, 0)
+
+	add := func(m map[string]string) {
+		for k, v := range m {
+			vars = append(vars, &mesosproto.Environment_Variable{
+				Name:  proto.String(k),
+				Value: proto.String(v),
+			})
+		}
+	}
+
+	add(c.extra(id, name))
+	add(c.Env)
+	for _, env := range envs {
+		add(env)
+	}
+
+	return &mesosproto.Environment{Variables: vars}
+}
+
+func scalarResources(cpus, mem, disk float64) []*mesosproto.Resource {
+	rs := make([]*mesosproto.Resource, 0, 3)
+
+	for _, r := range []struct {
+		name  string
+		value float64
+	}{{"cpus", cpus}, {"mem", mem}, {"disk", disk}} {
+		if r.value <= 0 {
+			continue
+		}
+
+		rs = append(rs, &mesosproto.Resource{
+			Name:   proto.String(r.name),
+			Type:   mesosproto.Value_SCALAR.Enum(),
+			Scalar: &mesosproto.Value_Scalar{Value: proto.Float64(r.value)},
+		})
+	}
+
+	return rs
+}
@@ -0,0 +1,139 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// the proxy configs of the app, which are converted to the janitor upstream
+// configs by the scheduler on sending the proxy records to the agents.
+
+// ProxyRule routes the http requests to the app by host, path, headers & methods.
+type ProxyRule struct {
+	Host       string            `json:"host,omitempty" yaml:"host,omitempty"` // default the proxy alias
+	PathPrefix string            `json:"pathPrefix,omitempty" yaml:"pathPrefix,omitempty"`
+	PathRegex  string            `json:"pathRegex,omitempty" yaml:"pathRegex,omitempty"`
+	Headers    map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"` // empty value matches any
+	Methods    []string          `json:"methods,omitempty" yaml:"methods,omitempty"`
+	Rewrite    string            `json:"rewrite,omitempty" yaml:"rewrite,omitempty"` // replaces the matched path prefix or regex
+	Priority   int               `json:"priority,omitempty" yaml:"priority,omitempty"`
+}
+
+func (r *ProxyRule) Valid() error {
+	if r == nil {
+		return errors.New("nil rule")
+	}
+
+	if r.PathPrefix != "" && r.PathRegex != "" {
+		return errors.New("rule path prefix & regex can't be both set")
+	}
+
+	if r.PathPrefix != "" && !strings.HasPrefix(r.PathPrefix, "/") {
+		return errors.New("rule path prefix should start with /")
+	}
+
+	if r.PathRegex != "" {
+		if _, err := regexp.Compile(r.PathRegex); err != nil {
+			return fmt.Errorf("rule path regex invalid: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// ProxyHealthCheck configures the backend health checking by the proxy,
+// independent of the task health checks.
+type ProxyHealthCheck struct {
+	Protocol    string  `json:"protocol,omitempty" yaml:"protocol,omitempty"`       // tcp or http, the active checks are disabled if empty
+	Path        string  `json:"path,omitempty" yaml:"path,omitempty"`               // http check path
+	Interval    float64 `json:"interval,omitempty" yaml:"interval,omitempty"`       // by seconds, default 5
+	Timeout     float64 `json:"timeout,omitempty" yaml:"timeout,omitempty"`         // by seconds, default 2
+	MaxFailures int     `json:"maxFailures,omitempty" yaml:"maxFailures,omitempty"` // consecutive failures to eject, default 3
+	CoolDown    float64 `json:"coolDown,omitempty" yaml:"coolDown,omitempty"`       // by seconds the backend stays ejected, default 30
+	Passive     bool    `json:"passive,omitempty" yaml:"passive,omitempty"`         // outlier detection by the connection failures & 5xx responses
+}
+
+func (hc *ProxyHealthCheck) Valid() error {
+	switch hc.Protocol {
+	case "", "tcp", "http":
+	default:
+		return errors.New("unsupported health check protocol, should be [tcp,http]")
+	}
+
+	if hc.Interval < 0 || hc.Timeout < 0 || hc.CoolDown < 0 || hc.MaxFailures < 0 {
+		return errors.New("health check interval, timeout, cool down & max failures can't be negative")
+	}
+
+	return nil
+}
+
+// ProxyRetry configures the failover of the failed connections or requests
+// to the other backends by the proxy.
+type ProxyRetry struct {
+	Retries        int     `json:"retries,omitempty" yaml:"retries,omitempty"`               // max retries on the other backends, 0 disables the failover
+	ConnectTimeout float64 `json:"connectTimeout,omitempty" yaml:"connectTimeout,omitempty"` // by seconds, default 60
+	OnStatus       bool    `json:"onStatus,omitempty" yaml:"onStatus,omitempty"`             // retry the idempotent http requests on 502 & 503 as well
+}
+
+func (r *ProxyRetry) Valid() error {
+	if r.Retries < 0 {
+		return errors.New("proxy retries can't be negative")
+	}
+	if r.ConnectTimeout < 0 {
+		return errors.New("proxy connect timeout can't be negative")
+	}
+	return nil
+}
+
+// ProxyBalance configures the load balancing algorithm of the proxy.
+type ProxyBalance struct {
+	Algorithm string `json:"algorithm" yaml:"algorithm"`                 // rr, wrr, swrr, leastconn, p2c, hash. default wrr
+	HashOn    string `json:"hashOn,omitempty" yaml:"hashOn,omitempty"`   // ip, header, cookie. default ip
+	HashKey   string `json:"hashKey,omitempty" yaml:"hashKey,omitempty"` // name of the header or cookie
+}
+
+func (bl *ProxyBalance) Valid() error {
+	switch bl.Algorithm {
+	case "", "rr", "wrr", "swrr", "leastconn", "p2c":
+	case "hash":
+		switch bl.HashOn {
+		case "", "ip":
+		case "header", "cookie":
+			if bl.HashKey == "" {
+				return errors.New("balance hash key required by hashing on header or cookie")
+			}
+		default:
+			return errors.New("unsupported balance hash on, should be [ip,header,cookie]")
+		}
+	default:
+		return errors.New("unsupported balance algorithm, should be [rr,wrr,swrr,leastconn,p2c,hash]")
+	}
+	return nil
+}
+
+// ProxyStickiness configures how the clients stick to the backends.
+type ProxyStickiness struct {
+	Mode    string  `json:"mode" yaml:"mode"`                           // ip, cookie, header. default ip
+	Name    string  `json:"name,omitempty" yaml:"name,omitempty"`       // name of the cookie or header, default SWAN_STICKY for the cookie
+	Timeout float64 `json:"timeout,omitempty" yaml:"timeout,omitempty"` // idle timeout of the sessions by seconds, default 3600
+}
+
+func (s *ProxyStickiness) Valid() error {
+	switch s.Mode {
+	case "", "ip", "cookie":
+	case "header":
+		if s.Name == "" {
+			return errors.New("sticky header name required")
+		}
+	default:
+		return errors.New("unsupported sticky mode, should be [ip,cookie,header]")
+	}
+
+	if s.Timeout < 0 {
+		return errors.New("sticky session timeout can't be negative")
+	}
+
+	return nil
+}
@@ -0,0 +1,49 @@
+package types
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	RepairRelaunch = "relaunch" // relaunch the missing task
+	RepairDrop     = "drop"     // drop the db record of the missing task
+	RepairKill     = "kill"     // kill the orphan task
+)
+
+// ReconcileReport is the diff between the tasks in the store and the tasks
+// of the framework known to the mesos master.
+type ReconcileReport struct {
+	Time    time.Time        `json:"time"`
+	Missing []*ReconcileTask `json:"missing"` // in the store, but unknown to mesos
+	Orphans []*ReconcileTask `json:"orphans"` // running on mesos, but not in the store
+
+	Unreachable []*ReconcileTask `json:"unreachable"` // on the unreachable agents, not repairable
+}
+
+type ReconcileTask struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	AppID   string `json:"app_id"`
+	AgentID string `json:"agent_id"`
+	State   string `json:"state"`
+}
+
+// ReconcileRepair is the repair action against one task of the report.
+type ReconcileRepair struct {
+	Action string `json:"action"`
+	TaskID string `json:"task_id"`
+}
+
+func (r *ReconcileRepair) Validate() error {
+	if r.TaskID == "" {
+		return errors.New("task_id required")
+	}
+
+	switch r.Action {
+	case RepairRelaunch, RepairDrop, RepairKill:
+		return nil
+	}
+
+	return errors.New("action must be one of relaunch, drop, kill")
+}
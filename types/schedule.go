@@ -0,0 +1,79 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Dataman-Cloud/swan/utils/cron"
+)
+
+const (
+	ScheduleScale = "scale"
+	ScheduleStart = "start"
+	ScheduleStop  = "stop"
+)
+
+// Schedule runs the app action periodically by the cron expression.
+type Schedule struct {
+	ID        string    `json:"id"`
+	Cron      string    `json:"cron"`
+	Timezone  string    `json:"timezone,omitempty"` // IANA name, eg: Asia/Shanghai, default is the manager's local timezone
+	Action    string    `json:"action"`
+	Instances int       `json:"instances,omitempty"` // only for scale action
+	LastRunAt time.Time `json:"lastRunAt"`           // the due time of the last run
+	NextRunAt time.Time `json:"nextRunAt"`
+	ErrMsg    string    `json:"errmsg"` // the error of the last run
+	CreatedAt time.Time `json:"created"`
+}
+
+func (s *Schedule) Valid() error {
+	sched, err := cron.Parse(s.Cron)
+	if err != nil {
+		return err
+	}
+
+	loc, err := s.Location()
+	if err != nil {
+		return err
+	}
+
+	if sched.Next(time.Now().In(loc)).IsZero() {
+		return fmt.Errorf("cron expression %s never fires", s.Cron)
+	}
+
+	switch s.Action {
+	case ScheduleScale:
+		if s.Instances <= 0 {
+			return errors.New("instances must be positive for scale action")
+		}
+	case ScheduleStart, ScheduleStop:
+	default:
+		return errors.New("unsupported schedule action: " + s.Action)
+	}
+
+	return nil
+}
+
+func (s *Schedule) Location() (*time.Location, error) {
+	if s.Timezone == "" {
+		return time.Local, nil
+	}
+
+	return time.LoadLocation(s.Timezone)
+}
+
+// Next returns the first due time after t, zero time means never.
+func (s *Schedule) Next(t time.Time) time.Time {
+	sched, err := cron.Parse(s.Cron)
+	if err != nil {
+		return time.Time{}
+	}
+
+	loc, err := s.Location()
+	if err != nil {
+		return time.Time{}
+	}
+
+	return sched.Next(t.In(loc))
+}
@@ -0,0 +1,74 @@
+package types
+
+import (
+	"errors"
+)
+
+const (
+	StrategyBinPack  = "binpack"
+	StrategySpread   = "spread"
+	StrategyRandom   = "random"
+	StrategyWeighted = "weighted"
+
+	ScorerLeastAllocated = "least-allocated"
+	ScorerMostAllocated  = "most-allocated"
+	ScorerAttribute      = "attribute"
+	ScorerImageLocality  = "image-locality"
+)
+
+// Strategy picks the agent to run the tasks of the version, the manager's
+// default strategy is used if not specified.
+type Strategy struct {
+	Name    string    `json:"name"`
+	Scorers []*Scorer `json:"scorers,omitempty"` // only for weighted strategy
+}
+
+// Scorer scores the agent in [0, 1], the weighted strategy ranks the agents
+// by the weighted sum of the scores.
+type Scorer struct {
+	Name      string  `json:"name"`
+	Weight    float64 `json:"weight"`
+	Attribute string  `json:"attribute,omitempty"` // only for attribute scorer
+	Value     string  `json:"value,omitempty"`     // only for attribute scorer, empty means any value
+}
+
+func (s *Strategy) Valid() error {
+	switch s.Name {
+	case StrategyBinPack, StrategySpread, StrategyRandom:
+		if len(s.Scorers) > 0 {
+			return errors.New("scorers only supported by weighted strategy")
+		}
+	case StrategyWeighted:
+		if len(s.Scorers) == 0 {
+			return errors.New("scorers required for weighted strategy")
+		}
+	default:
+		return errors.New("unsupported strategy: " + s.Name)
+	}
+
+	for _, scorer := range s.Scorers {
+		if err := scorer.Valid(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Scorer) Valid() error {
+	switch s.Name {
+	case ScorerLeastAllocated, ScorerMostAllocated, ScorerImageLocality:
+	case ScorerAttribute:
+		if s.Attribute == "" {
+			return errors.New("attribute required for attribute scorer")
+		}
+	default:
+		return errors.New("unsupported scorer: " + s.Name)
+	}
+
+	if s.Weight <= 0 {
+		return errors.New("scorer weight must be positive")
+	}
+
+	return nil
+}
@@ -8,9 +8,12 @@ type SysInfo struct {
 	LoadAvg    float64             `json:"loadavg"`
 	CPU        CPUInfo             `json:"cpu"`
 	Memory     MemoryInfo          `json:"memory"`
+	GPU        GPUInfo             `json:"gpu"`
 	Containers ContainersInfo      `json:"containers"`
 	IPs        map[string][]string `json:"ips"` // inet name -> ips
 	Listenings []int64             `json:"listenings"`
+
+	Maintenance []*MaintenanceWindow `json:"maintenance,omitempty"` // filled by the manager
 }
 
 type MemoryInfo struct {
@@ -18,6 +21,11 @@ type MemoryInfo struct {
 	Used  int64 `json:"used"`
 }
 
+type GPUInfo struct {
+	Total  int64    `json:"total"`
+	Models []string `json:"models"`
+}
+
 type CPUInfo struct {
 	Processor int64   `json:"processor"`
 	Physical  int64   `json:"physical"`
@@ -18,24 +18,27 @@ const (
 )
 
 type Task struct {
-	ID            string    `json:"id"`
-	Name          string    `json:"name"`
-	IP            string    `json:"ip"`
-	Ports         []uint64  `json:"ports"`
-	Healthy       string    `json:"healthy"`
-	Weight        float64   `json:"weight"`
-	AgentId       string    `json:"agentId"`
-	Version       string    `json:"version"`
-	Status        string    `json:"status"`
-	ErrMsg        string    `json:"errmsg"`
-	OpStatus      string    `json:"opstatus"`
-	ContainerID   string    `json:"container_id"`
-	ContainerName string    `json:"container_name"`
-	Retries       int       `json:"retries"`
-	MaxRetries    int       `json:"maxRetries"`
-	Histories     []*Task   `json:"histories"`
-	Created       time.Time `json:"created"`
-	Updated       time.Time `json:"updated"`
+	ID            string              `json:"id"`
+	Name          string              `json:"name"`
+	IP            string              `json:"ip"`
+	Ports         []uint64            `json:"ports"`
+	Healthy       string              `json:"healthy"`
+	Weight        float64             `json:"weight"`
+	AgentId       string              `json:"agentId"`
+	Version       string              `json:"version"`
+	Status        string              `json:"status"`
+	ErrMsg        string              `json:"errmsg"`
+	OpStatus      string              `json:"opstatus"`
+	ContainerID   string              `json:"container_id"`
+	ContainerName string              `json:"container_name"`
+	Retries       int                 `json:"retries"`
+	MaxRetries    int                 `json:"maxRetries"`
+	Histories     []*Task             `json:"histories"`
+	Volumes       []*PersistentVolume `json:"volumes,omitempty"`
+	RunningSince  *time.Time          `json:"runningSince,omitempty"`
+	NextLaunch    *time.Time          `json:"nextLaunch,omitempty"` // the scheduled launch of the retrying task
+	Created       time.Time           `json:"created"`
+	Updated       time.Time           `json:"updated"`
 }
 
 type TaskList []*Task
@@ -64,7 +67,10 @@ type TaskConfig struct {
 	Disk           float64           `json:"disk"`
 	IP             string            `json:"ip"`
 	Ports          []uint64          `json:"ports"`
+	Containerizer  string            `json:"containerizer"`
 	Image          string            `json:"image"`
+	ImageType      string            `json:"imageType,omitempty"`
+	Containers     []*PodContainer   `json:"containers,omitempty"`
 	Command        string            `json:"cmd"`
 	Privileged     bool              `json:"privileged"`
 	ForcePullImage bool              `json:"forcePullImage"`
@@ -79,33 +85,62 @@ type TaskConfig struct {
 	URIs           []string          `json:"uris"`
 	Env            map[string]string `json:"env"`
 	Constraints    []*Constraint     `json:"constraints"`
+	Affinities     []*AffinityRule   `json:"affinities"`
+	Strategy       *Strategy         `json:"strategy"`
+	Priority       int               `json:"priority"`
+	Role           string            `json:"role"`
+	Reserve        bool              `json:"reserve"`
 	Proxy          *Proxy            `json:"proxy"`
 	Version        string            `json:"version"`
 }
 
 func NewTaskConfig(spec *Version, idx int) *TaskConfig {
 	cfg := &TaskConfig{
-		CPUs:           spec.CPUs,
-		GPUs:           spec.GPUs,
-		Mem:            spec.Mem,
-		Disk:           spec.Disk,
-		Image:          spec.Container.Docker.Image,
-		Command:        spec.Command,
-		Privileged:     spec.Container.Docker.Privileged,
-		ForcePullImage: spec.Container.Docker.ForcePullImage,
-		Volumes:        spec.Container.Volumes,
-		PortMappings:   spec.Container.Docker.PortMappings,
-		Network:        spec.Container.Docker.Network,
-		Parameters:     spec.Container.Docker.Parameters,
-		HealthCheck:    spec.HealthCheck,
-		KillPolicy:     spec.KillPolicy,
-		RestartPolicy:  spec.RestartPolicy,
-		Labels:         spec.Labels,
-		URIs:           spec.URIs,
-		Env:            spec.Env,
-		Constraints:    spec.Constraints,
-		Proxy:          spec.Proxy,
-		Version:        spec.ID,
+		CPUs:          spec.CPUs,
+		GPUs:          spec.GPUs,
+		Mem:           spec.Mem,
+		Disk:          spec.Disk,
+		Containerizer: ContainerizerDocker,
+		Image:         spec.Container.Image(),
+		Containers:    spec.Containers,
+		Command:       spec.Command,
+		Volumes:       spec.Container.Volumes,
+		PortMappings:  spec.Container.PortMappings(),
+		Network:       spec.Container.Network(),
+		HealthCheck:   spec.HealthCheck,
+		KillPolicy:    spec.KillPolicy,
+		RestartPolicy: spec.RestartPolicy,
+		Labels:        spec.Labels,
+		URIs:          spec.URIs,
+		Env:           spec.Env,
+		Constraints:   spec.Constraints,
+		Affinities:    spec.Affinities,
+		Strategy:      spec.Strategy,
+		Priority:      spec.Priority,
+		Role:          spec.Role,
+		Reserve:       spec.Reserve,
+		Proxy:         spec.Proxy,
+		Version:       spec.ID,
+	}
+
+	if spec.Container.IsMesos() {
+		cfg.Containerizer = ContainerizerMesos
+		cfg.ImageType = spec.Container.Mesos.ImageType
+		cfg.ForcePullImage = spec.Container.Mesos.ForcePullImage
+	} else {
+		cfg.Privileged = spec.Container.Docker.Privileged
+		cfg.ForcePullImage = spec.Container.Docker.ForcePullImage
+		cfg.Parameters = spec.Container.Docker.Parameters
+	}
+
+	// the pod instance takes the resources of all its containers & the executor
+	if spec.IsPod() {
+		cfg.CPUs, cfg.Mem, cfg.Disk = PodExecutorCPUs, PodExecutorMem, PodExecutorDisk
+		for _, c := range spec.Containers {
+			cfg.CPUs += c.CPUs
+			cfg.Mem += c.Mem
+			cfg.Disk += c.Disk
+		}
 	}
 
 	// with user specified ip address
@@ -114,10 +149,12 @@ func NewTaskConfig(spec *Version, idx int) *TaskConfig {
 			return cfg
 		}
 
-		cfg.Parameters = append(cfg.Parameters, &Parameter{
-			Key:   "ip",
-			Value: spec.IPs[idx],
-		})
+		if cfg.Containerizer == ContainerizerDocker {
+			cfg.Parameters = append(cfg.Parameters, &Parameter{
+				Key:   "ip",
+				Value: spec.IPs[idx],
+			})
+		}
 		cfg.IP = spec.IPs[idx]
 	}
 
@@ -172,10 +209,11 @@ func (c *TaskConfig) uris() []*mesosproto.CommandInfo_URI {
 }
 
 func (c *TaskConfig) volumes() []*mesosproto.Volume {
-	var (
-		vlms = c.Volumes
-		mvs  = make([]*mesosproto.Volume, 0, 0)
-	)
+	return mesosVolumes(c.Volumes)
+}
+
+func mesosVolumes(vlms []*Volume) []*mesosproto.Volume {
+	mvs := make([]*mesosproto.Volume, 0, 0)
 
 	for _, vlm := range vlms {
 		mode := mesosproto.Volume_RO
@@ -183,9 +221,15 @@ func (c *TaskConfig) volumes() []*mesosproto.Volume {
 			mode = mesosproto.Volume_RW
 		}
 
+		// the persistent volume is mounted in the sandbox
+		hostPath := vlm.HostPath
+		if vlm.Persistent != nil {
+			hostPath = vlm.PersistentPath()
+		}
+
 		mvs = append(mvs, &mesosproto.Volume{
 			ContainerPath: proto.String(vlm.ContainerPath),
-			HostPath:      proto.String(vlm.HostPath),
+			HostPath:      proto.String(hostPath),
 			Mode:          &mode,
 		})
 	}
@@ -300,6 +344,10 @@ func (c *TaskConfig) parameters(id, name string) []*mesosproto.Parameter {
 }
 
 func (c *TaskConfig) BuildContainer(id, name string) *mesosproto.ContainerInfo {
+	if c.Containerizer == ContainerizerMesos {
+		return c.buildMesosContainer()
+	}
+
 	var (
 		image      = c.Image
 		privileged = c.Privileged
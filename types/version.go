@@ -4,11 +4,13 @@ import (
 	//"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"net"
 	"path"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/Dataman-Cloud/swan/utils"
 )
@@ -17,7 +19,18 @@ const (
 	// update onfailure action
 	UpdateStop     = "stop"
 	UpdateContinue = "continue"
-	UpdateRollback = "rollback" // TODO(nmg)
+	UpdateRollback = "rollback"
+
+	// restart policy
+	RestartAlways    = "always"
+	RestartOnFailure = "on-failure"
+	RestartNever     = "never"
+
+	// restart backoff defaults, by seconds
+	DefaultRestartBackoff       = 1.0
+	DefaultRestartBackoffFactor = 1.15
+	DefaultMaxLaunchDelay       = 300.0
+	DefaultRestartResetAfter    = 60.0
 )
 
 type VersionList []*Version
@@ -51,37 +64,78 @@ type Version struct {
 	RunAs         string            `json:"runAs"`
 	Cluster       string            `json:"cluster"`
 	Container     *Container        `json:"container"`
+	Containers    []*PodContainer   `json:"containers,omitempty"` // the containers of the pod
 	Labels        map[string]string `json:"labels"`
 	HealthCheck   *HealthCheck      `json:"healthCheck"`
 	Env           map[string]string `json:"env"`
 	KillPolicy    *KillPolicy       `json:"kill"`
 	RestartPolicy *RestartPolicy    `json:"restart"`
 	UpdatePolicy  *UpdatePolicy     `json:"update"`
+	AutoScale     *AutoScalePolicy  `json:"autoscale,omitempty"`
 	Constraints   []*Constraint     `json:"constraints"`
+	Affinities    []*AffinityRule   `json:"affinities,omitempty"`
+	Strategy      *Strategy         `json:"strategy,omitempty"`
+	Priority      int               `json:"priority,omitempty"` // higher one could preempt the lower ones
+	Role          string            `json:"role,omitempty"`     // empty means any of the framework role & unreserved resources
+	Reserve       bool              `json:"reserve,omitempty"`  // reserve the resources for the app dynamically
 	URIs          []string          `json:"uris"`
 	IPs           []string          `json:"ips"`
 	Proxy         *Proxy            `json:"proxy"`
 }
 
 type Container struct {
-	Type    string    `json:"type"`
-	Docker  *Docker   `json:"docker"`
-	Volumes []*Volume `json:"volumes"`
+	Type    string          `json:"type"`
+	Docker  *Docker         `json:"docker"`
+	Mesos   *MesosContainer `json:"mesos,omitempty"`
+	Volumes []*Volume       `json:"volumes"`
 }
 
 func (c *Container) Valid() error {
-	if strings.ToLower(c.Type) != "docker" {
-		return errors.New("only support docker containerization")
-	}
-	if c.Docker == nil {
-		return errors.New("docker containerization settings required")
-	}
 	for _, v := range c.Volumes {
 		if err := v.Valid(); err != nil {
 			return err
 		}
 	}
-	return c.Docker.Valid()
+
+	switch strings.ToLower(c.Type) {
+	case ContainerizerDocker:
+		if c.Docker == nil {
+			return errors.New("docker containerization settings required")
+		}
+		return c.Docker.Valid()
+	case ContainerizerMesos:
+		if c.Mesos == nil {
+			return errors.New("mesos containerization settings required")
+		}
+		return c.Mesos.Valid()
+	}
+
+	return errors.New("unsupported containerization, should be [docker,mesos]")
+}
+
+func (c *Container) IsMesos() bool {
+	return strings.ToLower(c.Type) == ContainerizerMesos && c.Mesos != nil
+}
+
+func (c *Container) Image() string {
+	if c.IsMesos() {
+		return c.Mesos.Image
+	}
+	return c.Docker.Image
+}
+
+func (c *Container) Network() string {
+	if c.IsMesos() {
+		return c.Mesos.Network
+	}
+	return c.Docker.Network
+}
+
+func (c *Container) PortMappings() []*PortMapping {
+	if c.IsMesos() {
+		return c.Mesos.PortMappings
+	}
+	return c.Docker.PortMappings
 }
 
 type Docker struct {
@@ -154,12 +208,17 @@ func (pm *PortMapping) Valid() error {
 }
 
 type Volume struct {
-	ContainerPath string `json:"containerPath,omitempty"`
-	HostPath      string `json:"hostPath,omitempty"`
-	Mode          string `json:"mode,omitempty"`
+	ContainerPath string      `json:"containerPath,omitempty"`
+	HostPath      string      `json:"hostPath,omitempty"`
+	Mode          string      `json:"mode,omitempty"`
+	Persistent    *Persistent `json:"persistent,omitempty"` // mesos persistent volume instead of host path
 }
 
 func (v *Volume) Valid() error {
+	if v.Persistent != nil {
+		return v.validPersistent()
+	}
+
 	if !path.IsAbs(v.HostPath) {
 		return errors.New("Volume.HostPath should be absolute path")
 	}
@@ -183,16 +242,93 @@ func (p *KillPolicy) Valid() error {
 }
 
 type RestartPolicy struct {
-	Retries int `json:"retries"`
+	Retries        int     `json:"retries"`
+	Policy         string  `json:"policy,omitempty"`         // always, on-failure, never
+	Backoff        float64 `json:"backoff,omitempty"`        // by seconds
+	BackoffFactor  float64 `json:"backoffFactor,omitempty"`  // the multiplier of the backoff on each retry
+	MaxLaunchDelay float64 `json:"maxLaunchDelay,omitempty"` // by seconds
+	ResetAfter     float64 `json:"resetAfter,omitempty"`     // by seconds
 }
 
 func (p *RestartPolicy) Valid() error {
 	if p.Retries < 0 {
 		return errors.New("RestartPolicy.Retries can't be negative")
 	}
+	switch p.Policy {
+	case "", RestartAlways, RestartOnFailure, RestartNever:
+	default:
+		return errors.New("unsupported RestartPolicy.Policy, should be [always,on-failure,never]")
+	}
+	if p.Backoff < 0 {
+		return errors.New("RestartPolicy.Backoff can't be negative")
+	}
+	if p.BackoffFactor != 0 && p.BackoffFactor < 1 {
+		return errors.New("RestartPolicy.BackoffFactor can't be less than 1")
+	}
+	if p.MaxLaunchDelay < 0 {
+		return errors.New("RestartPolicy.MaxLaunchDelay can't be negative")
+	}
+	if p.ResetAfter < 0 {
+		return errors.New("RestartPolicy.ResetAfter can't be negative")
+	}
 	return nil
 }
 
+// Restart tells whether the task terminated by the state should be restarted.
+// the nil policy restarts always as before.
+func (p *RestartPolicy) Restart(state string) bool {
+	if p == nil {
+		return true
+	}
+
+	switch p.Policy {
+	case RestartNever:
+		return false
+	case RestartOnFailure:
+		return state != "TASK_FINISHED"
+	}
+	return true
+}
+
+// Delay returns the delay before relaunching the task retried by the times,
+// the delay grows by the backoff factor and is capped by the max launch delay.
+func (p *RestartPolicy) Delay(retries int) time.Duration {
+	var (
+		backoff  = DefaultRestartBackoff
+		factor   = DefaultRestartBackoffFactor
+		maxDelay = DefaultMaxLaunchDelay
+	)
+
+	if p != nil {
+		if p.Backoff > 0 {
+			backoff = p.Backoff
+		}
+		if p.BackoffFactor > 0 {
+			factor = p.BackoffFactor
+		}
+		if p.MaxLaunchDelay > 0 {
+			maxDelay = p.MaxLaunchDelay
+		}
+	}
+
+	delay := backoff * math.Pow(factor, float64(retries))
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+
+	return time.Duration(delay * float64(time.Second))
+}
+
+// ResetDuration returns how long the task should stay running to reset its retries.
+func (p *RestartPolicy) ResetDuration() time.Duration {
+	reset := DefaultRestartResetAfter
+	if p != nil && p.ResetAfter > 0 {
+		reset = p.ResetAfter
+	}
+
+	return time.Duration(reset * float64(time.Second))
+}
+
 type UpdatePolicy struct {
 	Delay     float64 `json:"delay"`
 	OnFailure string  `json:"onFailure,omitempty"`
@@ -210,6 +346,35 @@ func (p *UpdatePolicy) Valid() error {
 	return nil
 }
 
+const DefaultAutoScaleCooldown = 300
+
+// AutoScalePolicy scales the app between [MinInstances, MaxInstances] to keep
+// the requests per second of each task close to TargetRPS, the rate is taken
+// from the janitor proxy stats. Cooldown is the minimal seconds between two
+// scale actions of the app.
+type AutoScalePolicy struct {
+	MinInstances int     `json:"minInstances"`
+	MaxInstances int     `json:"maxInstances"`
+	TargetRPS    float64 `json:"targetRPS"`
+	Cooldown     float64 `json:"cooldown,omitempty"`
+}
+
+func (p *AutoScalePolicy) Valid() error {
+	if p.MinInstances <= 0 {
+		return errors.New("AutoScale.MinInstances must be positive")
+	}
+	if p.MaxInstances < p.MinInstances {
+		return errors.New("AutoScale.MaxInstances can't be less than MinInstances")
+	}
+	if p.TargetRPS <= 0 {
+		return errors.New("AutoScale.TargetRPS must be positive")
+	}
+	if p.Cooldown < 0 {
+		return errors.New("AutoScale.Cooldown can't be negative")
+	}
+	return nil
+}
+
 type HealthCheck struct {
 	Protocol            string  `json:"protocol,omitempty"`
 	PortName            string  `json:"portName,omitempty"`
@@ -270,9 +435,15 @@ type Proxy struct {
 }
 
 type ProxyItem struct {
-	Alias  string `json:"alias" yaml:"alias"`
-	Listen string `json:"listen" yaml:"listen"`
-	Sticky bool   `json:"sticky" yaml:"sticky"`
+	Alias  string       `json:"alias" yaml:"alias"`
+	Listen string       `json:"listen" yaml:"listen"`
+	Sticky bool         `json:"sticky" yaml:"sticky"`
+	Rules  []*ProxyRule `json:"rules,omitempty" yaml:"rules,omitempty"` // http routing rules
+
+	HealthCheck *ProxyHealthCheck `json:"healthCheck,omitempty" yaml:"healthCheck,omitempty"` // backend health checking by the proxy
+	Retry       *ProxyRetry       `json:"retry,omitempty" yaml:"retry,omitempty"`             // failover to the other backends by the proxy
+	Balance     *ProxyBalance     `json:"balance,omitempty" yaml:"balance,omitempty"`         // load balancing algorithm of the proxy
+	Stickiness  *ProxyStickiness  `json:"stickiness,omitempty" yaml:"stickiness,omitempty"`   // session sticky mode & timeout, requires sticky
 }
 
 // similiar as above, but `Listen` int type
@@ -301,6 +472,42 @@ func (p *Proxy) Valid() error {
 		if l < 0 || l > 65535 {
 			return errors.New("proxy.Listen out of range")
 		}
+
+		for _, rule := range proxy.Rules {
+			if err := rule.Valid(); err != nil {
+				return err
+			}
+			if rule.Host == "" && proxy.Alias == "" {
+				return errors.New("proxy rule host required without alias")
+			}
+		}
+
+		if hc := proxy.HealthCheck; hc != nil {
+			if err := hc.Valid(); err != nil {
+				return err
+			}
+		}
+
+		if r := proxy.Retry; r != nil {
+			if err := r.Valid(); err != nil {
+				return err
+			}
+		}
+
+		if bl := proxy.Balance; bl != nil {
+			if err := bl.Valid(); err != nil {
+				return err
+			}
+		}
+
+		if s := proxy.Stickiness; s != nil {
+			if !proxy.Sticky {
+				return errors.New("proxy stickiness requires sticky enabled")
+			}
+			if err := s.Valid(); err != nil {
+				return err
+			}
+		}
 	}
 
 	return nil
@@ -392,6 +599,14 @@ func (v *Version) Validate() error {
 		return errors.New("instance count must be positive")
 	}
 
+	if v.Priority < 0 {
+		return errors.New("priority can't be negative")
+	}
+
+	if v.Reserve && (v.Role == "" || v.Role == "*") {
+		return errors.New("role required to reserve resources")
+	}
+
 	// verify runas
 	if n := len(v.RunAs); n == 0 || n > 64 {
 		return errors.New("runAs length should between (0,64]")
@@ -419,6 +634,18 @@ func (v *Version) Validate() error {
 		return err
 	}
 
+	if v.IsPod() {
+		if err := v.validPod(); err != nil {
+			return err
+		}
+	} else if v.Container.Image() == "" {
+		return errors.New("image required")
+	}
+
+	if len(v.PersistentVolumes()) > 0 && v.Role == "*" {
+		return errors.New("persistent volumes can't be created on unreserved resources")
+	}
+
 	// verify healthcheck
 	if v.IsHealthSet() {
 		if err := v.HealthCheck.Valid(); err != nil {
@@ -447,6 +674,21 @@ func (v *Version) Validate() error {
 		}
 	}
 
+	// verify autoscale policy
+	if v.AutoScale != nil {
+		if err := v.AutoScale.Valid(); err != nil {
+			return err
+		}
+
+		if v.Proxy == nil || !v.Proxy.Enabled {
+			return errors.New("autoscale requires proxy enabled to collect the traffic")
+		}
+
+		if network := strings.ToLower(v.Container.Network()); network != "host" && network != "bridge" {
+			return errors.New("autoscale only support host or bridge network")
+		}
+	}
+
 	// verify constraints
 	for _, cons := range v.Constraints {
 		if err := cons.validate(); err != nil {
@@ -454,6 +696,20 @@ func (v *Version) Validate() error {
 		}
 	}
 
+	// verify affinities
+	for _, rule := range v.Affinities {
+		if err := rule.Valid(); err != nil {
+			return err
+		}
+	}
+
+	// verify strategy
+	if v.Strategy != nil {
+		if err := v.Strategy.Valid(); err != nil {
+			return err
+		}
+	}
+
 	// verify proxy
 	if v.Proxy != nil {
 		if err := v.Proxy.Valid(); err != nil {
@@ -462,7 +718,7 @@ func (v *Version) Validate() error {
 	}
 
 	// verify static ip mode
-	switch network := strings.ToLower(v.Container.Docker.Network); network {
+	switch network := strings.ToLower(v.Container.Network()); network {
 	case "host", "bridge":
 	default:
 		// ensure ip valid & uniq
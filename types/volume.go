@@ -0,0 +1,80 @@
+package types
+
+import (
+	"errors"
+	"path"
+	"strings"
+	"time"
+)
+
+// Persistent describes the mesos persistent volume, the disk is reserved and
+// the volume is created on the agent where the task firstly runs, the task is
+// always relaunched onto the same agent with the volume.
+type Persistent struct {
+	Size float64 `json:"size"` // by MB
+}
+
+// PersistentVolume is the db record of a created persistent volume, which is
+// bound to one instance of the app.
+type PersistentVolume struct {
+	ID            string    `json:"id"` // mesos persistence id
+	AppID         string    `json:"appId"`
+	TaskName      string    `json:"taskName"` // the bound instance, eg: 0.nginx.default.bbklab.datamanmesos
+	AgentID       string    `json:"agentId"`
+	Hostname      string    `json:"hostname"`
+	ContainerPath string    `json:"containerPath"`
+	Size          float64   `json:"size"`
+	CreatedAt     time.Time `json:"created"`
+}
+
+func (v *Volume) validPersistent() error {
+	if !path.IsAbs(v.ContainerPath) {
+		return errors.New("Volume.ContainerPath should be absolute path")
+	}
+
+	if v.HostPath != "" {
+		return errors.New("Volume.HostPath not allowed for persistent volume")
+	}
+
+	if v.Mode != "RW" {
+		return errors.New("persistent volume should be RW mode")
+	}
+
+	if v.Persistent.Size <= 0 {
+		return errors.New("persistent volume size must be positive")
+	}
+
+	return nil
+}
+
+// PersistentPath is the path of the persistent volume relative to the mesos
+// sandbox, which is mounted to the container path.
+func (v *Volume) PersistentPath() string {
+	return "volume" + strings.Replace(path.Clean(v.ContainerPath), "/", "_", -1)
+}
+
+func (v *Version) PersistentVolumes() []*Volume {
+	vols := make([]*Volume, 0)
+	if v.Container == nil {
+		return vols
+	}
+
+	for _, vol := range v.Container.Volumes {
+		if vol.Persistent != nil {
+			vols = append(vols, vol)
+		}
+	}
+
+	return vols
+}
+
+func (c *TaskConfig) PersistentVolumes() []*Volume {
+	vols := make([]*Volume, 0)
+	for _, vol := range c.Volumes {
+		if vol.Persistent != nil {
+			vols = append(vols, vol)
+		}
+	}
+
+	return vols
+}
@@ -0,0 +1,172 @@
+// Package cron parses the standard 5 fields cron expression:
+//
+//	minute hour day-of-month month day-of-week
+//
+// each field supports `*`, `a`, `a-b`, `*/n`, `a-b/n` and comma separated
+// lists of them. day-of-week 0 and 7 are both Sunday. As the classic cron,
+// if both day-of-month and day-of-week are restricted, the time matches when
+// either of them matches.
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type bounds struct {
+	name     string
+	min, max uint
+}
+
+var (
+	minutes = bounds{"minute", 0, 59}
+	hours   = bounds{"hour", 0, 23}
+	doms    = bounds{"day-of-month", 1, 31}
+	months  = bounds{"month", 1, 12}
+	dows    = bounds{"day-of-week", 0, 7}
+)
+
+// Schedule is a parsed cron expression.
+type Schedule struct {
+	minute, hour, dom, month, dow uint64 // bit set of matched values
+
+	domStar, dowStar bool
+}
+
+// Parse parses the cron expression.
+func Parse(spec string) (*Schedule, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("expected 5 fields in cron expression, got %d", len(fields))
+	}
+
+	var (
+		s   = &Schedule{}
+		err error
+	)
+
+	if s.minute, err = parseField(fields[0], minutes); err != nil {
+		return nil, err
+	}
+	if s.hour, err = parseField(fields[1], hours); err != nil {
+		return nil, err
+	}
+	if s.dom, err = parseField(fields[2], doms); err != nil {
+		return nil, err
+	}
+	if s.month, err = parseField(fields[3], months); err != nil {
+		return nil, err
+	}
+	if s.dow, err = parseField(fields[4], dows); err != nil {
+		return nil, err
+	}
+
+	// both 0 and 7 mean Sunday
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+
+	// the day field is unrestricted if it starts with `*`, eg: `*` or `*/2`.
+	s.domStar = strings.HasPrefix(fields[2], "*")
+	s.dowStar = strings.HasPrefix(fields[4], "*")
+
+	return s, nil
+}
+
+func parseField(field string, b bounds) (uint64, error) {
+	var bits uint64
+
+	for _, expr := range strings.Split(field, ",") {
+		var (
+			rng  = expr
+			step = uint(1)
+		)
+
+		if i := strings.Index(expr, "/"); i >= 0 {
+			n, err := strconv.ParseUint(expr[i+1:], 10, 8)
+			if err != nil || n == 0 {
+				return 0, fmt.Errorf("invalid step in %s field: %s", b.name, expr)
+			}
+			rng, step = expr[:i], uint(n)
+		}
+
+		lo, hi := b.min, b.max
+		if rng != "*" {
+			parts := strings.SplitN(rng, "-", 2)
+
+			n, err := strconv.ParseUint(parts[0], 10, 8)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value in %s field: %s", b.name, expr)
+			}
+			lo, hi = uint(n), uint(n)
+
+			if len(parts) == 2 {
+				n, err := strconv.ParseUint(parts[1], 10, 8)
+				if err != nil {
+					return 0, fmt.Errorf("invalid value in %s field: %s", b.name, expr)
+				}
+				hi = uint(n)
+			} else if step > 1 {
+				hi = b.max // `a/n` means from a to the max
+			}
+		}
+
+		if lo < b.min || hi > b.max || lo > hi {
+			return 0, fmt.Errorf("%s field out of range [%d, %d]: %s", b.name, b.min, b.max, expr)
+		}
+
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << v
+		}
+	}
+
+	return bits, nil
+}
+
+// Next returns the first matched time after t, in t's location. The zero
+// time is returned if nothing matched within 5 years, eg: `0 0 30 2 *`.
+func (s *Schedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	end := t.AddDate(5, 0, 0)
+
+	for t.Before(end) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location()).AddDate(0, 1, 0)
+			continue
+		}
+
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location()).AddDate(0, 0, 1)
+			continue
+		}
+
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location()).Add(time.Hour)
+			continue
+		}
+
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+
+		return t
+	}
+
+	return time.Time{}
+}
+
+func (s *Schedule) dayMatches(t time.Time) bool {
+	var (
+		domMatch = s.dom&(1<<uint(t.Day())) != 0
+		dowMatch = s.dow&(1<<uint(t.Weekday())) != 0
+	)
+
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+
+	return domMatch || dowMatch
+}
@@ -0,0 +1,108 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		wantErr bool
+	}{
+		{name: "every minute", spec: "* * * * *"},
+		{name: "lists ranges and steps", spec: "0,30 8-18/2 1-15 */3 1-5"},
+		{name: "sunday as 7", spec: "0 0 * * 7"},
+		{name: "too few fields", spec: "* * * *", wantErr: true},
+		{name: "too many fields", spec: "* * * * * *", wantErr: true},
+		{name: "out of range", spec: "60 * * * *", wantErr: true},
+		{name: "day of month zero", spec: "0 0 0 * *", wantErr: true},
+		{name: "zero step", spec: "*/0 * * * *", wantErr: true},
+		{name: "reversed range", spec: "5-1 * * * *", wantErr: true},
+		{name: "not a number", spec: "a * * * *", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNext(t *testing.T) {
+	// Monday
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		spec string
+		want time.Time
+	}{
+		{
+			name: "step minutes",
+			spec: "*/15 * * * *",
+			want: time.Date(2024, 1, 1, 0, 15, 0, 0, time.UTC),
+		},
+		{
+			name: "range with step",
+			spec: "1-10/3 * * * *",
+			want: time.Date(2024, 1, 1, 0, 1, 0, 0, time.UTC),
+		},
+		{
+			name: "daily",
+			spec: "0 3 * * *",
+			want: time.Date(2024, 1, 1, 3, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "monthly",
+			spec: "0 0 1 * *",
+			want: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "sunday as 0",
+			spec: "0 0 * * 0",
+			want: time.Date(2024, 1, 7, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "sunday as 7",
+			spec: "0 0 * * 7",
+			want: time.Date(2024, 1, 7, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "both days restricted matches either",
+			spec: "0 0 13 * 5",
+			want: time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "day of month starts with star matches both",
+			spec: "0 0 */2 * 5",
+			want: time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "day of week starts with star matches both",
+			spec: "0 0 1 * */2",
+			want: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "never",
+			spec: "0 0 30 2 *",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Parse(tt.spec)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.spec, err)
+			}
+
+			if got := s.Next(from); !got.Equal(tt.want) {
+				t.Errorf("Next() of %q = %v, want %v", tt.spec, got, tt.want)
+			}
+		})
+	}
+}